@@ -13,26 +13,26 @@ import (
 	"animeverse/cache"
 	"animeverse/config"
 	"animeverse/models"
+	"animeverse/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // BackendFirstTrendingHandler - Backend first with high-quality images
 func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil {
-			page = parsed
-		}
+	page, ok := readAniListPage(w, r, 24)
+	if !ok {
+		return
 	}
 
-	cacheKey := fmt.Sprintf("backend_trending_%d", page)
+	cacheKey := fmt.Sprintf("backend_trending_%d_%d_%s", page.Offset, page.Limit, page.Sort)
 	
 	// Check Redis cache first
 	var cachedAnimes []models.Anime
 	if cache.Exists(cacheKey) {
 		if err := cache.Get(cacheKey, &cachedAnimes); err == nil {
-			log.Printf("✅ Cache hit for trending page %d", page)
+			log.Printf("✅ Cache hit for trending page %d", page.AniListPage())
+			setNextPage(w, r, page.NextOffset(len(cachedAnimes)))
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": true,
@@ -48,9 +48,7 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	skip := (page - 1) * 24
-	opts := options.Find().SetSkip(int64(skip)).SetLimit(24).SetSort(bson.D{{"score", -1}})
-	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	cursor, err := collection.Find(ctx, services.CatalogBrowseFilter("", "", ""), databasePageOptions(page))
 	
 	var dbAnimes []models.Anime
 	if err == nil {
@@ -59,8 +57,8 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If we have good quality data in DB, use it
-	if len(dbAnimes) >= 20 {
-		log.Printf("✅ Using MongoDB data for trending page %d", page)
+	if len(dbAnimes)*6 >= page.Limit*5 {
+		log.Printf("✅ Using MongoDB data for trending page %d", page.AniListPage())
 		
 		// Upgrade images in background
 		go upgradeAnimeImages(dbAnimes)
@@ -68,6 +66,7 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 		// Cache for 10 minutes
 		cache.Set(cacheKey, dbAnimes, 10*time.Minute)
 		
+		setNextPage(w, r, page.NextOffset(len(dbAnimes)))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
@@ -78,7 +77,7 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fallback to AniList with high-quality data
-	log.Printf("🌐 Fetching from AniList for trending page %d", page)
+	log.Printf("🌐 Fetching from AniList for trending page %d", page.AniListPage())
 	animes, err := fetchTrendingFromAniList(page)
 	if err != nil {
 		http.Error(w, "Failed to load trending anime", http.StatusInternalServerError)
@@ -91,6 +90,7 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 	// Cache for 5 minutes
 	cache.Set(cacheKey, animes, 5*time.Minute)
 
+	setNextPage(w, r, page.NextOffset(len(animes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -101,23 +101,22 @@ func BackendFirstTrendingHandler(w http.ResponseWriter, r *http.Request) {
 
 // BackendFirstBrowseHandler - Backend first browse with filters
 func BackendFirstBrowseHandler(w http.ResponseWriter, r *http.Request) {
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil {
-			page = parsed
-		}
+	page, ok := readAniListPage(w, r, 25)
+	if !ok {
+		return
 	}
 
 	genre := r.URL.Query().Get("genre")
 	year := r.URL.Query().Get("year")
 	search := r.URL.Query().Get("search")
 
-	cacheKey := fmt.Sprintf("backend_browse_%d_%s_%s_%s", page, genre, year, search)
+	cacheKey := fmt.Sprintf("backend_browse_%d_%d_%s_%s_%s_%s", page.Offset, page.Limit, page.Sort, genre, year, search)
 	
 	// Check cache first
 	var cachedAnimes []models.Anime
 	if cache.Exists(cacheKey) {
 		if err := cache.Get(cacheKey, &cachedAnimes); err == nil {
+			setNextPage(w, r, page.NextOffset(len(cachedAnimes)))
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
 				"success": true,
@@ -128,28 +127,12 @@ func BackendFirstBrowseHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Build MongoDB query
-	filter := bson.M{}
-	if genre != "" {
-		filter["genre"] = bson.M{"$in": []string{genre}}
-	}
-	if year != "" {
-		if yearInt, err := strconv.Atoi(year); err == nil {
-			filter["year"] = yearInt
-		}
-	}
-	if search != "" {
-		filter["name"] = bson.M{"$regex": search, "$options": "i"}
-	}
-
 	// Try MongoDB first
 	collection := config.GetCollection(config.DB, "anime")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	skip := (page - 1) * 25
-	opts := options.Find().SetSkip(int64(skip)).SetLimit(25).SetSort(bson.D{{"score", -1}})
-	cursor, err := collection.Find(ctx, filter, opts)
+	cursor, err := collection.Find(ctx, services.CatalogBrowseFilter(genre, year, search), databasePageOptions(page))
 	
 	var dbAnimes []models.Anime
 	if err == nil {
@@ -158,10 +141,11 @@ func BackendFirstBrowseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If we have enough data, use it
-	if len(dbAnimes) >= 15 {
+	if len(dbAnimes)*5 >= page.Limit*3 {
 		go upgradeAnimeImages(dbAnimes)
 		cache.Set(cacheKey, dbAnimes, 15*time.Minute)
 		
+		setNextPage(w, r, page.NextOffset(len(dbAnimes)))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
@@ -181,6 +165,7 @@ func BackendFirstBrowseHandler(w http.ResponseWriter, r *http.Request) {
 	go saveAnimesToDB(animes)
 	cache.Set(cacheKey, animes, 10*time.Minute)
 
+	setNextPage(w, r, page.NextOffset(len(animes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -190,10 +175,10 @@ func BackendFirstBrowseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // fetchTrendingFromAniList fetches trending anime with high-quality images
-func fetchTrendingFromAniList(page int) ([]models.Anime, error) {
-	query := `{
-		Page(page: %d, perPage: 24) {
-			media(type: ANIME, sort: TRENDING_DESC) {
+func fetchTrendingFromAniList(page services.PageRequest) ([]models.Anime, error) {
+	query := `query ($page: Int, $perPage: Int, $sort: [MediaSort]) {
+		Page(page: $page, perPage: $perPage) {
+			media(type: ANIME, sort: $sort) {
 				id
 				title { romaji english native }
 				description(asHtml: false)
@@ -211,7 +196,12 @@ func fetchTrendingFromAniList(page int) ([]models.Anime, error) {
 	}`
 
 	requestBody := map[string]interface{}{
-		"query": fmt.Sprintf(query, page),
+		"query": query,
+		"variables": map[string]interface{}{
+			"page":    page.AniListPage(),
+			"perPage": page.Limit,
+			"sort":    []string{page.AniListSort("TRENDING_DESC")},
+		},
 	}
 
 	jsonData, _ := json.Marshal(requestBody)
@@ -230,10 +220,10 @@ func fetchTrendingFromAniList(page int) ([]models.Anime, error) {
 }
 
 // fetchBrowseFromAniList fetches browse anime with filters
-func fetchBrowseFromAniList(page int, genre, year, search string) ([]models.Anime, error) {
-	query := `{
-		Page(page: %d, perPage: 25) {
-			media(type: ANIME, sort: POPULARITY_DESC%s) {
+func fetchBrowseFromAniList(page services.PageRequest, genre, year, search string) ([]models.Anime, error) {
+	query := `query ($page: Int, $perPage: Int, $sort: [MediaSort], $search: String, $genre: String, $year: Int) {
+		Page(page: $page, perPage: $perPage) {
+			media(type: ANIME, sort: $sort, search: $search, genre: $genre, seasonYear: $year) {
 				id
 				title { romaji english native }
 				description(asHtml: false)
@@ -250,34 +240,40 @@ func fetchBrowseFromAniList(page int, genre, year, search string) ([]models.Anim
 		}
 	}`
 
-	filters := ""
+	var result map[string]interface{}
+	if err := services.QueryAniList(query, aniListBrowseVariables(page, genre, year, search), &result); err != nil {
+		return nil, err
+	}
+
+	return processAniListResponse(result)
+}
+
+// aniListBrowseVariables passes browse filters to AniList; unset variables are null, which AniList ignores
+func aniListBrowseVariables(page services.PageRequest, genre, year, search string) map[string]interface{} {
+	variables := map[string]interface{}{
+		"page":    page.AniListPage(),
+		"perPage": page.Limit,
+		"sort":    []string{page.AniListSort("POPULARITY_DESC")},
+	}
 	if search != "" {
-		filters += fmt.Sprintf(`, search: "%s"`, search)
+		variables["search"] = search
 	}
 	if genre != "" {
-		filters += fmt.Sprintf(`, genre_in: ["%s"]`, genre)
+		variables["genre"] = genre
 	}
-	if year != "" {
-		filters += fmt.Sprintf(`, seasonYear: %s`, year)
+	if yearInt, err := strconv.Atoi(year); err == nil {
+		variables["year"] = yearInt
 	}
+	return variables
+}
 
-	requestBody := map[string]interface{}{
-		"query": fmt.Sprintf(query, page, filters),
-	}
-
-	jsonData, _ := json.Marshal(requestBody)
-	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+// databasePageOptions reads one page of the anime collection, best scored first unless the
+// request asks for another order
+func databasePageOptions(page services.PageRequest) *options.FindOptions {
+	if page.Sort == "" {
+		page.Sort = "score"
 	}
-
-	return processAniListResponse(result)
+	return options.Find().SetSkip(int64(page.Offset)).SetLimit(int64(page.Limit)).SetSort(page.SortDocument())
 }
 
 // processAniListResponse converts AniList response to our models
@@ -382,10 +378,25 @@ func saveAnimesToDB(animes []models.Anime) {
 	defer cancel()
 
 	for _, anime := range animes {
-		filter := bson.M{"name": anime.Name}
+		// Match catalog anime only, under this title or a spelling variant, never users' list copies
+		existing, err := services.FindAnimeByName(anime.Name)
+		if err != nil {
+			log.Printf("Error saving anime %s: %v", anime.Name, err)
+			continue
+		}
+
+		// Listeners build the search terms and title keys of new and changed anime
+		if existing == nil {
+			anime.CreatedAt = time.Now()
+			anime.UpdatedAt = time.Now()
+			if err := services.InsertOneAnime(anime); err != nil {
+				log.Printf("Error saving anime %s: %v", anime.Name, err)
+			}
+			continue
+		}
+
 		update := bson.M{
 			"$set": bson.M{
-				"name":        anime.Name,
 				"synopsis":    anime.Synopsis,
 				"genre":       anime.Genre,
 				"score":       anime.Score,
@@ -396,16 +407,12 @@ func saveAnimesToDB(animes []models.Anime) {
 				"information": anime.Information,
 				"updatedAt":   time.Now(),
 			},
-			"$setOnInsert": bson.M{
-				"createdAt": time.Now(),
-			},
 		}
-
-		opts := options.Update().SetUpsert(true)
-		_, err := collection.UpdateOne(ctx, filter, update, opts)
-		if err != nil {
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, update); err != nil {
 			log.Printf("Error saving anime %s: %v", anime.Name, err)
+			continue
 		}
+		services.PublishCatalogUpdate(existing.ID)
 	}
 	log.Printf("✅ Saved %d anime to database", len(animes))
 }
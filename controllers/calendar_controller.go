@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"animeverse/middleware"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+func GetCalendarEventsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	events, err := services.GetCalendarEvents(claims.Sub)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to load calendar")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Calendar retrieved successfully", events, "")
+}
+
+func CreateCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	feedURL, err := services.CreateCalendarFeed(claims.Sub)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to create calendar feed")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, true, "Calendar feed created. Any previous feed URL no longer works.",
+		map[string]string{"url": feedURL}, "")
+}
+
+func RevokeCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	if err := services.RevokeCalendarFeed(claims.Sub); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to revoke calendar feed")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Calendar feed revoked", nil, "")
+}
+
+// CalendarFeedHandler serves a user's private iCalendar feed; the token in the URL is the only credential
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	feed, err := services.GetCalendarFeed(chi.URLParam(r, "token"))
+	if err == services.ErrInvalidFeedToken {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to build calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="animeverse.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	fmt.Fprint(w, feed)
+}
@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+type commentRequest struct {
+	Content  string `json:"content"`
+	Spoiler  bool   `json:"spoiler"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// decodeCommentRequest accepts both JSON bodies and HTMX form posts
+func decodeCommentRequest(r *http.Request) (commentRequest, error) {
+	var req commentRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		return req, err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return req, err
+	}
+	req.Content = r.FormValue("content")
+	req.ParentID = r.FormValue("parent_id")
+	spoiler := r.FormValue("spoiler")
+	req.Spoiler = spoiler == "on" || spoiler == "true"
+	return req, nil
+}
+
+func episodeParam(r *http.Request) int {
+	episode, _ := strconv.Atoi(chi.URLParam(r, "episode"))
+	return episode
+}
+
+func GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	animeID := chi.URLParam(r, "id")
+	episode := episodeParam(r)
+
+	page, ok := readListPage(w, r, "newest", 20)
+	if !ok {
+		return
+	}
+
+	comments, next, err := services.GetComments(animeID, episode, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html")
+		nextURL := fmt.Sprintf("/api/anime/%s/comments", animeID)
+		if episode > 0 {
+			nextURL = fmt.Sprintf("/api/anime/%s/episodes/%d/comments", animeID, episode)
+		}
+		renderComments(w, comments, nextURL, next)
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Comments retrieved successfully", comments, "")
+}
+
+func GetCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	commentID := chi.URLParam(r, "id")
+
+	page, ok := readListPage(w, r, "oldest", 20)
+	if !ok {
+		return
+	}
+
+	replies, next, err := services.GetReplies(commentID, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html")
+		renderComments(w, replies, fmt.Sprintf("/api/comments/%s/replies", commentID), next)
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Replies retrieved successfully", replies, "")
+}
+
+func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	req, err := decodeCommentRequest(r)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	comment, err := services.CreateComment(claims.Sub, claims.Name, chi.URLParam(r, "id"), episodeParam(r), req.ParentID, req.Content, req.Spoiler)
+	if err == services.ErrCommentRateLimited {
+		sendJSONResponse(w, http.StatusTooManyRequests, false, "", nil, err.Error())
+		return
+	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html")
+		renderComment(w, comment)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, true, "Comment posted successfully", comment, "")
+}
+
+func UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	req, err := decodeCommentRequest(r)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	comment, err := services.UpdateComment(claims.Sub, chi.URLParam(r, "id"), req.Content, req.Spoiler)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Comment updated successfully", comment, "")
+}
+
+func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	if err := services.DeleteComment(claims.Sub, chi.URLParam(r, "id")); err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Comment not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Comment deleted successfully", nil, "")
+}
+
+func AddCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	reactToComment(w, r, true)
+}
+
+func RemoveCommentReactionHandler(w http.ResponseWriter, r *http.Request) {
+	reactToComment(w, r, false)
+}
+
+func reactToComment(w http.ResponseWriter, r *http.Request, add bool) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	comment, err := services.ReactToComment(claims.Sub, chi.URLParam(r, "id"), chi.URLParam(r, "reaction"), add)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Reaction updated successfully", comment, "")
+}
+
+// renderComments renders a page of comments for the HTMX anime modal, with a button that loads
+// the page after it from nextURL
+func renderComments(w http.ResponseWriter, page *services.CommentPage, nextURL, next string) {
+	if len(page.Comments) == 0 {
+		fmt.Fprintf(w, `<p class="text-gray-400 text-center py-6">No comments yet. Be the first!</p>`)
+		return
+	}
+
+	for i := range page.Comments {
+		renderComment(w, &page.Comments[i])
+	}
+
+	if next != "" {
+		fmt.Fprintf(w, `
+		<button class="w-full text-center text-indigo-500 hover:text-indigo-700 py-3"
+		        hx-get="%s?cursor=%s" hx-swap="outerHTML">Load more comments</button>`,
+			nextURL, next)
+	}
+}
+
+// renderComment renders a single comment, blurring spoilers until clicked
+func renderComment(w http.ResponseWriter, comment *model.Comment) {
+	content := html.EscapeString(comment.Content)
+	if comment.Deleted {
+		content = `<span class="italic text-gray-400">[deleted]</span>`
+	} else if comment.Hidden {
+		content = `<span class="italic text-gray-400">[removed by a moderator]</span>`
+	} else if comment.Spoiler {
+		content = fmt.Sprintf(`<span class="blur-sm hover:blur-none cursor-pointer transition-all" title="Spoiler - click to reveal"
+		      onclick="this.classList.remove('blur-sm')">%s</span>`, content)
+	}
+
+	edited := ""
+	if comment.Edited && !comment.Deleted {
+		edited = ` · edited`
+	}
+
+	reactions := []string{}
+	for _, reaction := range services.AllowedReactions {
+		if count := comment.Reactions[reaction]; count > 0 {
+			reactions = append(reactions, fmt.Sprintf(`<span class="bg-gray-100 px-2 py-0.5 rounded-full">%s %d</span>`, reaction, count))
+		}
+	}
+
+	replies := ""
+	if comment.ReplyCount > 0 {
+		replies = fmt.Sprintf(`
+			<button class="text-indigo-500 hover:text-indigo-700 text-xs mt-2"
+			        hx-get="/api/comments/%s/replies" hx-target="#replies-%s" hx-swap="innerHTML">View %d replies</button>`,
+			comment.ID.Hex(), comment.ID.Hex(), comment.ReplyCount)
+	}
+
+	fmt.Fprintf(w, `
+	<div class="border-l-2 border-gray-200 pl-4 py-3" id="comment-%s">
+		<div class="flex items-center justify-between text-xs text-gray-500 mb-1">
+			<span class="font-semibold text-gray-700">%s</span>
+			<span>%s%s</span>
+		</div>
+		<p class="text-gray-700 text-sm">%s</p>
+		<div class="flex gap-2 text-xs mt-2">%s</div>%s
+		<div id="replies-%s"></div>
+	</div>`,
+		comment.ID.Hex(),
+		html.EscapeString(comment.UserName),
+		comment.CreatedAt.Format("Jan 2, 2006 15:04"), edited,
+		content, strings.Join(reactions, ""), replies,
+		comment.ID.Hex())
+}
@@ -3,7 +3,11 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	"html"
+	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -320,6 +324,17 @@ func renderAnimeModal(w http.ResponseWriter, anime *model.Anime) {
 			event.target.classList.add('border-anime-blue', 'text-anime-blue');
 		}
 		
+		// Send the auth token with HTMX requests so comments can be posted
+		if (!window.commentAuthConfigured) {
+			window.commentAuthConfigured = true;
+			document.body.addEventListener('htmx:configRequest', function(evt) {
+				const token = localStorage.getItem('auth_token');
+				if (token) {
+					evt.detail.headers['Authorization'] = 'Bearer ' + token;
+				}
+			});
+		}
+		
 		function loadEpisodes(animeId, season) {
 			// Mock episode loading
 			console.log('Loading episodes for anime:', animeId, 'season:', season);
@@ -340,11 +355,16 @@ func renderAnimeModal(w http.ResponseWriter, anime *model.Anime) {
 }
 
 func GetMyAllAnimesHandler(w http.ResponseWriter, r *http.Request) {
-	allAnimes := services.GetAllAnimes()
-	if allAnimes == nil {
+	page, ok := readPage(w, r, "popularity", services.DEFAULT_PAGE_LIMIT)
+	if !ok {
+		return
+	}
+	allAnimes, next, err := services.ListAnimes(page)
+	if err != nil {
 		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to fetch animes")
 		return
 	}
+	setNextPage(w, r, next)
 	
 	// Check if request wants HTML (HTMX)
 	if r.Header.Get("HX-Request") == "true" {
@@ -498,42 +518,129 @@ func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func FilterAnimesHandler(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	genre := r.URL.Query().Get("genre")
-	year := r.URL.Query().Get("year")
-	season := r.URL.Query().Get("season")
-	format := r.URL.Query().Get("format")
-	status := r.URL.Query().Get("status")
-	
-	// Get user ID if authenticated
-	userID := ""
-	if user := r.Context().Value("user"); user != nil {
-		claims := user.(*middleware.SupabaseClaims)
-		userID = claims.Sub
+	query, info, userID, err := browseParams(r)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+	page, ok := readPage(w, r, "popularity", services.DEFAULT_PAGE_LIMIT)
+	if !ok {
+		return
 	}
 
-	filteredAnimes := services.SmartSearch(search, genre, year, season, format, status, userID)
+	filteredAnimes, next := services.SmartSearch(query, userID, info, page)
 	if filteredAnimes == nil {
 		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to filter animes")
 		return
 	}
+	setNextPage(w, r, next)
+	if userID == "" {
+		// Only catalog searches are logged; a search of one's own list says nothing about the catalog
+		logSearch(w, page, query.Text, services.SEARCH_SOURCE_FILTER, int64(len(filteredAnimes)))
+	}
+	var didYouMean []string
+	if len(filteredAnimes) == 0 && page.Offset == 0 && page.After == nil {
+		didYouMean = services.DidYouMean(query)
+	}
 	
 	// Check if request wants HTML (HTMX)
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html")
+		if len(didYouMean) > 0 {
+			renderDidYouMean(w, r.URL.Path, didYouMean)
+			return
+		}
 		renderAnimeCards(w, filteredAnimes)
 		return
 	}
 	
-	sendJSONResponse(w, http.StatusOK, true, "Animes filtered successfully", filteredAnimes, "")
+	facets, err := services.BrowseFacets(query, userID, info)
+	if err != nil {
+		log.Printf("Failed to count browse facets: %v", err)
+	}
+	sendFacetedResponse(w, "Animes filtered successfully", filteredAnimes, facets, didYouMean)
+}
+
+// FacetsHandler returns only the facet counts for the browse filters, for the filter sidebar
+func FacetsHandler(w http.ResponseWriter, r *http.Request) {
+	query, info, userID, err := browseParams(r)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	facets, err := services.BrowseFacets(query, userID, info)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to count facets")
+		return
+	}
+	sendJSONResponse(w, http.StatusOK, true, "Facets retrieved successfully", facets, "")
+}
+
+// sendFacetedResponse sends a list of results with the facet counts beside them, and respelled
+// queries to try when there are no results
+func sendFacetedResponse(w http.ResponseWriter, message string, data interface{}, facets *services.Facets, didYouMean []string) {
+	response := map[string]interface{}{
+		"success": true,
+		"message": message,
+		"data":    data,
+		"facets":  facets,
+	}
+	if len(didYouMean) > 0 {
+		response["did_you_mean"] = didYouMean
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// browseParams reads the browse filters shared by FilterAnimesHandler and FacetsHandler
+func browseParams(r *http.Request) (*services.SearchQuery, services.InformationFilters, string, error) {
+	// The search box takes the query language, e.g. genre:action -genre:ecchi year:2015..2020
+	text := r.URL.Query().Get("q")
+	if text == "" {
+		text = r.URL.Query().Get("search")
+	}
+	query, err := services.ParseSearchQuery(text)
+	if err != nil {
+		return nil, services.InformationFilters{}, "", err
+	}
+	// The browse form's dropdowns arrive as separate parameters
+	for _, param := range []string{"genre", "year", "season", "format", "type", "status", "airing", "studio"} {
+		if err := query.Require(param, r.URL.Query().Get(param)); err != nil {
+			return nil, services.InformationFilters{}, "", err
+		}
+	}
+	info := services.InformationFilters{
+		AiredFrom:     r.URL.Query().Get("aired_from"),
+		AiredTo:       r.URL.Query().Get("aired_to"),
+		AiringOn:      r.URL.Query().Get("airing_on"),
+		BroadcastDay:  r.URL.Query().Get("broadcast_day"),
+		BroadcastFrom: r.URL.Query().Get("broadcast_from"),
+		BroadcastTo:   r.URL.Query().Get("broadcast_to"),
+		MinDuration:   r.URL.Query().Get("min_duration"),
+		MaxDuration:   r.URL.Query().Get("max_duration"),
+	}
+	
+	// Get user ID if authenticated
+	userID := ""
+	if user := r.Context().Value("user"); user != nil {
+		claims := user.(*middleware.SupabaseClaims)
+		userID = claims.Sub
+	}
+	return query, info, userID, nil
 }
 
 func GetTrendingAnimesHandler(w http.ResponseWriter, r *http.Request) {
-	trendingAnimes := services.GetTrendingAnimes()
+	page, ok := readPage(w, r, "score", 5)
+	if !ok {
+		return
+	}
+	trendingAnimes, next := services.GetTrendingAnimes(page)
 	if trendingAnimes == nil {
 		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to fetch trending animes")
 		return
 	}
+	setNextPage(w, r, next)
 	
 	// Check if request wants HTML (HTMX)
 	if r.Header.Get("HX-Request") == "true" {
@@ -546,11 +653,16 @@ func GetTrendingAnimesHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetPopularAnimesHandler(w http.ResponseWriter, r *http.Request) {
-	popularAnimes := services.GetPopularAnimes()
+	page, ok := readPage(w, r, "score", 5)
+	if !ok {
+		return
+	}
+	popularAnimes, next := services.GetPopularAnimes(page)
 	if popularAnimes == nil {
 		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to fetch popular animes")
 		return
 	}
+	setNextPage(w, r, next)
 	
 	// Check if request wants HTML (HTMX)
 	if r.Header.Get("HX-Request") == "true" {
@@ -1020,27 +1132,98 @@ func UpdateCurrentSeasonHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, true, fmt.Sprintf("Updated %d current season anime", count), nil, "")
 }
 
+func BackfillInformationHandler(w http.ResponseWriter, r *http.Request) {
+	if err := services.EnqueueInformationBackfill(); err != nil {
+		// No job queue, so run it here
+		count, err := services.RunInformationBackfill()
+		if err != nil {
+			sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to parse anime information: "+err.Error())
+			return
+		}
+		sendJSONResponse(w, http.StatusOK, true, fmt.Sprintf("Parsed information for %d anime", count), nil, "")
+		return
+	}
+	sendJSONResponse(w, http.StatusAccepted, true, "Information backfill queued", nil, "")
+}
+
 func SearchAnimesHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
+	query, err := services.ParseSearchQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+	if query.Empty() {
 		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Search query is required")
 		return
 	}
 	
-	searchResults := services.SearchAnimes(query)
-	if searchResults == nil {
+	page, ok := readPage(w, r, services.SORT_RELEVANCE, 20)
+	if !ok {
+		return
+	}
+
+	search := services.NewSearchService()
+	var searchResults []primitive.M
+	var total int64
+	next := ""
+	if page.Sort == services.SORT_RELEVANCE {
+		searchResults, total, err = search.Search(query, page.Limit, page.Offset)
+		if int64(page.Offset+len(searchResults)) < total {
+			next = page.NextOffset(len(searchResults))
+		}
+	} else {
+		searchResults, next, total, err = search.SearchSorted(query, page)
+	}
+	if err != nil {
 		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to search animes")
 		return
 	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	setNextPage(w, r, next)
+	logSearch(w, page, query.Text, services.SEARCH_SOURCE_SEARCH, total)
+	var didYouMean []string
+	if total == 0 {
+		didYouMean = services.DidYouMean(query)
+	}
 	
 	// Check if request wants HTML (HTMX)
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html")
+		if len(didYouMean) > 0 {
+			renderDidYouMean(w, r.URL.Path, didYouMean)
+			return
+		}
 		renderSearchResults(w, searchResults)
 		return
 	}
 	
-	sendJSONResponse(w, http.StatusOK, true, "Search results retrieved successfully", searchResults, "")
+	facets, err := search.Facets(query)
+	if err != nil {
+		log.Printf("Failed to count search facets: %v", err)
+	}
+	sendFacetedResponse(w, "Search results retrieved successfully", searchResults, facets, didYouMean)
+}
+
+// SuggestAnimesHandler returns typeahead suggestions for a partly typed title
+func SuggestAnimesHandler(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	suggestions := services.SuggestAnime(r.URL.Query().Get("q"), limit)
+	sendJSONResponse(w, http.StatusOK, true, "Suggestions retrieved successfully", suggestions, "")
+}
+
+// renderDidYouMean renders the "No anime found" card with respelled queries to try instead. Each
+// one runs the query on the same endpoint and swaps its results in for the card.
+func renderDidYouMean(w http.ResponseWriter, path string, didYouMean []string) {
+	links := make([]string, len(didYouMean))
+	for i, suggestion := range didYouMean {
+		links[i] = fmt.Sprintf(`<a href="#" class="text-indigo-500 hover:text-indigo-700 font-semibold"
+		        hx-get="%s?q=%s" hx-target="closest .did-you-mean" hx-swap="outerHTML">%s</a>`,
+			path, url.QueryEscape(suggestion), html.EscapeString(suggestion))
+	}
+	fmt.Fprintf(w, `<div class="did-you-mean col-span-full text-center py-12 text-gray-500">
+			<p class="text-xl mb-2">😢 No anime found</p>
+			<p>Did you mean %s?</p>
+		</div>`, strings.Join(links, " or "))
 }
 
 // renderSearchResults renders search results as cards
@@ -1288,14 +1471,64 @@ func SaveImagesHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, true, "Images saved successfully", nil, "")
 }
 
+// GetRandomAnimeHandler picks anime at random among those matching the browse filters. Without
+// "count" it answers with one anime, as it always has; with it, with a list of distinct picks.
+// X-Random-Seed carries the seed, which replays the same picks when passed back as "seed".
 func GetRandomAnimeHandler(w http.ResponseWriter, r *http.Request) {
-	randomAnime := services.GetRandomAnime()
-	if randomAnime == nil {
+	query, info, userID, err := browseParams(r)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	values := r.URL.Query()
+	opts := services.RandomOptions{
+		Count:       1,
+		Seed:        rand.Int63(),
+		ExcludeList: values.Get("exclude_list") == "true",
+		PlanToWatch: values.Get("plan_to_watch") == "true",
+	}
+	if raw := values.Get("count"); raw != "" {
+		count, err := strconv.Atoi(raw)
+		if err != nil || count < 1 {
+			sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "count must be a positive number")
+			return
+		}
+		opts.Count = min(count, services.RANDOM_MAX_PICKS)
+	}
+	if raw := values.Get("seed"); raw != "" {
+		seed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "seed must be a whole number")
+			return
+		}
+		opts.Seed = seed
+	}
+	if (opts.ExcludeList || opts.PlanToWatch) && userID == "" {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Sign in to pick around your list")
+		return
+	}
+	if opts.ExcludeList && opts.PlanToWatch {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "exclude_list and plan_to_watch cannot be combined")
+		return
+	}
+
+	picks, err := services.GetRandomAnimes(query, userID, info, opts)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to pick random anime")
+		return
+	}
+	w.Header().Set("X-Random-Seed", strconv.FormatInt(opts.Seed, 10))
+
+	if values.Get("count") != "" {
+		sendJSONResponse(w, http.StatusOK, true, "Random anime retrieved", picks, "")
+		return
+	}
+	if len(picks) == 0 {
 		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "No anime found")
 		return
 	}
-	
-	sendJSONResponse(w, http.StatusOK, true, "Random anime retrieved", randomAnime, "")
+	sendJSONResponse(w, http.StatusOK, true, "Random anime retrieved", picks[0], "")
 }
 
 func GetTop2025AnimesHandler(w http.ResponseWriter, r *http.Request) {
@@ -1373,60 +1606,3 @@ func renderHeroCards(w http.ResponseWriter, animes []primitive.M) {
 			imageUrl, name, name, episodes)
 	}
 }
-
-func GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
-	day := r.URL.Query().Get("day")
-	if day == "" {
-		day = time.Now().Weekday().String()
-	}
-	
-	schedule := services.GetDailySchedule(day)
-	
-	// Check if request wants HTML (HTMX)
-	if r.Header.Get("HX-Request") == "true" {
-		w.Header().Set("Content-Type", "text/html")
-		renderSchedule(w, schedule)
-		return
-	}
-	
-	sendJSONResponse(w, http.StatusOK, true, "Schedule retrieved", schedule, "")
-}
-
-func renderSchedule(w http.ResponseWriter, schedule []map[string]interface{}) {
-	if len(schedule) == 0 {
-		fmt.Fprintf(w, `<div class="text-center py-8 text-gray-500">
-			<p>No scheduled anime for today</p>
-		</div>`)
-		return
-	}
-	
-	for _, item := range schedule {
-		name := ""
-		time := ""
-		episode := ""
-		
-		if n, ok := item["name"].(string); ok {
-			name = n
-		}
-		if t, ok := item["time"].(string); ok {
-			time = t
-		}
-		if e, ok := item["episode"].(string); ok {
-			episode = e
-		}
-		
-		fmt.Fprintf(w, `
-		<div class="flex items-center justify-between p-4 hover:bg-gray-50 rounded-xl transition-colors cursor-pointer border-l-4 border-primary"
-		     onclick="showAnimeModal('%s')">
-		    <div class="flex-1">
-		        <h3 class="font-semibold text-gray-800 mb-1">%s</h3>
-		        <p class="text-gray-500 text-sm">%s</p>
-		    </div>
-		    <div class="text-right">
-		        <span class="bg-gradient-to-r from-indigo-500 to-purple-600 text-white px-3 py-1 rounded-full text-sm font-medium">%s</span>
-		    </div>
-		</div>`,
-			strings.ReplaceAll(strings.ToLower(name), " ", "-"),
-			name, episode, time)
-	}
-}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"animeverse/middleware"
+	"animeverse/services"
+)
+
+func UnsubscribeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	err := services.Unsubscribe(query.Get("u"), query.Get("c"), query.Get("t"))
+
+	w.Header().Set("Content-Type", "text/html")
+	message := "You have been unsubscribed from AnimeVerse weekly digests."
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		message = "This unsubscribe link is invalid."
+	}
+
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>Email preferences - AnimeVerse</title>
+	<script src="https://cdn.tailwindcss.com"></script>
+</head>
+<body class="bg-gray-50 text-gray-800">
+	<div class="max-w-md mx-auto py-24 px-4 text-center">
+		<h1 class="text-2xl font-bold mb-4">Email preferences</h1>
+		<p class="text-gray-600">%s</p>
+		<a href="/" class="inline-block mt-8 text-indigo-500 hover:text-indigo-700">Back to AnimeVerse</a>
+	</div>
+</body>
+</html>`, message)
+}
+
+func UpdateEmailPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req struct {
+		WeeklyDigest bool `json:"weekly_digest"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	if err := services.SetDigestSubscription(claims.Sub, req.WeeklyDigest); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Email preferences updated successfully", req, "")
+}
+
+func SendWeeklyDigestsHandler(w http.ResponseWriter, r *http.Request) {
+	sent, err := services.SendWeeklyDigests()
+	if err == services.ErrEmailDisabled {
+		sendJSONResponse(w, http.StatusServiceUnavailable, false, "", nil, err.Error())
+		return
+	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to send weekly digests")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, fmt.Sprintf("Queued %d weekly digests", sent), map[string]int{"queued": sent}, "")
+}
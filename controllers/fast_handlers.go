@@ -4,16 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"strconv"
+
+	"animeverse/services"
 )
 
 // GetFastBrowseHandler handles fast browse requests
 func GetFastBrowseHandler(w http.ResponseWriter, r *http.Request) {
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil {
-			page = parsed
-		}
+	page, ok := readAniListPage(w, r, 24)
+	if !ok {
+		return
 	}
 
 	animes, err := fetchEnhancedAnimeData("", page)
@@ -22,21 +21,20 @@ func GetFastBrowseHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setNextPage(w, r, page.NextOffset(len(animes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"data":    animes,
-		"page":    page,
+		"page":    page.AniListPage(),
 	})
 }
 
 // GetFastTopRatedHandler handles fast top-rated requests
 func GetFastTopRatedHandler(w http.ResponseWriter, r *http.Request) {
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil {
-			page = parsed
-		}
+	page, ok := readAniListPage(w, r, 24)
+	if !ok {
+		return
 	}
 
 	animes, err := fetchTopRatedAnimeData(page)
@@ -45,11 +43,12 @@ func GetFastTopRatedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setNextPage(w, r, page.NextOffset(len(animes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"data":    animes,
-		"page":    page,
+		"page":    page.AniListPage(),
 	})
 }
 
@@ -61,11 +60,9 @@ func GetFastSearchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil {
-			page = parsed
-		}
+	page, ok := readAniListPage(w, r, 24)
+	if !ok {
+		return
 	}
 
 	animes, err := fetchEnhancedAnimeData(query, page)
@@ -74,21 +71,23 @@ func GetFastSearchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setNextPage(w, r, page.NextOffset(len(animes)))
+	logSearch(w, page, query, services.SEARCH_SOURCE_FAST, int64(len(animes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"data":    animes,
 		"query":   query,
-		"page":    page,
+		"page":    page.AniListPage(),
 	})
 }
 
 // fetchEnhancedAnimeData fetches anime with high-quality images and metadata
-func fetchEnhancedAnimeData(search string, page int) ([]map[string]interface{}, error) {
+func fetchEnhancedAnimeData(search string, page services.PageRequest) ([]map[string]interface{}, error) {
 	graphqlQuery := `
-	query ($page: Int, $search: String) {
-		Page(page: $page, perPage: 24) {
-			media(search: $search, type: ANIME, sort: POPULARITY_DESC) {
+	query ($page: Int, $perPage: Int, $search: String, $sort: [MediaSort]) {
+		Page(page: $page, perPage: $perPage) {
+			media(search: $search, type: ANIME, sort: $sort) {
 				id
 				title {
 					romaji
@@ -124,7 +123,9 @@ func fetchEnhancedAnimeData(search string, page int) ([]map[string]interface{},
 	}`
 
 	variables := map[string]interface{}{
-		"page": page,
+		"page":    page.AniListPage(),
+		"perPage": page.Limit,
+		"sort":    []string{page.AniListSort("POPULARITY_DESC")},
 	}
 	if search != "" {
 		variables["search"] = search
@@ -166,11 +167,11 @@ func fetchEnhancedAnimeData(search string, page int) ([]map[string]interface{},
 }
 
 // fetchTopRatedAnimeData fetches top-rated anime
-func fetchTopRatedAnimeData(page int) ([]map[string]interface{}, error) {
+func fetchTopRatedAnimeData(page services.PageRequest) ([]map[string]interface{}, error) {
 	graphqlQuery := `
-	query ($page: Int) {
-		Page(page: $page, perPage: 24) {
-			media(type: ANIME, sort: SCORE_DESC) {
+	query ($page: Int, $perPage: Int, $sort: [MediaSort]) {
+		Page(page: $page, perPage: $perPage) {
+			media(type: ANIME, sort: $sort) {
 				id
 				title {
 					romaji
@@ -204,7 +205,9 @@ func fetchTopRatedAnimeData(page int) ([]map[string]interface{}, error) {
 	requestBody := map[string]interface{}{
 		"query": graphqlQuery,
 		"variables": map[string]interface{}{
-			"page": page,
+			"page":    page.AniListPage(),
+			"perPage": page.Limit,
+			"sort":    []string{page.AniListSort("SCORE_DESC")},
 		},
 	}
 
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// publicUserSummaries strips private fields before listing users
+func publicUserSummaries(users []model.User) []map[string]interface{} {
+	summaries := []map[string]interface{}{}
+	for _, u := range users {
+		summaries = append(summaries, map[string]interface{}{
+			"user_id":  u.SupabaseID,
+			"username": u.Username,
+			"name":     u.Name,
+		})
+	}
+	return summaries
+}
+
+func FollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	target, err := services.ResolveUser(chi.URLParam(r, "id"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	if err := services.FollowUser(claims.Sub, target.SupabaseID); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Failed to follow user: "+err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "User followed successfully", map[string]string{"followee_id": target.SupabaseID}, "")
+}
+
+func UnfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	target, err := services.ResolveUser(chi.URLParam(r, "id"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	if err := services.UnfollowUser(claims.Sub, target.SupabaseID); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to unfollow user")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "User unfollowed successfully", nil, "")
+}
+
+func GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	target, err := services.ResolveUser(chi.URLParam(r, "id"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	page, ok := readListPage(w, r, "newest", 50)
+	if !ok {
+		return
+	}
+
+	users, next, err := services.GetFollowersPage(target.SupabaseID, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get followers")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Followers retrieved successfully", publicUserSummaries(users), "")
+}
+
+func GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	target, err := services.ResolveUser(chi.URLParam(r, "id"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	page, ok := readListPage(w, r, "newest", 50)
+	if !ok {
+		return
+	}
+
+	users, next, err := services.GetFollowingPage(target.SupabaseID, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get following")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Following retrieved successfully", publicUserSummaries(users), "")
+}
+
+func CompareUsersHandler(w http.ResponseWriter, r *http.Request) {
+	userA, errA := services.ResolveUser(chi.URLParam(r, "a"))
+	userB, errB := services.ResolveUser(chi.URLParam(r, "b"))
+	if errA != nil || errB != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	// Comparing exposes both lists, so both must be visible to the viewer
+	viewerID := viewerIDFromRequest(r)
+	if !services.CanViewList(userA.SupabaseID, viewerID) || !services.CanViewList(userB.SupabaseID, viewerID) {
+		sendJSONResponse(w, http.StatusForbidden, false, "", nil, "One of these lists is private")
+		return
+	}
+
+	compatibility, err := services.CompareUsers(userA.SupabaseID, userB.SupabaseID)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to compare users")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Compatibility computed successfully", compatibility, "")
+}
+
+func GetFriendsAnimeHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	animeName := strings.ReplaceAll(chi.URLParam(r, "animeName"), "-", " ")
+
+	entries, err := services.GetFriendsEntriesForAnime(claims.Sub, animeName)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get friends' entries")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Friends' entries retrieved successfully", entries, "")
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+func CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req struct {
+		TargetType model.ReportTargetType `json:"target_type"`
+		TargetID   string                 `json:"target_id"`
+		Reason     string                 `json:"reason"`
+		Details    string                 `json:"details"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	report, err := services.CreateReport(claims.Sub, req.TargetType, req.TargetID, req.Reason, req.Details)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, true, "Report submitted successfully", report, "")
+}
+
+func GetModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := readListPage(w, r, "oldest", 50)
+	if !ok {
+		return
+	}
+
+	queue, next, err := services.GetModerationQueue(r.URL.Query().Get("status"), page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get moderation queue")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Moderation queue retrieved successfully", queue, "")
+}
+
+func ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user").(*middleware.SupabaseClaims)
+
+	var req services.ModerationInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	entry, err := services.ResolveReport(claims.Sub, chi.URLParam(r, "id"), req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Moderation action applied", entry, "")
+}
+
+func ModerateTargetHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user").(*middleware.SupabaseClaims)
+
+	var req services.ModerationInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	entry, err := services.ModerateTarget(claims.Sub, req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Moderation action applied", entry, "")
+}
+
+func GetModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := readListPage(w, r, "newest", 50)
+	if !ok {
+		return
+	}
+
+	entries, next, err := services.GetModerationLog(r.URL.Query().Get("user_id"), page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get moderation log")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Moderation log retrieved successfully", entries, "")
+}
+
+func GetWordFilterHandler(w http.ResponseWriter, r *http.Request) {
+	sendJSONResponse(w, http.StatusOK, true, "Word filter retrieved successfully", services.GetWordFilter(), "")
+}
+
+func UpdateWordFilterHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Words []string `json:"words"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	if err := services.SetWordFilter(req.Words); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to update word filter")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Word filter updated successfully", services.GetWordFilter(), "")
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	page, ok := readListPage(w, r, "newest", 20)
+	if !ok {
+		return
+	}
+
+	notifications, next, err := services.GetNotifications(claims.Sub, unreadOnly, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Notifications retrieved successfully", notifications, "")
+}
+
+func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	if err := services.MarkNotificationRead(claims.Sub, chi.URLParam(r, "id")); err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Notification not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Notification marked as read", nil, "")
+}
+
+func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	updated, err := services.MarkAllNotificationsRead(claims.Sub)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to update notifications")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "All notifications marked as read", map[string]int64{"updated": updated}, "")
+}
+
+func GetAlertPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	userData, err := services.GetUserBySupabaseID(claims.Sub)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Alert preferences retrieved successfully", userData.Alerts, "")
+}
+
+func UpdateAlertPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req model.AlertPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	updated, err := services.UpdateAlertPreferences(claims.Sub, req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Alert preferences updated successfully", updated.Alerts, "")
+}
+
+func MuteAnimeAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	setAnimeAlertsMuted(w, r, true)
+}
+
+func UnmuteAnimeAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	setAnimeAlertsMuted(w, r, false)
+}
+
+func setAnimeAlertsMuted(w http.ResponseWriter, r *http.Request, muted bool) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	updated, err := services.SetAnimeAlertsMuted(claims.Sub, chi.URLParam(r, "id"), muted)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Alert preferences updated successfully", updated.Alerts, "")
+}
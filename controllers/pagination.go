@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"animeverse/services"
+)
+
+// ANILIST_PAGE_LIMIT is the largest page AniList serves
+const ANILIST_PAGE_LIMIT = 50
+
+// readPage parses the shared pagination parameters, answering 400 itself when they are invalid
+func readPage(w http.ResponseWriter, r *http.Request, defaultSort string, defaultLimit int) (services.PageRequest, bool) {
+	return parsePage(w, r, defaultSort, defaultLimit, services.MAX_PAGE_LIMIT)
+}
+
+// readAniListPage is readPage for endpoints served from AniList, whose pages are smaller and
+// numbered, so an offset must fall on a page boundary
+func readAniListPage(w http.ResponseWriter, r *http.Request, defaultLimit int) (services.PageRequest, bool) {
+	page, ok := parsePage(w, r, "", defaultLimit, ANILIST_PAGE_LIMIT)
+	if ok && !page.PageAligned() {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "offset must be a multiple of limit")
+		return page, false
+	}
+	return page, ok
+}
+
+// readListPage parses limit and cursor for a list in one of the services' fixed orders
+func readListPage(w http.ResponseWriter, r *http.Request, order string, defaultLimit int) (services.PageRequest, bool) {
+	page, err := services.ParseListPage(r.URL.Query(), order, defaultLimit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return page, false
+	}
+	return page, true
+}
+
+func parsePage(w http.ResponseWriter, r *http.Request, defaultSort string, defaultLimit, maxLimit int) (services.PageRequest, bool) {
+	page, err := services.ParsePageRequest(r.URL.Query(), defaultSort, defaultLimit, maxLimit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return page, false
+	}
+	return page, true
+}
+
+// setNextPage points the client at the next page with a Link header and X-Next-Cursor.
+// The last page has neither.
+func setNextPage(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Del("page")
+	query.Del("offset")
+	query.Set("cursor", next)
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
+	w.Header().Set("X-Next-Cursor", next)
+}
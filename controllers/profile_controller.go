@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// viewerIDFromRequest returns the authenticated user's ID, or "" for anonymous requests
+func viewerIDFromRequest(r *http.Request) string {
+	if user := r.Context().Value("user"); user != nil {
+		return user.(*middleware.SupabaseClaims).Sub
+	}
+	return ""
+}
+
+func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req services.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	// Make sure the user document exists before updating it
+	if _, err := services.CreateOrUpdateUser(claims.Sub, claims.Email, claims.Name); err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get user data")
+		return
+	}
+
+	updated, err := services.UpdateUserProfile(claims.Sub, req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Profile updated successfully", updated, "")
+}
+
+func GetPublicProfileHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := readPage(w, r, "recent", services.DEFAULT_PAGE_LIMIT)
+	if !ok {
+		return
+	}
+
+	profile, next, err := services.GetPublicProfile(chi.URLParam(r, "username"), viewerIDFromRequest(r), page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Profile retrieved successfully", profile, "")
+}
+
+func ServePublicProfileHandler(w http.ResponseWriter, r *http.Request) {
+	page := services.PageRequest{Limit: services.MAX_PAGE_LIMIT, Sort: "recent"}
+	profile, _, err := services.GetPublicProfile(chi.URLParam(r, "username"), viewerIDFromRequest(r), page)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	renderPublicProfile(w, profile)
+}
+
+// renderPublicProfile renders a privacy-filtered profile page
+func renderPublicProfile(w http.ResponseWriter, profile *model.PublicProfile) {
+	displayName := profile.Name
+	if displayName == "" {
+		displayName = profile.Username
+	}
+
+	avatar := ""
+	if profile.AvatarUrl != "" {
+		avatar = fmt.Sprintf(`<img src="%s" alt="%s" class="w-24 h-24 rounded-full object-cover shadow-lg">`,
+			html.EscapeString(profile.AvatarUrl), html.EscapeString(displayName))
+	}
+
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>%s - AnimeVerse</title>
+	<script src="https://cdn.tailwindcss.com"></script>
+</head>
+<body class="bg-gray-50 text-gray-800">
+	<div class="max-w-4xl mx-auto py-12 px-4">
+		<div class="flex items-center space-x-6 mb-8">
+			%s
+			<div>
+				<h1 class="text-3xl font-bold">%s</h1>
+				<p class="text-gray-500">@%s · %d followers · %d following</p>
+			</div>
+		</div>
+		<p class="text-gray-600 mb-8">%s</p>`,
+		html.EscapeString(displayName), avatar, html.EscapeString(displayName),
+		html.EscapeString(profile.Username), profile.Followers, profile.Following,
+		html.EscapeString(profile.Bio))
+
+	if profile.Stats != nil {
+		fmt.Fprintf(w, `
+		<div class="grid grid-cols-3 md:grid-cols-6 gap-4 mb-8 text-center">
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">Total</p></div>
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">Watching</p></div>
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">Completed</p></div>
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">On Hold</p></div>
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">Dropped</p></div>
+			<div class="bg-white rounded-xl p-4 shadow"><p class="text-2xl font-bold">%d</p><p class="text-xs text-gray-500">Planned</p></div>
+		</div>`,
+			profile.Stats.TotalAnimes, profile.Stats.WatchingCount, profile.Stats.CompletedCount,
+			profile.Stats.OnHoldCount, profile.Stats.DroppedCount, profile.Stats.PlanToWatchCount)
+	}
+
+	if len(profile.Favorites) > 0 {
+		favorites := make([]string, len(profile.Favorites))
+		for i, fav := range profile.Favorites {
+			favorites[i] = fmt.Sprintf(`<span class="bg-indigo-100 text-indigo-700 px-3 py-1 rounded-full text-sm">%s</span>`, html.EscapeString(fav))
+		}
+		fmt.Fprintf(w, `
+		<h2 class="text-2xl font-bold mb-4">Favorites</h2>
+		<div class="flex flex-wrap gap-2 mb-8">%s</div>`, strings.Join(favorites, ""))
+	}
+
+	if len(profile.List) > 0 {
+		fmt.Fprintf(w, `
+		<h2 class="text-2xl font-bold mb-4">Anime List</h2>
+		<div class="bg-white rounded-xl shadow divide-y">`)
+		for _, anime := range profile.List {
+			score := "-"
+			if anime.Score > 0 {
+				score = fmt.Sprintf("%.0f★", anime.Score)
+			}
+			fmt.Fprintf(w, `
+			<div class="flex justify-between p-4"><span class="font-medium">%s</span><span class="text-gray-500 capitalize">%s · %s</span></div>`,
+				html.EscapeString(anime.Name), html.EscapeString(string(anime.Status)), score)
+		}
+		fmt.Fprintf(w, `
+		</div>`)
+	}
+
+	if len(profile.Hidden) > 0 {
+		fmt.Fprintf(w, `
+		<p class="text-gray-400 text-sm mt-8">Some sections of this profile are private.</p>`)
+	}
+
+	fmt.Fprintf(w, `
+	</div>
+</body>
+</html>`)
+}
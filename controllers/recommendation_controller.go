@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"animeverse/middleware"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+func GetRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+
+	result, err := services.GetRecommendations(claims.Sub, limit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to build recommendations")
+		return
+	}
+
+	message := "Recommendations retrieved successfully"
+	if result.ScoredEntries == 0 {
+		message = "Score some anime on your list to get recommendations"
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, message, result, "")
+}
+
+func GetAlsoLikedHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 30 {
+		limit = l
+	}
+
+	animes, err := services.GetAlsoLiked(chi.URLParam(r, "id"), limit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Anime not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Also liked retrieved successfully", animes, "")
+}
+
+func GetSimilarAnimeHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 20 {
+		limit = l
+	}
+
+	animes, err := services.GetSimilarAnime(chi.URLParam(r, "id"), limit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Anime not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Similar anime retrieved successfully", animes, "")
+}
+
+func ComputeItemSimilaritiesHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := services.ComputeItemSimilarities()
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to compute similarities: "+err.Error())
+		return
+	}
+	sendJSONResponse(w, http.StatusOK, true, fmt.Sprintf("Computed similarities for %d anime", count), nil, "")
+}
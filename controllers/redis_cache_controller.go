@@ -8,12 +8,18 @@ import (
 )
 
 func GetTrendingFastHandler(w http.ResponseWriter, r *http.Request) {
-	animes, err := services.GetTrendingWithRedisCache()
+	page, ok := readPage(w, r, "score", 50)
+	if !ok {
+		return
+	}
+
+	animes, next, err := services.GetTrendingWithRedisCache(page)
 	if err != nil {
 		http.Error(w, "Failed to get trending anime", http.StatusInternalServerError)
 		return
 	}
 
+	setNextPage(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
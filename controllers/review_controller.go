@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"animeverse/middleware"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+func CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req services.ReviewInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	review, err := services.CreateReview(claims.Sub, claims.Name, req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, true, "Review created successfully", review, "")
+}
+
+func UpdateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req services.ReviewInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	review, err := services.UpdateReview(claims.Sub, chi.URLParam(r, "id"), req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Review updated successfully", review, "")
+}
+
+func DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	if err := services.DeleteReview(claims.Sub, chi.URLParam(r, "id")); err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Review not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Review deleted successfully", nil, "")
+}
+
+func VoteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req struct {
+		Helpful bool `json:"helpful"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	review, err := services.VoteReview(claims.Sub, chi.URLParam(r, "id"), req.Helpful)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Vote recorded successfully", review, "")
+}
+
+func GetReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review, err := services.GetReview(chi.URLParam(r, "id"))
+	if err != nil || review.Hidden {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Review not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Review retrieved successfully", review, "")
+}
+
+func GetAnimeReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	page, ok := readListPage(w, r, "helpful", 10)
+	if !ok {
+		return
+	}
+
+	reviews, next, err := services.GetReviewsForAnime(chi.URLParam(r, "id"), page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Failed to get reviews")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Reviews retrieved successfully", reviews, "")
+}
+
+func GetUserReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	target, err := services.ResolveUser(chi.URLParam(r, "id"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "User not found")
+		return
+	}
+
+	page, ok := readListPage(w, r, "newest", 10)
+	if !ok {
+		return
+	}
+
+	reviews, next, err := services.GetReviewsByUser(target.SupabaseID, page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get reviews")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Reviews retrieved successfully", reviews, "")
+}
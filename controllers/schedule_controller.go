@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"time"
+
+	"animeverse/middleware"
+	"animeverse/services"
+)
+
+// GetScheduleHandler serves the week's airing schedule, optionally for one day (?day=monday),
+// in a timezone (?tz=Europe/Berlin) and limited to the caller's list (?mine=true)
+func GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	serveSchedule(w, r, r.URL.Query().Get("day"))
+}
+
+// GetTodayScheduleHandler serves today's airing schedule unless another day is requested
+func GetTodayScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	day := r.URL.Query().Get("day")
+	if day == "" {
+		day = "today"
+	}
+	serveSchedule(w, r, day)
+}
+
+func serveSchedule(w http.ResponseWriter, r *http.Request, day string) {
+	query := services.ScheduleQuery{Day: day}
+
+	var claims *middleware.SupabaseClaims
+	if user := r.Context().Value("user"); user != nil {
+		claims = user.(*middleware.SupabaseClaims)
+	}
+
+	if r.URL.Query().Get("mine") == "true" {
+		if claims == nil {
+			sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+			return
+		}
+		query.UserID = claims.Sub
+	}
+
+	// An explicit timezone wins, then the one saved in the user's alert preferences
+	tz := r.URL.Query().Get("tz")
+	if tz == "" && claims != nil {
+		if userData, err := services.GetUserBySupabaseID(claims.Sub); err == nil {
+			tz = userData.Alerts.Timezone
+		}
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Unknown timezone: "+tz)
+			return
+		}
+		query.Location = loc
+	}
+
+	schedule, err := services.GetWeeklySchedule(query)
+	if err != nil {
+		if err == services.ErrUnknownScheduleDay {
+			sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+			return
+		}
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to load schedule")
+		return
+	}
+
+	// Check if request wants HTML (HTMX)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html")
+		renderSchedule(w, schedule)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Schedule retrieved", schedule, "")
+}
+
+func renderSchedule(w http.ResponseWriter, schedule *services.WeeklySchedule) {
+	empty := true
+	for _, day := range schedule.Days {
+		for _, episode := range day.Episodes {
+			empty = false
+
+			badge := episode.LocalTime
+			if !episode.Aired {
+				badge = fmt.Sprintf("%s · in %s", episode.LocalTime, formatCountdown(episode.CountdownSeconds))
+			}
+			if len(schedule.Days) > 1 {
+				badge = day.Weekday[:3] + " " + badge
+			}
+
+			fmt.Fprintf(w, `
+		<a href="/static/anime-detail.html?name=%s"
+		   class="flex items-center justify-between p-4 hover:bg-gray-50 rounded-xl transition-colors border-l-4 border-primary">
+		    <div class="flex items-center space-x-4 min-w-0">
+		        <img src="%s" alt="%s" class="w-12 h-16 object-cover rounded-lg">
+		        <div class="min-w-0">
+		            <h3 class="font-semibold text-gray-800 mb-1 truncate">%s</h3>
+		            <p class="text-gray-500 text-sm">Episode %d</p>
+		        </div>
+		    </div>
+		    <span class="bg-gradient-to-r from-indigo-500 to-purple-600 text-white px-3 py-1 rounded-full text-sm font-medium whitespace-nowrap">%s</span>
+		</a>`,
+				url.QueryEscape(episode.Title),
+				html.EscapeString(episode.ImageUrl),
+				html.EscapeString(episode.Title),
+				html.EscapeString(episode.Title),
+				episode.Episode,
+				html.EscapeString(badge))
+		}
+	}
+
+	if empty {
+		fmt.Fprintf(w, `<div class="text-center py-8 text-gray-500">
+			<p>No scheduled anime</p>
+		</div>`)
+	}
+}
+
+// formatCountdown renders a duration in seconds as "2d 4h", "3h 15m" or "12m"
+func formatCountdown(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// logSearch records the first page of a catalog search and hands the client the ID to report
+// clicks on its results against, in X-Search-ID. Later pages of the same search are not logged.
+func logSearch(w http.ResponseWriter, page services.PageRequest, text, source string, results int64) {
+	if page.Offset > 0 || page.After != nil {
+		return
+	}
+	if id := services.LogSearch(text, source, results); id != "" {
+		w.Header().Set("X-Search-ID", id)
+	}
+}
+
+// RecordSearchClickHandler records which result of a logged search was opened
+func RecordSearchClickHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResultID string `json:"result_id"`
+		Rank     int    `json:"rank"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	if err := services.RecordSearchClick(chi.URLParam(r, "id"), req.ResultID, req.Rank); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Click recorded", nil, "")
+}
+
+func GetSearchReportHandler(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = d
+	}
+	limit := 20
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	report, err := services.GetSearchReport(time.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to build search report")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Search report retrieved successfully", report, "")
+}
+
+func ImportSearchTermHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query string `json:"query"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	result, err := services.ImportSearchTerm(req.Query)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Search term imported", result, "")
+}
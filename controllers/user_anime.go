@@ -94,6 +94,34 @@ func UpdateAnimeScoreHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, http.StatusOK, true, "Score updated successfully", anime, "")
 }
 
+func UpdateAnimeProgressHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	animeID := chi.URLParam(r, "id")
+
+	var req struct {
+		Watched int `json:"watched"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	anime, err := services.UpdateAnimeProgress(claims.Sub, animeID, req.Watched)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Failed to update progress: "+err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Progress updated successfully", anime, "")
+}
+
 func RemoveAnimeHandler(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value("user")
 	if user == nil {
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"animeverse/middleware"
+	model "animeverse/models"
+	"animeverse/services"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Personal webhooks under /api/user/webhooks
+
+func CreateUserWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	createWebhook(w, r, model.WebhookScopeUser)
+}
+
+func GetUserWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	getWebhooks(w, r, model.WebhookScopeUser)
+}
+
+func UpdateUserWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	updateWebhook(w, r, model.WebhookScopeUser)
+}
+
+func DeleteUserWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	deleteWebhook(w, r, model.WebhookScopeUser)
+}
+
+func GetUserWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	getWebhookDeliveries(w, r, model.WebhookScopeUser)
+}
+
+func RedeliverUserWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	redeliverWebhook(w, r, model.WebhookScopeUser)
+}
+
+// Catalog webhooks under /api/admin/webhooks
+
+func CreateAdminWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	createWebhook(w, r, model.WebhookScopeAdmin)
+}
+
+func GetAdminWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	getWebhooks(w, r, model.WebhookScopeAdmin)
+}
+
+func UpdateAdminWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	updateWebhook(w, r, model.WebhookScopeAdmin)
+}
+
+func DeleteAdminWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	deleteWebhook(w, r, model.WebhookScopeAdmin)
+}
+
+func GetAdminWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	getWebhookDeliveries(w, r, model.WebhookScopeAdmin)
+}
+
+func RedeliverAdminWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	redeliverWebhook(w, r, model.WebhookScopeAdmin)
+}
+
+func createWebhook(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req services.WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	webhook, err := services.CreateWebhook(scope, claims.Sub, req)
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusCreated, true, "Webhook created. Store the secret now, it won't be shown again", webhook, "")
+}
+
+func getWebhooks(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	webhooks, err := services.GetWebhooks(scope, claims.Sub)
+	if err != nil {
+		sendJSONResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to get webhooks")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Webhooks retrieved successfully", webhooks, "")
+}
+
+func updateWebhook(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	var req services.WebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request")
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(scope, claims.Sub, chi.URLParam(r, "id"), req)
+	if err == mongo.ErrNoDocuments {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Webhook not found")
+		return
+	}
+	if err != nil {
+		sendJSONResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Webhook updated successfully", webhook, "")
+}
+
+func deleteWebhook(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	if err := services.DeleteWebhook(scope, claims.Sub, chi.URLParam(r, "id")); err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Webhook not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, true, "Webhook deleted successfully", nil, "")
+}
+
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+	page, ok := readListPage(w, r, "newest", 20)
+	if !ok {
+		return
+	}
+
+	deliveries, next, err := services.GetWebhookDeliveries(scope, claims.Sub, chi.URLParam(r, "id"), page)
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Webhook not found")
+		return
+	}
+
+	setNextPage(w, r, next)
+	sendJSONResponse(w, http.StatusOK, true, "Deliveries retrieved successfully", deliveries, "")
+}
+
+func redeliverWebhook(w http.ResponseWriter, r *http.Request, scope model.WebhookScope) {
+	user := r.Context().Value("user")
+	if user == nil {
+		sendJSONResponse(w, http.StatusUnauthorized, false, "", nil, "Not authenticated")
+		return
+	}
+
+	claims := user.(*middleware.SupabaseClaims)
+
+	delivery, err := services.RedeliverWebhook(scope, claims.Sub, chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		sendJSONResponse(w, http.StatusNotFound, false, "", nil, "Delivery not found")
+		return
+	}
+
+	sendJSONResponse(w, http.StatusAccepted, true, "Redelivery queued", delivery, "")
+}
@@ -28,6 +28,32 @@ func main() {
 	// Initialize image collection
 	services.InitImageCollection()
 
+	// Initialize social collections
+	services.EnsureUserIndexes()
+	services.InitFollowCollection()
+	services.InitReviewCollections()
+	services.InitCommentCollections()
+	services.InitModerationCollections()
+	services.InitNotificationCollection()
+	services.InitEpisodeAlertCollection()
+	services.InitWebhookCollections()
+	services.InitItemSimilarityCollection()
+	services.InitContentSimilarityCollection()
+	services.InitAiringScheduleCollection()
+	services.InitSearchLogCollection()
+	services.EnsureSearchIndexes()
+
+	// Start background job processing
+	go services.GlobalJobQueue.ProcessJobs()
+	go services.StartEpisodeAlertScheduler(5 * time.Minute)
+	go services.StartAiringScheduleScheduler(time.Hour)
+	go services.StartWeeklyDigestScheduler()
+	go services.StartItemSimilarityScheduler(6 * time.Hour)
+	go services.StartSimilarIndex()
+	go services.StartSuggestIndex()
+	go services.StartSpellingIndex()
+	go services.RunBackfills()
+
 	// Setup router
 	r := router.Router()
 
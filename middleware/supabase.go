@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"animeverse/services"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -14,6 +15,7 @@ type SupabaseClaims struct {
 	Email string `json:"email"`
 	Name  string `json:"name"`
 	Role  string `json:"role"`
+	Muted bool   `json:"-"` // Set from moderation state, never from the token
 	jwt.RegisteredClaims
 }
 
@@ -42,6 +44,11 @@ func SupabaseAuth(next http.Handler) http.Handler {
 		}
 
 		claims := token.Claims.(*SupabaseClaims)
+		if !applyModerationState(claims) {
+			http.Error(w, "Account suspended", http.StatusForbidden)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "user", claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -65,7 +72,8 @@ func OptionalSupabaseAuth(next http.Handler) http.Handler {
 			return []byte(os.Getenv("SUPABASE_JWT_SECRET")), nil
 		})
 
-		if err == nil && token.Valid {
+		// Banned users are treated as anonymous on public routes
+		if err == nil && token.Valid && applyModerationState(token.Claims.(*SupabaseClaims)) {
 			claims := token.Claims.(*SupabaseClaims)
 			ctx := context.WithValue(r.Context(), "user", claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -96,4 +104,23 @@ func AdminOnly(next http.Handler) http.Handler {
 
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// applyModerationState marks muted users on their claims and returns false for banned users
+func applyModerationState(claims *SupabaseClaims) bool {
+	banned, muted := services.GetModerationState(claims.Sub)
+	claims.Muted = muted
+	return !banned
+}
+
+// NotMuted blocks muted users from posting or editing user content
+func NotMuted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.Context().Value("user"); user != nil && user.(*SupabaseClaims).Muted {
+			http.Error(w, "You are muted and cannot post right now", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -85,18 +85,85 @@ type UserStats struct {
 	LastUpdated      time.Time `json:"last_updated" bson:"last_updated"`
 }
 
+// PrivacyLevel controls who can see a section of a user's profile
+type PrivacyLevel string
+
+const (
+	PrivacyPublic    PrivacyLevel = "public"
+	PrivacyFollowers PrivacyLevel = "followers"
+	PrivacyPrivate   PrivacyLevel = "private"
+)
+
+// PrivacySettings holds per-section visibility (empty means public)
+type PrivacySettings struct {
+	Profile   PrivacyLevel `json:"profile,omitempty" bson:"profile,omitempty"`     // Bio and avatar
+	Favorites PrivacyLevel `json:"favorites,omitempty" bson:"favorites,omitempty"` // Favorite anime
+	Stats     PrivacyLevel `json:"stats,omitempty" bson:"stats,omitempty"`         // List statistics
+	List      PrivacyLevel `json:"list,omitempty" bson:"list,omitempty"`           // Anime list entries
+}
+
+// UserModeration holds moderation state applied to a user
+type UserModeration struct {
+	BannedUntil   *time.Time `json:"banned_until,omitempty" bson:"banned_until,omitempty"`
+	MutedUntil    *time.Time `json:"muted_until,omitempty" bson:"muted_until,omitempty"`
+	Warnings      int        `json:"warnings,omitempty" bson:"warnings,omitempty"`
+	ProfileHidden bool       `json:"profile_hidden,omitempty" bson:"profile_hidden,omitempty"`
+	ListHidden    bool       `json:"list_hidden,omitempty" bson:"list_hidden,omitempty"`
+}
+
+// AlertPreferences controls how new-episode notifications are delivered
+type AlertPreferences struct {
+	Mode            string   `json:"mode,omitempty" bson:"mode,omitempty"`                           // "instant" (default) or "digest"
+	DigestHour      *int     `json:"digest_hour,omitempty" bson:"digest_hour,omitempty"`             // Local hour the daily digest is sent, defaults to 9
+	QuietHoursStart *int     `json:"quiet_hours_start,omitempty" bson:"quiet_hours_start,omitempty"` // Local hour, inclusive
+	QuietHoursEnd   *int     `json:"quiet_hours_end,omitempty" bson:"quiet_hours_end,omitempty"`     // Local hour, exclusive
+	Timezone        string   `json:"timezone,omitempty" bson:"timezone,omitempty"`                   // IANA name, defaults to UTC
+	MutedAnime      []string `json:"muted_anime,omitempty" bson:"muted_anime,omitempty"`             // Catalog anime IDs
+}
+
+// EmailPreferences controls which optional emails a user receives
+type EmailPreferences struct {
+	DigestUnsubscribed bool   `json:"digest_unsubscribed" bson:"digest_unsubscribed"`
+	LastDigestWeek     string `json:"-" bson:"last_digest_week,omitempty"` // ISO week of the last digest, e.g. "2025-W07"
+}
+
 // User represents a user in the system
 type User struct {
 	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	SupabaseID string             `json:"supabase_id" bson:"supabase_id"`
 	Email      string             `json:"email" bson:"email"`
 	Name       string             `json:"name,omitempty" bson:"name,omitempty"`
+	Username   string             `json:"username,omitempty" bson:"username,omitempty"` // Unique, lowercase
+	Bio        string             `json:"bio,omitempty" bson:"bio,omitempty" validate:"max=500"`
+	AvatarUrl  string             `json:"avatarUrl,omitempty" bson:"avatarUrl,omitempty"`
+	Favorites  []string           `json:"favorites,omitempty" bson:"favorites,omitempty"`
+	Privacy    PrivacySettings    `json:"privacy" bson:"privacy"`
+	Moderation UserModeration     `json:"moderation,omitempty" bson:"moderation,omitempty"`
+	Alerts     AlertPreferences   `json:"alerts" bson:"alerts"`
+	EmailPrefs EmailPreferences   `json:"email_prefs" bson:"email_prefs"`
+	FeedToken  string             `json:"-" bson:"feed_token_hash,omitempty"` // SHA-256 of the private iCal feed token
 	Role       string             `json:"role" bson:"role"` // "user" or "admin"
 	Stats      UserStats          `json:"stats" bson:"stats"`
 	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// PublicProfile is the privacy-filtered view of a user shown to other users
+type PublicProfile struct {
+	UserID    string     `json:"user_id"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name,omitempty"`
+	Bio       string     `json:"bio,omitempty"`
+	AvatarUrl string     `json:"avatarUrl,omitempty"`
+	Favorites []string   `json:"favorites,omitempty"`
+	Stats     *UserStats `json:"stats,omitempty"`
+	List      []Anime    `json:"list,omitempty"`
+	Followers int        `json:"followers"`
+	Following int        `json:"following"`
+	Hidden    []string   `json:"hidden,omitempty"` // Sections withheld from this viewer
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // ImageCache represents cached image data
 type ImageCache struct {
 	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -118,6 +185,7 @@ type Anime struct {
 	Progress  Progress           `json:"progress,omitempty" bson:"progress,omitempty"`
 	Status    WatchStatus        `json:"status,omitempty" bson:"status,omitempty"`
 	Genre     []string           `json:"genre,omitempty" bson:"genre,omitempty"`
+	Tags      []string           `json:"tags,omitempty" bson:"tags,omitempty"`
 	Notes     string             `json:"notes,omitempty" bson:"notes,omitempty"`
 	Synopsis  string             `json:"synopsis,omitempty" bson:"synopsis,omitempty"`
 	BannerUrl string             `json:"bannerUrl,omitempty" bson:"bannerUrl,omitempty"`
@@ -135,11 +203,48 @@ type Anime struct {
 	Staff             []StaffMember     `json:"staff,omitempty" bson:"staff,omitempty"`
 	Themes            AnimeThemes       `json:"themes,omitempty" bson:"themes,omitempty"`
 	Related           []RelatedAnime    `json:"related,omitempty" bson:"related,omitempty"`
+	Airing            *AiringInfo       `json:"airing,omitempty" bson:"airing,omitempty"`
+	Search            *SearchTerms      `json:"-" bson:"search,omitempty"`
+	NameKey           string            `json:"-" bson:"name_key,omitempty"`   // Normalized name, for matching spelling variants
+	TitleKeys         []string          `json:"-" bson:"title_keys,omitempty"` // Normalized name and alternative titles
 	
 	CreatedAt time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
 }
 
+// AiringInfo tracks episode releases of an airing anime
+type AiringInfo struct {
+	LastAiredEpisode int        `json:"last_aired_episode" bson:"last_aired_episode"`
+	NextEpisode      int        `json:"next_episode,omitempty" bson:"next_episode,omitempty"`
+	NextAiringAt     *time.Time `json:"next_airing_at,omitempty" bson:"next_airing_at,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// AiringScheduleEntry is one scheduled episode broadcast reported by AniList
+type AiringScheduleEntry struct {
+	ID            int                 `json:"id" bson:"_id"` // AniList airing schedule ID
+	AniListID     int                 `json:"anilist_id" bson:"anilist_id"`
+	AnimeID       *primitive.ObjectID `json:"anime_id,omitempty" bson:"anime_id,omitempty"` // Catalog anime, once imported
+	Title         string              `json:"title" bson:"title"`
+	ImageUrl      string              `json:"imageUrl,omitempty" bson:"imageUrl,omitempty"`
+	Format        string              `json:"format,omitempty" bson:"format,omitempty"`
+	Episode       int                 `json:"episode" bson:"episode"`
+	TotalEpisodes int                 `json:"total_episodes,omitempty" bson:"total_episodes,omitempty"`
+	AiringAt      time.Time           `json:"airing_at" bson:"airing_at"` // UTC
+	UpdatedAt     time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// SearchTerms holds the stemmed search terms of each weighted field, derived from the titles and synopsis
+type SearchTerms struct {
+	Name      []string  `bson:"name,omitempty"`
+	English   []string  `bson:"english,omitempty"`
+	Synonyms  []string  `bson:"synonyms,omitempty"`
+	Japanese  []string  `bson:"japanese,omitempty"`
+	Synopsis  []string  `bson:"synopsis,omitempty"`
+	Version   int       `bson:"version,omitempty"` // How the terms were built, to rebuild outdated ones
+	IndexedAt time.Time `bson:"indexed_at"`
+}
+
 // AlternativeTitles represents alternative titles
 type AlternativeTitles struct {
 	Synonyms []string `json:"synonyms,omitempty" bson:"synonyms,omitempty"`
@@ -160,6 +265,16 @@ type AnimeInformation struct {
 	Source      string   `json:"source,omitempty" bson:"source,omitempty"`
 	Duration    string   `json:"duration,omitempty" bson:"duration,omitempty"`
 	Rating      string   `json:"rating,omitempty" bson:"rating,omitempty"`
+
+	// Structured values parsed from the free-text fields above
+	StartDate       *time.Time `json:"start_date,omitempty" bson:"start_date,omitempty"`
+	EndDate         *time.Time `json:"end_date,omitempty" bson:"end_date,omitempty"`
+	BroadcastDay    string     `json:"broadcast_day,omitempty" bson:"broadcast_day,omitempty"`       // English weekday, e.g. "Sunday"
+	BroadcastTime   string     `json:"broadcast_time,omitempty" bson:"broadcast_time,omitempty"`     // HH:MM in JST
+	DurationMinutes int        `json:"duration_minutes,omitempty" bson:"duration_minutes,omitempty"` // Per episode
+	PremieredSeason Season     `json:"premiered_season,omitempty" bson:"premiered_season,omitempty"`
+	PremieredYear   int        `json:"premiered_year,omitempty" bson:"premiered_year,omitempty"`
+	ParsedAt        *time.Time `json:"-" bson:"parsed_at,omitempty"`
 }
 
 // AnimeStatistics represents anime statistics
@@ -218,12 +333,251 @@ type Episode struct {
 
 // Comment represents user comments on anime
 type Comment struct {
+	ID         primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	AnimeID    primitive.ObjectID  `json:"anime_id" bson:"anime_id"`
+	Episode    int                 `json:"episode,omitempty" bson:"episode,omitempty"` // 0 for comments on the anime itself
+	ParentID   *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Depth      int                 `json:"depth" bson:"depth"`
+	UserID     string              `json:"user_id" bson:"user_id"`
+	UserName   string              `json:"user_name" bson:"user_name"`
+	Content    string              `json:"content" bson:"content" validate:"required,max=1000"`
+	Spoiler    bool                `json:"spoiler,omitempty" bson:"spoiler,omitempty"`
+	ReplyCount int                 `json:"reply_count" bson:"reply_count"`
+	Reactions  map[string]int      `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	Edited     bool                `json:"edited,omitempty" bson:"edited,omitempty"`
+	Deleted    bool                `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	Hidden     bool                `json:"hidden,omitempty" bson:"hidden,omitempty"` // Hidden by a moderator
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// CommentReaction records a single user's reaction to a comment
+type CommentReaction struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	CommentID primitive.ObjectID `json:"comment_id" bson:"comment_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Reaction  string             `json:"reaction" bson:"reaction"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// Follow represents a follower relationship between two users
+type Follow struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	FollowerID string             `json:"follower_id" bson:"follower_id"` // Supabase ID of the follower
+	FolloweeID string             `json:"followee_id" bson:"followee_id"` // Supabase ID of the followed user
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FriendEntry represents a followed user's entry for an anime
+type FriendEntry struct {
+	UserID   string      `json:"user_id"`
+	Name     string      `json:"name,omitempty"`
+	Score    float64     `json:"score,omitempty"`
+	Status   WatchStatus `json:"status,omitempty"`
+	Progress Progress    `json:"progress,omitempty"`
+}
+
+// SharedTitle represents an anime that appears on both compared lists
+type SharedTitle struct {
+	Name       string      `json:"name"`
+	ScoreA     float64     `json:"score_a,omitempty"`
+	ScoreB     float64     `json:"score_b,omitempty"`
+	StatusA    WatchStatus `json:"status_a,omitempty"`
+	StatusB    WatchStatus `json:"status_b,omitempty"`
+	Difference float64     `json:"difference"`
+}
+
+// Compatibility represents taste compatibility between two users
+type Compatibility struct {
+	UserA          string        `json:"user_a"`
+	UserB          string        `json:"user_b"`
+	Percentage     float64       `json:"percentage"`
+	Correlation    float64       `json:"correlation"`
+	SharedScored   int           `json:"shared_scored"`
+	SharedComplete int           `json:"shared_completed"`
+	Shared         []SharedTitle `json:"shared"`
+	Divergent      []SharedTitle `json:"divergent"`
+}
+
+// ReviewRatings represents per-category ratings on a 1-10 scale
+type ReviewRatings struct {
+	Story      int `json:"story" bson:"story" validate:"min=1,max=10"`
+	Animation  int `json:"animation" bson:"animation" validate:"min=1,max=10"`
+	Sound      int `json:"sound" bson:"sound" validate:"min=1,max=10"`
+	Characters int `json:"characters" bson:"characters" validate:"min=1,max=10"`
+}
+
+// ReviewRevision represents a previous version of an edited review
+type ReviewRevision struct {
+	Body     string        `json:"body" bson:"body"`
+	Ratings  ReviewRatings `json:"ratings" bson:"ratings"`
+	Spoiler  bool          `json:"spoiler,omitempty" bson:"spoiler,omitempty"`
+	EditedAt time.Time     `json:"edited_at" bson:"edited_at"`
+}
+
+// Review represents a long-form user review of an anime
+type Review struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	AnimeID     primitive.ObjectID `json:"anime_id" bson:"anime_id"`
+	AnimeName   string             `json:"anime_name" bson:"anime_name"`
+	UserID      string             `json:"user_id" bson:"user_id"`
+	UserName    string             `json:"user_name" bson:"user_name"`
+	Body        string             `json:"body" bson:"body" validate:"required,min=200,max=20000"`
+	Ratings     ReviewRatings      `json:"ratings" bson:"ratings"`
+	Overall     float64            `json:"overall" bson:"overall"`
+	Spoiler     bool               `json:"spoiler,omitempty" bson:"spoiler,omitempty"`
+	Helpful     int                `json:"helpful" bson:"helpful"`
+	NotHelpful  int                `json:"not_helpful" bson:"not_helpful"`
+	WilsonScore float64            `json:"wilson_score" bson:"wilson_score"`
+	History     []ReviewRevision   `json:"history,omitempty" bson:"history,omitempty"`
+	Hidden      bool               `json:"hidden,omitempty" bson:"hidden,omitempty"` // Hidden by a moderator
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// ReviewVote represents a user's helpfulness vote on a review
+type ReviewVote struct {
 	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	AnimeID   primitive.ObjectID `json:"anime_id" bson:"anime_id"`
+	ReviewID  primitive.ObjectID `json:"review_id" bson:"review_id"`
 	UserID    string             `json:"user_id" bson:"user_id"`
-	UserName  string             `json:"user_name" bson:"user_name"`
-	Content   string             `json:"content" bson:"content" validate:"required,max=1000"`
-	Spoiler   bool               `json:"spoiler,omitempty" bson:"spoiler,omitempty"`
+	Helpful   bool               `json:"helpful" bson:"helpful"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// ReportTargetType identifies what kind of content a report is about
+type ReportTargetType string
+
+const (
+	ReportComment ReportTargetType = "comment"
+	ReportReview  ReportTargetType = "review"
+	ReportProfile ReportTargetType = "profile"
+	ReportList    ReportTargetType = "list"
+)
+
+// Report represents a user report or automatic flag awaiting moderation
+type Report struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	TargetType ReportTargetType   `json:"target_type" bson:"target_type"`
+	TargetID   string             `json:"target_id" bson:"target_id"`
+	ReporterID string             `json:"reporter_id,omitempty" bson:"reporter_id,omitempty"` // Empty for automatic flags
+	Reason     string             `json:"reason" bson:"reason"`
+	Details    string             `json:"details,omitempty" bson:"details,omitempty" validate:"max=1000"`
+	Source     string             `json:"source" bson:"source"` // "user" or "filter"
+	Status     string             `json:"status" bson:"status"` // "open", "resolved" or "dismissed"
+	ResolvedBy string             `json:"resolved_by,omitempty" bson:"resolved_by,omitempty"`
+	ResolvedAt *time.Time         `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// ModerationLogEntry records a single moderation decision
+type ModerationLogEntry struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ModeratorID string             `json:"moderator_id" bson:"moderator_id"`
+	Action      string             `json:"action" bson:"action"`
+	TargetType  ReportTargetType   `json:"target_type" bson:"target_type"`
+	TargetID    string             `json:"target_id" bson:"target_id"`
+	UserID      string             `json:"user_id,omitempty" bson:"user_id,omitempty"` // Author of the moderated content
+	ReportID    string             `json:"report_id,omitempty" bson:"report_id,omitempty"`
+	Note        string             `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// NotificationType identifies what a notification is about
+type NotificationType string
+
+const (
+	NotificationNewEpisode NotificationType = "new_episode"
+	NotificationSequel     NotificationType = "sequel_announced"
+	NotificationReply      NotificationType = "reply"
+	NotificationFollow     NotificationType = "follow"
+)
+
+// Notification represents an entry in a user's notification inbox
+type Notification struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	Type      NotificationType   `json:"type" bson:"type"`
+	Title     string             `json:"title" bson:"title"`
+	Message   string             `json:"message" bson:"message"`
+	Link      string             `json:"link,omitempty" bson:"link,omitempty"`
+	AnimeID   string             `json:"anime_id,omitempty" bson:"anime_id,omitempty"`
+	ActorID   string             `json:"actor_id,omitempty" bson:"actor_id,omitempty"` // User who triggered it, if any
+	DedupKey  string             `json:"-" bson:"dedup_key,omitempty"`                 // Prevents delivering the same event twice
+	Read      bool               `json:"read" bson:"read"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// WebhookScope separates personal webhooks from catalog-wide admin webhooks
+type WebhookScope string
+
+const (
+	WebhookScopeUser  WebhookScope = "user"
+	WebhookScopeAdmin WebhookScope = "admin"
+)
+
+// Webhook is an outgoing HTTP callback registered for a set of events
+type Webhook struct {
+	ID                  primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OwnerID             string             `json:"owner_id" bson:"owner_id"`
+	Scope               WebhookScope       `json:"scope" bson:"scope"`
+	URL                 string             `json:"url" bson:"url"`
+	Events              []string           `json:"events" bson:"events"`
+	Secret              string             `json:"-" bson:"secret"` // Only revealed once, when the webhook is created
+	Active              bool               `json:"active" bson:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures" bson:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty" bson:"disabled_at,omitempty"`
+	DisabledReason      string             `json:"disabled_reason,omitempty" bson:"disabled_reason,omitempty"`
+	CreatedAt           time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// WebhookAttempt records one HTTP attempt at delivering a webhook payload
+type WebhookAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// WebhookDelivery is a single event sent to a webhook, along with its attempt history
+type WebhookDelivery struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	WebhookID    primitive.ObjectID `json:"webhook_id" bson:"webhook_id"`
+	Event        string             `json:"event" bson:"event"`
+	Payload      string             `json:"payload" bson:"payload"` // Exact signed request body
+	Status       string             `json:"status" bson:"status"`
+	Attempts     []WebhookAttempt   `json:"attempts" bson:"attempts"`
+	RedeliveryOf string             `json:"redelivery_of,omitempty" bson:"redelivery_of,omitempty"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	CompletedAt  *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// AnimeNeighbor is an anime scored as similar to another one
+type AnimeNeighbor struct {
+	Key     string  `json:"key" bson:"key"` // Identifies the anime across catalog and list copies
+	Name    string  `json:"name" bson:"name"`
+	Score   float64 `json:"score" bson:"score"`
+	Support int     `json:"support,omitempty" bson:"support,omitempty"` // Users the similarity is based on
+}
+
+// AnimeNeighbors holds the precomputed nearest neighbors of an anime
+type AnimeNeighbors struct {
+	Key       string          `json:"key" bson:"_id"`
+	Name      string          `json:"name" bson:"name"`
+	Neighbors []AnimeNeighbor `json:"neighbors" bson:"neighbors"`
+	UpdatedAt time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// SearchLogEntry records one search, anonymously: nothing about who searched is kept
+type SearchLogEntry struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Query       string             `json:"query" bson:"query"` // Normalized, so spelling variants count as one query
+	Text        string             `json:"text" bson:"text"`   // As typed
+	Source      string             `json:"source" bson:"source"`
+	Results     int64              `json:"results" bson:"results"`
+	ClickedID   string             `json:"clicked_id,omitempty" bson:"clicked_id,omitempty"`
+	ClickedRank int                `json:"clicked_rank,omitempty" bson:"clicked_rank,omitempty"` // 1 for the first result
+	ClickedAt   *time.Time         `json:"clicked_at,omitempty" bson:"clicked_at,omitempty"`
+	ImportedAt  *time.Time         `json:"imported_at,omitempty" bson:"imported_at,omitempty"` // When an admin imported anime for a search that found nothing
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 }
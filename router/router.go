@@ -36,6 +36,9 @@ func Router() *chi.Mux {
 	router.Get("/api-home", controller.ServeHomeHandler)
 	router.Get("/old", controller.ServeOldFrontendHandler)
 	router.Get("/health", controller.HealthCheckHandler)
+	router.With(middlewareAuth.OptionalSupabaseAuth).Get("/u/{username}", controller.ServePublicProfileHandler)
+	router.Get("/email/unsubscribe", controller.UnsubscribeEmailHandler)
+	router.Get("/calendar/{token}.ics", controller.CalendarFeedHandler)
 
 	// Static files
 	fileServer := http.FileServer(http.Dir("./static/"))
@@ -46,12 +49,15 @@ func Router() *chi.Mux {
 		r.Use(middlewareAuth.OptionalSupabaseAuth)
 		r.Get("/animes", controller.GetMyAllAnimesHandler)
 		r.Get("/animes/filter", controller.FilterAnimesHandler)
+		r.Get("/animes/facets", controller.FacetsHandler)
 		r.Get("/animes/trending", controller.GetTrendingAnimesHandler)
 		r.Get("/animes/popular", controller.GetPopularAnimesHandler)
 		r.Get("/animes/random", controller.GetRandomAnimeHandler)
 		r.Get("/animes/top2025", controller.GetTop2025AnimesHandler)
 		r.Get("/animes/preview", controller.GetPreviewAnimesHandler)
 		r.Get("/animes/search", controller.SearchAnimesHandler)
+		r.Get("/animes/suggest", controller.SuggestAnimesHandler)
+		r.Post("/animes/search/{id}/click", controller.RecordSearchClickHandler)
 		r.Get("/animes/spotlight", controller.GetSpotlightHandler)
 		r.Get("/animes/top-rated-mixed", controller.GetTopRatedMixedHandler)
 		r.Get("/animes/trending-fast", controller.GetTrendingFastHandler)
@@ -60,7 +66,8 @@ func Router() *chi.Mux {
 		r.Get("/anime/themes", controller.GetAnimeThemesHandler)
 		r.Get("/anime/hq-images", controller.GetHighQualityImagesHandler)
 		r.Get("/anime/upgrade-images", controller.UpgradeImagesHandler)
-		r.Get("/schedule/today", controller.GetScheduleHandler)
+		r.Get("/schedule", controller.GetScheduleHandler)
+		r.Get("/schedule/today", controller.GetTodayScheduleHandler)
 
 		// Fast loading endpoints with enhanced data
 		r.Get("/fast/browse", controller.GetFastBrowseHandler)
@@ -73,6 +80,28 @@ func Router() *chi.Mux {
 		r.Get("/simple/browse", controller.SimpleBrowseHandler)
 		r.Get("/images/check", controller.CheckImagesHandler)
 		r.Post("/images/save", controller.SaveImagesHandler)
+
+		// Social endpoints
+		r.Get("/users/{id}/followers", controller.GetFollowersHandler)
+		r.Get("/users/{id}/following", controller.GetFollowingHandler)
+		r.Get("/users/{a}/compare/{b}", controller.CompareUsersHandler)
+		r.Get("/users/{username}", controller.GetPublicProfileHandler)
+		r.Get("/users/{id}/reviews", controller.GetUserReviewsHandler)
+
+		// Reviews
+		r.Get("/anime/{id}/reviews", controller.GetAnimeReviewsHandler)
+		r.Get("/reviews/{id}", controller.GetReviewHandler)
+
+		// Recommendations
+		r.Get("/anime/{id}/also-liked", controller.GetAlsoLikedHandler)
+		r.Get("/anime/{id}/similar", controller.GetSimilarAnimeHandler)
+
+		// Comments (posting requires auth, checked in the handler)
+		r.Get("/anime/{id}/comments", controller.GetCommentsHandler)
+		r.With(middlewareAuth.NotMuted).Post("/anime/{id}/comments", controller.CreateCommentHandler)
+		r.Get("/anime/{id}/episodes/{episode}/comments", controller.GetCommentsHandler)
+		r.With(middlewareAuth.NotMuted).Post("/anime/{id}/episodes/{episode}/comments", controller.CreateCommentHandler)
+		r.Get("/comments/{id}/replies", controller.GetCommentRepliesHandler)
 	})
 
 	// Auth routes
@@ -87,12 +116,44 @@ func Router() *chi.Mux {
 	router.Route("/api/user", func(r chi.Router) {
 		r.Use(middlewareAuth.SupabaseAuth)
 		r.Get("/me", controller.GetCurrentUserHandler)
+		r.With(middlewareAuth.NotMuted).Put("/profile", controller.UpdateProfileHandler)
 		r.Get("/stats", controller.GetUserStatsHandler)
+		r.Get("/recommendations", controller.GetRecommendationsHandler)
+		r.Get("/calendar", controller.GetCalendarEventsHandler)
+		r.Post("/calendar", controller.CreateCalendarFeedHandler)
+		r.Delete("/calendar", controller.RevokeCalendarFeedHandler)
 		r.Post("/anime", controller.AddAnimeHandler)
 		r.Put("/anime/{id}/status", controller.UpdateAnimeStatusHandler)
 		r.Put("/anime/{id}/score", controller.UpdateAnimeScoreHandler)
+		r.Put("/anime/{id}/progress", controller.UpdateAnimeProgressHandler)
 		r.Delete("/anime/{id}", controller.RemoveAnimeHandler)
 		r.Get("/search", controller.SearchAnimeHandler)
+		r.Post("/follow/{id}", controller.FollowUserHandler)
+		r.Delete("/follow/{id}", controller.UnfollowUserHandler)
+		r.Get("/friends/anime/{animeName}", controller.GetFriendsAnimeHandler)
+		r.With(middlewareAuth.NotMuted).Post("/reviews", controller.CreateReviewHandler)
+		r.With(middlewareAuth.NotMuted).Put("/reviews/{id}", controller.UpdateReviewHandler)
+		r.Delete("/reviews/{id}", controller.DeleteReviewHandler)
+		r.Post("/reviews/{id}/vote", controller.VoteReviewHandler)
+		r.With(middlewareAuth.NotMuted).Put("/comments/{id}", controller.UpdateCommentHandler)
+		r.Delete("/comments/{id}", controller.DeleteCommentHandler)
+		r.Post("/comments/{id}/reactions/{reaction}", controller.AddCommentReactionHandler)
+		r.Delete("/comments/{id}/reactions/{reaction}", controller.RemoveCommentReactionHandler)
+		r.Post("/reports", controller.CreateReportHandler)
+		r.Get("/notifications", controller.GetNotificationsHandler)
+		r.Post("/notifications/read-all", controller.MarkAllNotificationsReadHandler)
+		r.Post("/notifications/{id}/read", controller.MarkNotificationReadHandler)
+		r.Get("/notifications/preferences", controller.GetAlertPreferencesHandler)
+		r.Put("/notifications/preferences", controller.UpdateAlertPreferencesHandler)
+		r.Post("/notifications/mute/{id}", controller.MuteAnimeAlertsHandler)
+		r.Delete("/notifications/mute/{id}", controller.UnmuteAnimeAlertsHandler)
+		r.Put("/email/preferences", controller.UpdateEmailPreferencesHandler)
+		r.Get("/webhooks", controller.GetUserWebhooksHandler)
+		r.Post("/webhooks", controller.CreateUserWebhookHandler)
+		r.Put("/webhooks/{id}", controller.UpdateUserWebhookHandler)
+		r.Delete("/webhooks/{id}", controller.DeleteUserWebhookHandler)
+		r.Get("/webhooks/{id}/deliveries", controller.GetUserWebhookDeliveriesHandler)
+		r.Post("/webhooks/{id}/deliveries/{deliveryId}/redeliver", controller.RedeliverUserWebhookHandler)
 	})
 
 	// Protected Admin API routes (Supabase auth + admin check)
@@ -111,10 +172,35 @@ func Router() *chi.Mux {
 		r.Post("/import/seasonal", controller.ImportSeasonalHandler)
 		r.Post("/import/bulk", controller.BulkImportHandler)
 		r.Post("/update/current", controller.UpdateCurrentSeasonHandler)
+		r.Post("/update/information", controller.BackfillInformationHandler)
 		r.Post("/backfill", controller.BackfillDataHandler)
+		r.Post("/recommendations/similarity", controller.ComputeItemSimilaritiesHandler)
 		r.Post("/anime/{id}/enhance", controller.EnhanceAnime)
 		r.Post("/anime/create-from-api", controller.CreateAnimeFromAPI)
 		r.Get("/anime/{id}/enhanced", controller.GetEnhancedAnime)
+
+		// Moderation
+		r.Get("/moderation/queue", controller.GetModerationQueueHandler)
+		r.Post("/moderation/reports/{id}/action", controller.ResolveReportHandler)
+		r.Post("/moderation/actions", controller.ModerateTargetHandler)
+		r.Get("/moderation/log", controller.GetModerationLogHandler)
+		r.Get("/moderation/word-filter", controller.GetWordFilterHandler)
+		r.Put("/moderation/word-filter", controller.UpdateWordFilterHandler)
+
+		// Search analytics
+		r.Get("/search/report", controller.GetSearchReportHandler)
+		r.Post("/search/import", controller.ImportSearchTermHandler)
+
+		// Email
+		r.Post("/email/digest", controller.SendWeeklyDigestsHandler)
+
+		// Webhooks
+		r.Get("/webhooks", controller.GetAdminWebhooksHandler)
+		r.Post("/webhooks", controller.CreateAdminWebhookHandler)
+		r.Put("/webhooks/{id}", controller.UpdateAdminWebhookHandler)
+		r.Delete("/webhooks/{id}", controller.DeleteAdminWebhookHandler)
+		r.Get("/webhooks/{id}/deliveries", controller.GetAdminWebhookDeliveriesHandler)
+		r.Post("/webhooks/{id}/deliveries/{deliveryId}/redeliver", controller.RedeliverAdminWebhookHandler)
 	})
 
 	router.Route("/api/legacy", func(r chi.Router) {
@@ -95,7 +95,7 @@ func BackfillAnimeData(animeName string) error {
 	}
 
 	// Update MongoDB record with missing data
-	filter := bson.M{"name": bson.M{"$regex": "^" + animeName + "$", "$options": "i"}}
+	filter := bson.M{"name": ExactText(animeName)}
 	
 	updateFields := bson.M{}
 	
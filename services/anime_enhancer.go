@@ -149,6 +149,7 @@ func saveAnimeToDatabase(anime *models.Anime) (*models.Anime, error) {
 		return nil, err
 	}
 	
+	PublishCatalogChange(CatalogAnimeAdded, *anime)
 	return anime, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"animeverse/config"
@@ -19,21 +20,34 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// FindAnimeByName finds the catalog anime with this name or a spelling variant of it, such
+// as "Shingeki no Kyoujin" for "Shingeki no Kyojin". A matching name is preferred over a
+// matching alternative title.
 func FindAnimeByName(name string) (*model.Anime, error) {
 	collection := config.Collection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	var anime model.Anime
-	err := collection.FindOne(ctx, bson.M{"name": name}).Decode(&anime)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil // No existing anime found
+	filters := []bson.M{{"name": name}}
+	if key := NormalizeTitle(name); key != "" {
+		filters = []bson.M{
+			{"$or": []bson.M{{"name": name}, {"name_key": key}}},
+			{"title_keys": key},
 		}
-		log.Println("Error finding anime:", err)
-		return nil, err
 	}
-	return &anime, nil
+
+	for _, filter := range filters {
+		var anime model.Anime
+		err := collection.FindOne(ctx, PublicCatalogFilter(filter)).Decode(&anime)
+		if err == nil {
+			return &anime, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			log.Println("Error finding anime:", err)
+			return nil, err
+		}
+	}
+	return nil, nil // No existing anime found
 }
 
 func SearchAnimeByName(name string) (*model.Anime, error) {
@@ -42,7 +56,7 @@ func SearchAnimeByName(name string) (*model.Anime, error) {
 	defer cancel()
 
 	var anime model.Anime
-	filter := bson.M{"name": bson.M{"$regex": "^" + name + "$", "$options": "i"}} // Case-insensitive search
+	filter := PublicCatalogFilter(bson.M{"name": ExactText(name)}) // Case-insensitive search
 
 	err := collection.FindOne(ctx, filter).Decode(&anime)
 	if err != nil {
@@ -59,12 +73,21 @@ func InsertOneAnime(anime model.Anime) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// List copies get no catalog events, so their title keys are stored with them
+	if anime.NameKey == "" {
+		anime.NameKey, anime.TitleKeys = buildTitleKeys(anime)
+	}
 	inserted, err := config.Collection.InsertOne(ctx, anime)
 	if err != nil {
 		log.Println("Error inserting anime:", err)
 		return err
 	}
 	fmt.Println("Inserted 1 anime in db with id:", inserted.InsertedID)
+
+	if id, ok := inserted.InsertedID.(primitive.ObjectID); ok {
+		anime.ID = id
+	}
+	PublishCatalogChange(CatalogAnimeAdded, anime)
 	return nil
 }
 
@@ -104,6 +127,13 @@ func InsertMultipleAnimes(animes []model.Anime) ([]interface{}, []string, error)
 	}
 
 	fmt.Printf("Inserted %d animes in db\n", len(result.InsertedIDs))
+	for i, doc := range validAnimes {
+		anime := doc.(model.Anime)
+		if id, ok := result.InsertedIDs[i].(primitive.ObjectID); ok {
+			anime.ID = id
+		}
+		PublishCatalogChange(CatalogAnimeAdded, anime)
+	}
 	return result.InsertedIDs, duplicates, nil
 }
 
@@ -142,6 +172,7 @@ func UpdateAnime(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Println("Successfully updated anime:", idStr)
+	PublishCatalogUpdate(objectID)
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"matched":  result.MatchedCount,
@@ -158,18 +189,19 @@ func DeleteOneAnime(animeId string) bool {
 	}
 
 	filter := bson.M{"_id": id}
-	result, err := config.Collection.DeleteOne(context.Background(), filter)
-	if err != nil {
-		log.Println("Error deleting anime:", err)
+	var deleted model.Anime
+	err = config.Collection.FindOneAndDelete(context.Background(), filter).Decode(&deleted)
+	if err == mongo.ErrNoDocuments {
+		log.Println("No anime found with ID:", animeId)
 		return false
 	}
-	
-	if result.DeletedCount == 0 {
-		log.Println("No anime found with ID:", animeId)
+	if err != nil {
+		log.Println("Error deleting anime:", err)
 		return false
 	}
 	
-	fmt.Println("Anime deleted with count:", result.DeletedCount)
+	fmt.Println("Anime deleted with ID:", animeId)
+	PublishCatalogChange(CatalogAnimeRemoved, deleted)
 	return true
 }
 
@@ -183,190 +215,192 @@ func DeleteAllAnime() int64 {
 	return deleteResult.DeletedCount
 }
 
-func GetAllAnimes() []primitive.M {
-	cur, err := config.Collection.Find(context.Background(), bson.D{{}})
+// ListAnimes returns one page of the catalog and the cursor of the next page
+func ListAnimes(page PageRequest) ([]primitive.M, string, error) {
+	animes, next, err := FindPage(context.Background(), config.Collection, PublicCatalogFilter(nil), page, searchProjection)
 	if err != nil {
 		log.Println("Error fetching animes:", err)
-		return []primitive.M{} // Return empty slice instead of nil
+		return nil, "", err
 	}
-	defer cur.Close(context.Background())
-
-	var animes []primitive.M
-	for cur.Next(context.Background()) {
-		var anime bson.M
-		if err := cur.Decode(&anime); err != nil {
-			log.Println("Error decoding anime:", err)
-			continue
-		}
-		animes = append(animes, anime)
-	}
-	log.Printf("GetAllAnimes: Found %d total animes", len(animes))
-	return animes
+	return animes, next, nil
 }
 
-func FilterAnimes(search, genre, year, season, format, status, userID string) []primitive.M {
+// browseFilter scopes FilterAnimes to the user's list or the catalog and applies the free
+// text and information filters, leaving out the query's field filters
+func browseFilter(query *SearchQuery, userID string, info InformationFilters) bson.M {
 	filter := bson.M{}
-	
+	for _, word := range strings.Fields(NormalizeTitle(query.Text)) {
+		// List entries copied before title keys existed only have their name
+		filter["$and"] = append(andClauses(filter), bson.M{"$or": []bson.M{
+			{"title_keys": ContainsText(word)},
+			{"name": ContainsText(word)},
+		}})
+	}
+
 	// Add user filter if provided (for user-specific data)
 	if userID != "" {
 		filter["user_id"] = userID
+	} else {
+		filter = PublicCatalogFilter(filter)
 	}
-	
-	// Build filter with proper field matching
-	if search != "" {
-		filter["name"] = bson.M{"$regex": search, "$options": "i"}
-	}
-	if genre != "" {
-		// Handle both string and array genre fields
-		filter["$or"] = []bson.M{
-			{"genre": bson.M{"$regex": genre, "$options": "i"}}, // String genre
-			{"genre": bson.M{"$in": []string{genre}}},              // Array genre
-		}
-	}
-	if year != "" {
-		if yearInt, err := strconv.Atoi(year); err == nil {
-			filter["year"] = yearInt
-		}
-	}
-	if season != "" {
-		filter["season"] = bson.M{"$regex": "^" + season + "$", "$options": "i"}
-	}
-	if format != "" {
-		filter["type"] = bson.M{"$regex": "^" + format + "$", "$options": "i"}
-	}
-	if status != "" {
-		filter["status"] = bson.M{"$regex": "^" + status + "$", "$options": "i"}
-	}
+
+	applyInformationFilters(filter, info)
+	return filter
+}
+
+// FilterAnimes lists one page of the anime matching a parsed search query, from the user's list
+// when userID is set and from the catalog otherwise. Each word of the free text must appear in
+// a title.
+func FilterAnimes(query *SearchQuery, userID string, info InformationFilters, page PageRequest) ([]primitive.M, string) {
+	filter := withFilter(browseFilter(query, userID, info), query.Filter())
 
 	// Log filter for debugging
 	log.Printf("Filter query: %+v", filter)
 	
-	cur, err := config.Collection.Find(context.Background(), filter)
+	animes, next, err := FindPage(context.Background(), config.Collection, filter, page, searchProjection)
 	if err != nil {
 		log.Println("Error filtering animes:", err)
-		return []primitive.M{} // Return empty slice instead of nil
-	}
-	defer cur.Close(context.Background())
-
-	var animes []primitive.M
-	for cur.Next(context.Background()) {
-		var anime bson.M
-		if err := cur.Decode(&anime); err != nil {
-			log.Println("Error decoding anime:", err)
-			continue
-		}
-		animes = append(animes, anime)
+		return []primitive.M{}, "" // Return empty slice instead of nil
 	}
 	
 	log.Printf("Found %d animes with filter", len(animes))
-	return animes
+	return animes, next
 }
 
-func GetTrendingAnimes() []primitive.M {
-	filter := bson.M{}
-	opts := options.Find().SetSort(bson.D{{"score", -1}}).SetLimit(5)
-	
-	cur, err := config.Collection.Find(context.Background(), filter, opts)
+// GetTrendingAnimes returns a page of the catalog, best scored first unless the page sorts otherwise
+func GetTrendingAnimes(page PageRequest) ([]primitive.M, string) {
+	animes, next, err := FindPage(context.Background(), config.Collection, PublicCatalogFilter(nil), page, nil)
 	if err != nil {
 		log.Println("Error fetching trending animes:", err)
-		return nil
+		return nil, ""
 	}
-	defer cur.Close(context.Background())
+	return animes, next
+}
 
-	var animes []primitive.M
-	for cur.Next(context.Background()) {
-		var anime bson.M
-		if err := cur.Decode(&anime); err != nil {
-			log.Println("Error decoding anime:", err)
-			continue
-		}
-		animes = append(animes, anime)
+func GetPopularAnimes(page PageRequest) ([]primitive.M, string) {
+	filter := PublicCatalogFilter(bson.M{"status": "completed"})
+	animes, next, err := FindPage(context.Background(), config.Collection, filter, page, nil)
+	if err != nil {
+		log.Println("Error fetching popular animes:", err)
+		return nil, ""
 	}
-	return animes
+	return animes, next
 }
 
-func GetPopularAnimes() []primitive.M {
-	filter := bson.M{"status": "completed"}
-	opts := options.Find().SetSort(bson.D{{"score", -1}}).SetLimit(5)
-	
-	cur, err := config.Collection.Find(context.Background(), filter, opts)
+// RANDOM_MAX_PICKS caps how many anime one random request picks
+const RANDOM_MAX_PICKS = 20
+
+// RandomOptions shapes a random pick
+type RandomOptions struct {
+	Count       int
+	Seed        int64
+	ExcludeList bool // Leave out anything on the user's list
+	PlanToWatch bool // Pick from the user's plan-to-watch entries rather than the catalog
+}
+
+// GetRandomAnimes picks up to opts.Count distinct anime at random among those matching the browse
+// filters, from the catalog or, with PlanToWatch, from the user's plan-to-watch entries. userID
+// must be set for ExcludeList and PlanToWatch. Picks are drawn one at a time by a generator seeded
+// with opts.Seed over the matches in _id order, so the same seed and filters give the same picks
+// while the matches stay the same, and asking for more picks only adds to the end.
+func GetRandomAnimes(query *SearchQuery, userID string, info InformationFilters, opts RandomOptions) ([]primitive.M, error) {
+	scope := ""
+	if opts.PlanToWatch {
+		scope = userID
+	}
+	filter := withFilter(browseFilter(query, scope, info), query.Filter())
+
+	if opts.PlanToWatch {
+		filter = withFilter(filter, bson.M{"status": model.PlanToWatch})
+	} else if opts.ExcludeList {
+		list, err := GetUserList(userID)
+		if err != nil {
+			return nil, err
+		}
+		filter = withFilter(filter, notOnListFilter(list))
+	}
+
+	ctx := context.Background()
+	total, err := config.Collection.CountDocuments(ctx, filter)
 	if err != nil {
-		log.Println("Error fetching popular animes:", err)
-		return nil
+		return nil, err
 	}
-	defer cur.Close(context.Background())
 
-	var animes []primitive.M
-	for cur.Next(context.Background()) {
-		var anime bson.M
-		if err := cur.Decode(&anime); err != nil {
-			log.Println("Error decoding anime:", err)
-			continue
+	animes := []primitive.M{}
+	for _, index := range randomIndexes(rand.New(rand.NewSource(opts.Seed)), total, opts.Count) {
+		findOpts := options.FindOne().
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetSkip(index).
+			SetProjection(searchProjection)
+
+		var anime primitive.M
+		err := config.Collection.FindOne(ctx, filter, findOpts).Decode(&anime)
+		if err == mongo.ErrNoDocuments {
+			continue // Matches were removed since counting
+		}
+		if err != nil {
+			return nil, err
 		}
 		animes = append(animes, anime)
 	}
-	return animes
+	return animes, nil
 }
 
-// GetRandomAnime returns a random anime
-func GetRandomAnime() *model.Anime {
-	count, err := config.Collection.CountDocuments(context.Background(), bson.M{})
-	if err != nil || count == 0 {
-		return nil
+// randomIndexes draws up to count distinct indexes below total, in the order drawn
+func randomIndexes(rng *rand.Rand, total int64, count int) []int64 {
+	if int64(count) > total {
+		count = int(total)
+	}
+	indexes := make([]int64, 0, count)
+	drawn := make(map[int64]bool, count)
+	for len(indexes) < count {
+		index := rng.Int63n(total)
+		if !drawn[index] {
+			drawn[index] = true
+			indexes = append(indexes, index)
+		}
 	}
-	
-	randomIndex := rand.Int63n(count)
-	opts := options.FindOne().SetSkip(randomIndex)
-	
-	var anime model.Anime
-	err = config.Collection.FindOne(context.Background(), bson.M{}, opts).Decode(&anime)
-	if err != nil {
-		return nil
+	return indexes
+}
+
+// notOnListFilter leaves out catalog anime on the list, matching them by AniList ID or by title
+// the way excludeListed does
+func notOnListFilter(list []model.Anime) bson.M {
+	ids := []int{}
+	names := []string{}
+	keys := []string{}
+	for _, anime := range list {
+		if anime.AniListID > 0 {
+			ids = append(ids, anime.AniListID)
+		}
+		names = append(names, anime.Name)
+		if key := NormalizeTitle(anime.Name); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return bson.M{
+		"anilist_id": bson.M{"$nin": ids},
+		"name":       bson.M{"$nin": names},
+		"name_key":   bson.M{"$nin": keys},
 	}
-	
-	return &anime
 }
 
-// GetDailySchedule returns scheduled anime for a given day
-func GetDailySchedule(day string) []map[string]interface{} {
-	// Mock schedule data - in real implementation, this would come from a schedule collection
-	schedules := map[string][]map[string]interface{}{
-		"Monday": {
-			{"name": "Attack on Titan", "time": "15:45", "episode": "Episode 12"},
-			{"name": "Demon Slayer", "time": "16:30", "episode": "Episode 8"},
-			{"name": "One Piece", "time": "17:00", "episode": "Episode 1045"},
-		},
-		"Tuesday": {
-			{"name": "Jujutsu Kaisen", "time": "15:30", "episode": "Episode 15"},
-			{"name": "My Hero Academia", "time": "16:00", "episode": "Episode 22"},
-		},
-		"Wednesday": {
-			{"name": "Chainsaw Man", "time": "15:45", "episode": "Episode 9"},
-			{"name": "Spy x Family", "time": "16:15", "episode": "Episode 11"},
-		},
-		"Thursday": {
-			{"name": "Tokyo Revengers", "time": "15:30", "episode": "Episode 18"},
-			{"name": "Blue Lock", "time": "16:45", "episode": "Episode 14"},
-		},
-		"Friday": {
-			{"name": "Mob Psycho 100", "time": "15:00", "episode": "Episode 7"},
-			{"name": "One Punch Man", "time": "16:30", "episode": "Episode 5"},
-		},
-		"Saturday": {
-			{"name": "Dragon Ball Super", "time": "14:00", "episode": "Episode 131"},
-			{"name": "Naruto Shippuden", "time": "15:30", "episode": "Episode 720"},
-		},
-		"Sunday": {
-			{"name": "Bleach", "time": "14:30", "episode": "Episode 366"},
-			{"name": "Hunter x Hunter", "time": "16:00", "episode": "Episode 148"},
-		},
+// CatalogBrowseFilter matches catalog anime by genre, year and part of the name, each optional.
+// Users' list copies never match, so their entries stay private.
+func CatalogBrowseFilter(genre, year, search string) bson.M {
+	filter := bson.M{}
+	if genre != "" {
+		filter["genre"] = bson.M{"$in": []string{genre}}
 	}
-	
-	if schedule, exists := schedules[day]; exists {
-		return schedule
+	if year != "" {
+		if yearInt, err := strconv.Atoi(year); err == nil {
+			filter["year"] = yearInt
+		}
 	}
-	
-	return []map[string]interface{}{}
+	if search != "" {
+		filter["name"] = ContainsText(search)
+	}
+	return PublicCatalogFilter(filter)
 }
 
 // GetTop2025Animes returns top rated anime from 2024-2025
@@ -392,7 +426,7 @@ func GetTop2025Animes() []primitive.M {
 		}
 		
 		opts := options.Find().SetSort(bson.D{{"score", -1}, {"year", -1}}).SetLimit(10)
-		cur, err := config.Collection.Find(context.Background(), filter, opts)
+		cur, err := config.Collection.Find(context.Background(), PublicCatalogFilter(filter), opts)
 		if err != nil {
 			continue
 		}
@@ -422,7 +456,7 @@ func GetTop2025Animes() []primitive.M {
 func GetPreviewAnimes() []primitive.M {
 	// Get diverse mix of anime
 	pipeline := []bson.M{
-		{"$match": bson.M{"score": bson.M{"$gte": 6}}},
+		{"$match": PublicCatalogFilter(bson.M{"score": bson.M{"$gte": 6}})},
 		{"$sort": bson.M{"score": -1, "year": -1}},
 		{"$limit": 12},
 	}
@@ -431,7 +465,8 @@ func GetPreviewAnimes() []primitive.M {
 	if err != nil {
 		log.Println("Error fetching preview animes:", err)
 		// Fallback to simple query
-		return GetAllAnimes()[:12]
+		animes, _, _ := ListAnimes(PageRequest{Limit: 12, Sort: "score"})
+		return animes
 	}
 	defer cur.Close(context.Background())
 
@@ -449,51 +484,3 @@ func GetPreviewAnimes() []primitive.M {
 	return animes
 }
 
-// SearchAnimes searches for anime by name with fuzzy matching
-func SearchAnimes(query string) []primitive.M {
-	// Multi-stage search for better results
-	filters := []bson.M{
-		// Exact match
-		{"name": bson.M{"$regex": "^" + query + "$", "$options": "i"}},
-		// Starts with
-		{"name": bson.M{"$regex": "^" + query, "$options": "i"}},
-		// Contains
-		{"name": bson.M{"$regex": query, "$options": "i"}},
-		// Synonyms/notes search
-		{"notes": bson.M{"$regex": query, "$options": "i"}},
-	}
-	
-	var allResults []primitive.M
-	seenNames := make(map[string]bool)
-	
-	for _, filter := range filters {
-		if len(allResults) >= 20 { // Limit total results
-			break
-		}
-		
-		opts := options.Find().SetSort(bson.D{{"score", -1}}).SetLimit(10)
-		cur, err := config.Collection.Find(context.Background(), filter, opts)
-		if err != nil {
-			continue
-		}
-		
-		for cur.Next(context.Background()) && len(allResults) < 20 {
-			var anime bson.M
-			if err := cur.Decode(&anime); err != nil {
-				continue
-			}
-			
-			name, ok := anime["name"].(string)
-			if !ok || seenNames[name] {
-				continue
-			}
-			
-			seenNames[name] = true
-			allResults = append(allResults, anime)
-		}
-		cur.Close(context.Background())
-	}
-	
-	log.Printf("SearchAnimes: Found %d results for query '%s'", len(allResults), query)
-	return allResults
-}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCatalogBrowseFilterExcludesListCopies(t *testing.T) {
+	tests := []struct {
+		genre, year, search string
+	}{
+		{"", "", ""},
+		{"Action", "", ""},
+		{"", "2023", ""},
+		{"", "not a year", ""},
+		{"", "", "naruto"},
+		{"Drama", "2019", `{"user_id": "x"}`},
+	}
+
+	for _, tt := range tests {
+		filter := CatalogBrowseFilter(tt.genre, tt.year, tt.search)
+		if !reflect.DeepEqual(filter["user_id"], bson.M{"$exists": false}) {
+			t.Errorf("CatalogBrowseFilter(%q, %q, %q) lets list copies through: %v", tt.genre, tt.year, tt.search, filter)
+		}
+	}
+}
+
+func TestCatalogBrowseFilterFields(t *testing.T) {
+	filter := CatalogBrowseFilter("Action", "2023", "naruto")
+	if _, ok := filter["genre"]; !ok {
+		t.Error("genre is not filtered")
+	}
+	if filter["year"] != 2023 {
+		t.Errorf("year = %v, want 2023", filter["year"])
+	}
+	if _, ok := filter["name"]; !ok {
+		t.Error("name is not filtered")
+	}
+	if _, ok := CatalogBrowseFilter("", "soon", "")["year"]; ok {
+		t.Error("an invalid year is filtered on")
+	}
+}
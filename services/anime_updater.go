@@ -11,6 +11,7 @@ import (
 	"animeverse/config"
 	model "animeverse/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -35,6 +36,16 @@ type AniListCurrentSeason struct {
 					Large string `json:"large"`
 				} `json:"coverImage"`
 				BannerImage string `json:"bannerImage"`
+				Tags        []aniListTag `json:"tags"`
+				Studios     struct {
+					Nodes []struct {
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"studios"`
+				NextAiringEpisode *struct {
+					Episode  int   `json:"episode"`
+					AiringAt int64 `json:"airingAt"`
+				} `json:"nextAiringEpisode"`
 			} `json:"media"`
 		} `json:"Page"`
 	} `json:"data"`
@@ -43,6 +54,14 @@ type AniListCurrentSeason struct {
 // UpdateCurrentSeasonAnime fetches and updates current season anime
 func UpdateCurrentSeasonAnime() (int, error) {
 	log.Println("Updating current season anime from AniList...")
+
+	// Episode air times come from AniList's schedule, which also covers long-running shows.
+	// It is refreshed whether or not the season itself could be fetched.
+	defer func() {
+		if _, err := RefreshAiringSchedule(); err != nil {
+			log.Printf("Error refreshing airing schedule: %v", err)
+		}
+	}()
 	
 	query := `
 	query ($season: MediaSeason, $year: Int, $page: Int) {
@@ -64,6 +83,20 @@ func UpdateCurrentSeasonAnime() (int, error) {
 					large
 				}
 				bannerImage
+				tags {
+					name
+					rank
+					isMediaSpoiler
+				}
+				studios(isMain: true) {
+					nodes {
+						name
+					}
+				}
+				nextAiringEpisode {
+					episode
+					airingAt
+				}
 			}
 		}
 	}`
@@ -110,11 +143,29 @@ func UpdateCurrentSeasonAnime() (int, error) {
 			title = media.Title.English
 		}
 
-		filter := bson.M{
+		filter := PublicCatalogFilter(bson.M{
 			"$or": []bson.M{
 				{"name": title},
 				{"anilist_id": media.ID},
 			},
+		})
+
+		tags := aniListTagNames(media.Tags)
+		var studios []string
+		for _, studio := range media.Studios.Nodes {
+			studios = append(studios, studio.Name)
+		}
+
+		// Work out the latest aired episode for new-episode alerts
+		lastAired, nextEpisode := 0, 0
+		var nextAiringAt *time.Time
+		if media.NextAiringEpisode != nil {
+			nextEpisode = media.NextAiringEpisode.Episode
+			lastAired = nextEpisode - 1
+			airingAt := time.Unix(media.NextAiringEpisode.AiringAt, 0)
+			nextAiringAt = &airingAt
+		} else if media.Status == "FINISHED" {
+			lastAired = media.Episodes
 		}
 
 		var existingAnime model.Anime
@@ -129,6 +180,10 @@ func UpdateCurrentSeasonAnime() (int, error) {
 				Progress:  model.Progress{Total: media.Episodes},
 				Status:    convertAniListStatus(media.Status),
 				Genre:     media.Genres,
+				Tags:      tags,
+				Information: model.AnimeInformation{
+					Studios: studios,
+				},
 				ImageUrl:  media.CoverImage.Large,
 				BannerUrl: media.BannerImage,
 				AniListID: media.ID,
@@ -138,38 +193,75 @@ func UpdateCurrentSeasonAnime() (int, error) {
 				UpdatedAt: time.Now(),
 			}
 
-			_, err := config.Collection.InsertOne(context.Background(), newAnime)
+			result, err := config.Collection.InsertOne(context.Background(), newAnime)
 			if err != nil {
 				log.Printf("Error inserting new anime %s: %v", title, err)
 				continue
 			}
+			newAnime.ID = result.InsertedID.(primitive.ObjectID)
+			if err := RecordAiring(newAnime.ID, lastAired, nextEpisode, nextAiringAt); err != nil {
+				log.Printf("Error recording airing data for %s: %v", title, err)
+			}
+			PublishCatalogChange(CatalogAnimeAdded, newAnime)
 			updated++
 		} else if err == nil {
 			// Update existing anime with new data
-			update := bson.M{
-				"$set": bson.M{
-					"score":      float64(media.AverageScore) / 10.0,
-					"status":     convertAniListStatus(media.Status),
-					"imageUrl":   media.CoverImage.Large,
-					"bannerUrl":  media.BannerImage,
-					"anilist_id": media.ID,
-					"updated_at": time.Now(),
-				},
+			set := bson.M{
+				"score":      float64(media.AverageScore) / 10.0,
+				"status":     convertAniListStatus(media.Status),
+				"imageUrl":   media.CoverImage.Large,
+				"bannerUrl":  media.BannerImage,
+				"anilist_id": media.ID,
+				"updated_at": time.Now(),
 			}
+			if len(tags) > 0 {
+				set["tags"] = tags
+			}
+			if len(studios) > 0 {
+				set["information.studios"] = studios
+			}
+			update := bson.M{"$set": set}
 
 			_, err := config.Collection.UpdateOne(context.Background(), bson.M{"_id": existingAnime.ID}, update)
 			if err != nil {
 				log.Printf("Error updating anime %s: %v", title, err)
 				continue
 			}
+			if err := RecordAiring(existingAnime.ID, lastAired, nextEpisode, nextAiringAt); err != nil {
+				log.Printf("Error recording airing data for %s: %v", title, err)
+			}
+			PublishCatalogUpdate(existingAnime.ID)
 			updated++
 		}
 	}
 
 	log.Printf("Updated %d current season anime", updated)
+	EmitImportFinished("current_season", updated, nil)
 	return updated, nil
 }
 
+// aniListTag is a descriptive tag AniList attaches to a media entry
+type aniListTag struct {
+	Name           string `json:"name"`
+	Rank           int    `json:"rank"`
+	IsMediaSpoiler bool   `json:"isMediaSpoiler"`
+}
+
+// aniListTagNames keeps the relevant, spoiler-free tags of a media entry
+func aniListTagNames(tags []aniListTag) []string {
+	var names []string
+	for _, tag := range tags {
+		if tag.IsMediaSpoiler || tag.Rank < 60 {
+			continue
+		}
+		names = append(names, tag.Name)
+		if len(names) == 10 {
+			break
+		}
+	}
+	return names
+}
+
 func getCurrentSeason() string {
 	month := time.Now().Month()
 	switch {
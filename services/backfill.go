@@ -0,0 +1,53 @@
+package services
+
+import "log"
+
+// BACKFILL_BATCH is how many documents a backfill step converts per batch
+const BACKFILL_BATCH = 500
+
+// backfillStep derives one set of fields for the documents that lack them, a batch at a time.
+// Batch returns how many documents it converted and whether more remain. Each derived field is
+// written by one step only.
+type backfillStep struct {
+	Name  string
+	Batch func() (int, bool, error)
+	Done  string // Logged with the number converted, when there were any
+}
+
+var (
+	informationBackfill = backfillStep{"information", BackfillAnimeInformation, "Parsed broadcast, aired, duration and premiered data for %d anime"}
+	titleKeyBackfill    = backfillStep{"title keys", BackfillTitleKeys, "Stored title keys for %d anime"}
+	searchBackfill      = backfillStep{"search terms", BackfillSearchTerms, "Indexed search terms for %d anime"}
+)
+
+// backfillSteps run one after another at startup rather than all at once, so they don't compete
+// for the database while the server comes up
+var backfillSteps = []backfillStep{informationBackfill, titleKeyBackfill, searchBackfill}
+
+// RunBackfills brings every document's derived fields up to date, step by step
+func RunBackfills() {
+	for _, step := range backfillSteps {
+		if _, err := runBackfillStep(step); err != nil {
+			log.Printf("Backfill of %s failed: %v", step.Name, err)
+		}
+	}
+}
+
+// runBackfillStep runs a step batch by batch until no document is left to convert
+func runBackfillStep(step backfillStep) (int, error) {
+	total := 0
+	for {
+		count, more, err := step.Batch()
+		total += count
+		if err != nil {
+			return total, err
+		}
+		if !more {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf(step.Done, total)
+	}
+	return total, nil
+}
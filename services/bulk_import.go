@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -53,6 +54,7 @@ func BulkImportAnimeDatabase() (int, error) {
 
 	// Convert to our model and batch insert
 	var animes []interface{}
+	inserted := 0
 	for _, item := range animeData.Data {
 		if item.Title == "" {
 			continue
@@ -97,27 +99,35 @@ func BulkImportAnimeDatabase() (int, error) {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
+		fillSearchFields(&anime)
 
 		animes = append(animes, anime)
 
 		// Batch insert every 1000 records
 		if len(animes) >= 1000 {
-			if err := insertBatch(animes); err != nil {
+			count, err := insertBatch(animes)
+			if err != nil {
 				log.Printf("Error inserting batch: %v", err)
 			}
+			inserted += count
 			animes = animes[:0] // Clear slice
 		}
 	}
 
 	// Insert remaining records
 	if len(animes) > 0 {
-		if err := insertBatch(animes); err != nil {
+		count, err := insertBatch(animes)
+		if err != nil {
 			log.Printf("Error inserting final batch: %v", err)
 		}
+		inserted += count
 	}
 
-	log.Printf("Bulk import completed. Imported %d anime", len(animeData.Data))
-	return len(animeData.Data), nil
+	log.Printf("Bulk import completed. Imported %d of %d anime", inserted, len(animeData.Data))
+	// Bulk batches skip per-anime catalog events, so this is the only notification. Their search
+	// terms and title keys are stored with them instead.
+	EmitImportFinished("bulk", inserted, nil)
+	return inserted, nil
 }
 
 func extractIDs(sources []string) (int, int) {
@@ -203,9 +213,11 @@ func generateScore(item AnimeOfflineDB) int {
 	return score
 }
 
-func insertBatch(animes []interface{}) error {
+// insertBatch inserts a batch of anime and returns how many were stored. Duplicates are
+// skipped without failing the batch.
+func insertBatch(animes []interface{}) (int, error) {
 	if len(animes) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -218,13 +230,15 @@ func insertBatch(animes []interface{}) error {
 	_, err := config.Collection.InsertMany(ctx, animes, opts)
 	if err != nil {
 		// Log but don't fail on duplicate key errors
-		if mongo.IsDuplicateKeyError(err) {
-			log.Printf("Some duplicates found in batch, continuing...")
-			return nil
+		var bulkErr mongo.BulkWriteException
+		if mongo.IsDuplicateKeyError(err) && errors.As(err, &bulkErr) {
+			inserted := len(animes) - len(bulkErr.WriteErrors)
+			log.Printf("Inserted %d of %d anime in batch, skipping duplicates", inserted, len(animes))
+			return inserted, nil
 		}
-		return err
+		return 0, err
 	}
 
 	log.Printf("Inserted batch of %d anime", len(animes))
-	return nil
+	return len(animes), nil
 }
\ No newline at end of file
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"animeverse/cache"
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	CALENDAR_PREMIERE_CACHE_TTL = 6 * time.Hour
+	CALENDAR_EPISODE_LENGTH     = 24 * time.Minute
+	CALENDAR_LOOKBACK           = 24 * time.Hour // Keep just-aired episodes visible for a day
+	CALENDAR_MAX_LOOKUPS        = 200            // List entries checked for announced sequels
+)
+
+// ErrInvalidFeedToken is returned when a calendar feed token is unknown or revoked
+var ErrInvalidFeedToken = fmt.Errorf("invalid calendar feed token")
+
+// CalendarEvent is one entry of a user's calendar feed
+type CalendarEvent struct {
+	UID     string    `json:"uid"`
+	Summary string    `json:"summary"`
+	Start   time.Time `json:"start"`
+	AllDay  bool      `json:"all_day"` // Premieres are only known to the day
+	URL     string    `json:"url,omitempty"`
+}
+
+// CreateCalendarFeed issues a new private feed token for a user, revoking any previous one.
+// Only a hash is stored, so the returned URL cannot be shown again.
+func CreateCalendarFeed(userID string) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	result, err := config.UserCollection.UpdateOne(context.Background(),
+		bson.M{"supabase_id": userID},
+		bson.M{"$set": bson.M{"feed_token_hash": hashFeedToken(token), "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return "", err
+	}
+	if result.MatchedCount == 0 {
+		return "", mongo.ErrNoDocuments
+	}
+
+	return CalendarFeedURL(token), nil
+}
+
+// RevokeCalendarFeed disables a user's feed URL
+func RevokeCalendarFeed(userID string) error {
+	_, err := config.UserCollection.UpdateOne(context.Background(),
+		bson.M{"supabase_id": userID},
+		bson.M{"$unset": bson.M{"feed_token_hash": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	return err
+}
+
+// CalendarFeedURL builds the subscription URL for a feed token
+func CalendarFeedURL(token string) string {
+	return strings.TrimRight(os.Getenv("APP_BASE_URL"), "/") + "/calendar/" + token + ".ics"
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetCalendarFeed renders the iCalendar feed behind a token
+func GetCalendarFeed(token string) (string, error) {
+	if token == "" {
+		return "", ErrInvalidFeedToken
+	}
+
+	var user model.User
+	err := config.UserCollection.FindOne(context.Background(), bson.M{"feed_token_hash": hashFeedToken(token)}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return "", ErrInvalidFeedToken
+	}
+	if err != nil {
+		return "", err
+	}
+
+	events, err := GetCalendarEvents(user.SupabaseID)
+	if err != nil {
+		return "", err
+	}
+	return renderICalendar(events, time.Now()), nil
+}
+
+// GetCalendarEvents collects upcoming episodes of anime being watched or planned,
+// and premiere dates of announced anime and sequels related to the user's list
+func GetCalendarEvents(userID string) ([]CalendarEvent, error) {
+	list, err := GetUserList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := findCatalogCopies(list)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve list entries to AniList IDs through their catalog copies
+	following := map[int]model.Anime{}
+	related := []int{}
+	for _, entry := range list {
+		id := entry.AniListID
+		if id == 0 {
+			for _, key := range animeKeys(entry) {
+				if catalogCopy, ok := catalog[key]; ok && catalogCopy.AniListID > 0 {
+					id = catalogCopy.AniListID
+					break
+				}
+			}
+		}
+		if id == 0 {
+			continue
+		}
+		switch entry.Status {
+		case model.Watching, model.PlanToWatch:
+			following[id] = entry
+			related = append(related, id)
+		case model.Completed:
+			related = append(related, id)
+		}
+	}
+
+	events, err := upcomingEpisodeEvents(following, catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	premieres, err := cachedPremiereEvents(related)
+	if err != nil {
+		// AniList being unavailable shouldn't take the episode schedule down with it
+		premieres = nil
+	}
+	events = append(events, premieres...)
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+// upcomingEpisodeEvents reads episode air times from the airing schedule, falling back
+// to the next episode recorded on the catalog anime beyond the schedule's window
+func upcomingEpisodeEvents(following map[int]model.Anime, catalog map[string]model.Anime) ([]CalendarEvent, error) {
+	events := []CalendarEvent{}
+	if len(following) == 0 {
+		return events, nil
+	}
+
+	ids := make([]int, 0, len(following))
+	for id := range following {
+		ids = append(ids, id)
+	}
+
+	collection, err := getAiringScheduleCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cur, err := collection.Find(ctx, bson.M{
+		"anilist_id": bson.M{"$in": ids},
+		"airing_at":  bson.M{"$gte": time.Now().Add(-CALENDAR_LOOKBACK)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	seen := map[string]bool{}
+	for cur.Next(ctx) {
+		var entry model.AiringScheduleEntry
+		if err := cur.Decode(&entry); err != nil {
+			continue
+		}
+		event := episodeEvent(entry.AniListID, entry.Title, entry.Episode, entry.AiringAt)
+		seen[event.UID] = true
+		events = append(events, event)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, entry := range following {
+		catalogCopy, ok := catalog[fmt.Sprintf("anilist:%d", id)]
+		if !ok {
+			catalogCopy, ok = catalog["name:"+strings.ToLower(strings.TrimSpace(entry.Name))]
+		}
+		airing := catalogCopy.Airing
+		if !ok || airing == nil || airing.NextAiringAt == nil || airing.NextEpisode == 0 {
+			continue
+		}
+		event := episodeEvent(id, catalogCopy.Name, airing.NextEpisode, *airing.NextAiringAt)
+		if !seen[event.UID] && event.Start.After(time.Now().Add(-CALENDAR_LOOKBACK)) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func episodeEvent(anilistID int, title string, episode int, airingAt time.Time) CalendarEvent {
+	return CalendarEvent{
+		UID:     fmt.Sprintf("episode-%d-%d@animeverse", anilistID, episode),
+		Summary: fmt.Sprintf("%s - Episode %d", title, episode),
+		Start:   airingAt.UTC(),
+		URL:     fmt.Sprintf("https://anilist.co/anime/%d", anilistID),
+	}
+}
+
+// aniListPremierePage is the subset of AniList media used to find upcoming premieres
+type aniListPremierePage struct {
+	Data struct {
+		Page struct {
+			Media []struct {
+				aniListPremiereMedia
+				Relations struct {
+					Edges []struct {
+						RelationType string               `json:"relationType"`
+						Node         aniListPremiereMedia `json:"node"`
+					} `json:"edges"`
+				} `json:"relations"`
+			} `json:"media"`
+		} `json:"Page"`
+	} `json:"data"`
+}
+
+type aniListPremiereMedia struct {
+	ID     int    `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Title  struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+	} `json:"title"`
+	StartDate struct {
+		Year  int `json:"year"`
+		Month int `json:"month"`
+		Day   int `json:"day"`
+	} `json:"startDate"`
+}
+
+const aniListPremiereQuery = `
+query ($ids: [Int], $page: Int) {
+	Page(page: $page, perPage: 50) {
+		media(id_in: $ids, type: ANIME) {
+			id
+			type
+			status
+			title {
+				romaji
+				english
+			}
+			startDate {
+				year
+				month
+				day
+			}
+			relations {
+				edges {
+					relationType
+					node {
+						id
+						type
+						status
+						title {
+							romaji
+							english
+						}
+						startDate {
+							year
+							month
+							day
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+// cachedPremiereEvents caches premieres by the set of anime asked about, so list changes show up at once
+func cachedPremiereEvents(ids []int) ([]CalendarEvent, error) {
+	sort.Ints(ids)
+	sum := sha256.Sum256([]byte(fmt.Sprint(ids)))
+	cacheKey := "calendar:premieres:" + hex.EncodeToString(sum[:8])
+	var events []CalendarEvent
+	if cache.RedisClient != nil {
+		if err := cache.Get(cacheKey, &events); err == nil {
+			return events, nil
+		}
+	}
+
+	events, err := premiereEvents(ids)
+	if err != nil {
+		return nil, err
+	}
+	if cache.RedisClient != nil {
+		cache.Set(cacheKey, events, CALENDAR_PREMIERE_CACHE_TTL)
+	}
+	return events, nil
+}
+
+// premiereEvents asks AniList which of the given anime, or their sequels, are announced
+// with a known start date
+func premiereEvents(ids []int) ([]CalendarEvent, error) {
+	events := []CalendarEvent{}
+	if len(ids) > CALENDAR_MAX_LOOKUPS {
+		ids = ids[:CALENDAR_MAX_LOOKUPS]
+	}
+
+	seen := map[int]bool{}
+	add := func(media aniListPremiereMedia) {
+		if seen[media.ID] || media.Type != "ANIME" || media.Status != "NOT_YET_RELEASED" {
+			return
+		}
+		date := media.StartDate
+		if date.Year == 0 || date.Month == 0 || date.Day == 0 {
+			// Only "2026" or "spring 2026" announced so far
+			return
+		}
+		start := time.Date(date.Year, time.Month(date.Month), date.Day, 0, 0, 0, 0, time.UTC)
+		if start.Before(time.Now().Add(-CALENDAR_LOOKBACK)) {
+			return
+		}
+
+		title := media.Title.English
+		if title == "" {
+			title = media.Title.Romaji
+		}
+		seen[media.ID] = true
+		events = append(events, CalendarEvent{
+			UID:     fmt.Sprintf("premiere-%d@animeverse", media.ID),
+			Summary: title + " premieres",
+			Start:   start,
+			AllDay:  true,
+			URL:     fmt.Sprintf("https://anilist.co/anime/%d", media.ID),
+		})
+	}
+
+	for page := 1; (page-1)*50 < len(ids); page++ {
+		requestBody := map[string]interface{}{
+			"query":     aniListPremiereQuery,
+			"variables": map[string]interface{}{"ids": ids, "page": page},
+		}
+		jsonData, err := json.Marshal(requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		var result aniListPremierePage
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, media := range result.Data.Page.Media {
+			// Announced anime already on the list, e.g. planned before it airs
+			add(media.aniListPremiereMedia)
+			for _, edge := range media.Relations.Edges {
+				if edge.RelationType == "SEQUEL" {
+					add(edge.Node)
+				}
+			}
+		}
+	}
+	return events, nil
+}
+
+// renderICalendar writes events as an RFC 5545 calendar
+func renderICalendar(events []CalendarEvent, now time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//AnimeVerse//Episode Calendar//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:AnimeVerse")
+	writeICalLine(&b, "X-PUBLISHED-TTL:PT6H")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+event.UID)
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		if event.AllDay {
+			writeICalLine(&b, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+			writeICalLine(&b, "DTEND;VALUE=DATE:"+event.Start.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			writeICalLine(&b, "DTSTART:"+event.Start.UTC().Format("20060102T150405Z"))
+			writeICalLine(&b, "DTEND:"+event.Start.Add(CALENDAR_EPISODE_LENGTH).UTC().Format("20060102T150405Z"))
+		}
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(event.Summary))
+		if event.URL != "" {
+			writeICalLine(&b, "URL:"+event.URL)
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// writeICalLine writes a content line, folding it at 75 octets without splitting characters
+func writeICalLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && (line[cut]&0xC0) == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func escapeICalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
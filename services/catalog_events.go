@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CatalogEventType identifies how the public catalog changed
+type CatalogEventType string
+
+const (
+	CatalogAnimeAdded   CatalogEventType = "added"
+	CatalogAnimeUpdated CatalogEventType = "updated"
+	CatalogAnimeRemoved CatalogEventType = "removed"
+)
+
+// CatalogEvent describes a change to a catalog anime
+type CatalogEvent struct {
+	Type  CatalogEventType
+	Anime model.Anime
+}
+
+var (
+	catalogListenersMu sync.RWMutex
+	catalogListeners   []func(CatalogEvent)
+)
+
+// OnCatalogChange registers a listener that is called for every catalog change
+func OnCatalogChange(listener func(CatalogEvent)) {
+	catalogListenersMu.Lock()
+	defer catalogListenersMu.Unlock()
+	catalogListeners = append(catalogListeners, listener)
+}
+
+// PublishCatalogChange notifies listeners about a catalog change.
+// User list copies are not part of the catalog and are ignored.
+func PublishCatalogChange(eventType CatalogEventType, anime model.Anime) {
+	if anime.UserID != "" {
+		return
+	}
+
+	catalogListenersMu.RLock()
+	listeners := append([]func(CatalogEvent){}, catalogListeners...)
+	catalogListenersMu.RUnlock()
+
+	event := CatalogEvent{Type: eventType, Anime: anime}
+	for _, listener := range listeners {
+		func() {
+			// A misbehaving listener must not break the write that triggered it
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Catalog listener panicked on %s %s: %v", eventType, anime.Name, r)
+				}
+			}()
+			listener(event)
+		}()
+	}
+}
+
+// PublishCatalogUpdate reloads an anime after a partial update and notifies listeners
+func PublishCatalogUpdate(animeID primitive.ObjectID) {
+	var anime model.Anime
+	if err := config.Collection.FindOne(context.Background(), bson.M{"_id": animeID}).Decode(&anime); err != nil {
+		return
+	}
+	PublishCatalogChange(CatalogAnimeUpdated, anime)
+}
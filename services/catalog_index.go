@@ -0,0 +1,85 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	CATALOG_INDEX_QUEUE        = 1000 // Catalog changes waiting to be applied to an index
+	CATALOG_INDEX_UPDATE_BATCH = 500  // Changes applied to an index together
+)
+
+// catalogIndex keeps an in-memory index of the catalog current. The index is built at startup
+// and rebuilt every interval, and catalog changes in between are handed to apply in batches, so
+// an import's burst of changes is merged in at once.
+type catalogIndex struct {
+	name     string
+	interval time.Duration
+	rebuild  func() error
+	apply    func([]CatalogEvent)
+	updates  chan CatalogEvent
+
+	// Optional work between rebuilds, every tick
+	tick   time.Duration
+	onTick func()
+}
+
+// newCatalogIndex creates an index runner and subscribes it to catalog changes. Changes that
+// arrive while the queue is full are dropped; the next rebuild reloads them from the catalog.
+func newCatalogIndex(name string, interval time.Duration, rebuild func() error, apply func([]CatalogEvent)) *catalogIndex {
+	index := &catalogIndex{
+		name:     name,
+		interval: interval,
+		rebuild:  rebuild,
+		apply:    apply,
+		updates:  make(chan CatalogEvent, CATALOG_INDEX_QUEUE),
+	}
+	OnCatalogChange(func(event CatalogEvent) {
+		select {
+		case index.updates <- event:
+		default:
+		}
+	})
+	return index
+}
+
+// every runs fn between rebuilds each time tick passes
+func (c *catalogIndex) every(tick time.Duration, fn func()) *catalogIndex {
+	c.tick, c.onTick = tick, fn
+	return c
+}
+
+// run builds the index and keeps it current. It never returns. A failed build is retried after
+// a minute.
+func (c *catalogIndex) run() {
+	var ticks <-chan time.Time
+	if c.onTick != nil {
+		ticks = time.NewTicker(c.tick).C
+	}
+
+	for {
+		if err := c.rebuild(); err != nil {
+			log.Printf("Failed to build %s index: %v", c.name, err)
+			time.Sleep(time.Minute)
+			continue
+		}
+
+		rebuild := time.After(c.interval)
+	updates:
+		for {
+			select {
+			case event := <-c.updates:
+				batch := []CatalogEvent{event}
+				for len(batch) < CATALOG_INDEX_UPDATE_BATCH && len(c.updates) > 0 {
+					batch = append(batch, <-c.updates)
+				}
+				c.apply(batch)
+			case <-ticks:
+				c.onTick()
+			case <-rebuild:
+				break updates
+			}
+		}
+	}
+}
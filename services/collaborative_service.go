@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SIMILARITY_NEIGHBORS          = 30  // Neighbors stored per anime
+	SIMILARITY_MIN_SUPPORT        = 3   // Users that must share two titles before they count as similar
+	SIMILARITY_SHRINKAGE          = 10  // Damps similarities backed by only a few users
+	SIMILARITY_MAX_ITEMS_PER_USER = 500 // Caps the pairwise work a single huge list can cause
+	SIMILARITY_WRITE_BATCH        = 500
+)
+
+// statusRatings turns list statuses into an implicit preference for entries without a usable score
+var statusRatings = map[model.WatchStatus]float64{
+	model.Completed: 0.5,
+	model.Watching:  0.3,
+	model.OnHold:    -0.2,
+	model.Dropped:   -1.0,
+}
+
+var itemSimilarityCollection *mongo.Collection
+
+// InitItemSimilarityCollection initializes the item_similarity collection
+func InitItemSimilarityCollection() {
+	if config.DB == nil {
+		return
+	}
+	itemSimilarityCollection = config.GetCollection(config.DB, "item_similarity")
+}
+
+func getItemSimilarityCollection() (*mongo.Collection, error) {
+	if itemSimilarityCollection == nil {
+		InitItemSimilarityCollection()
+	}
+	if itemSimilarityCollection == nil {
+		return nil, fmt.Errorf("item similarity collection not initialized")
+	}
+	return itemSimilarityCollection, nil
+}
+
+// StartItemSimilarityScheduler periodically recomputes the item-item similarity table
+func StartItemSimilarityScheduler(interval time.Duration) {
+	// Build the table shortly after startup so new deployments don't wait a full interval
+	time.Sleep(time.Minute)
+	for {
+		if _, err := ComputeItemSimilarities(); err != nil {
+			log.Printf("Item similarity computation failed: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// listRating is one user's preference for one anime
+type listRating struct {
+	item   int
+	rating float64
+}
+
+// ComputeItemSimilarities rebuilds the item-item collaborative filtering table from every
+// user's list, using adjusted cosine similarity over mean-centred scores
+func ComputeItemSimilarities() (int, error) {
+	collection, err := getItemSimilarityCollection()
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	ctx := context.Background()
+
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "name": 1, "anilist_id": 1, "score": 1, "status": 1})
+	cur, err := config.Collection.Find(ctx, bson.M{"user_id": bson.M{"$exists": true, "$ne": ""}}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	itemIndex := make(map[string]int)
+	var itemKeys, itemNames []string
+	userEntries := make(map[string][]model.Anime)
+
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		key := animeKey(anime)
+		if _, ok := itemIndex[key]; !ok {
+			itemIndex[key] = len(itemKeys)
+			itemKeys = append(itemKeys, key)
+			itemNames = append(itemNames, anime.Name)
+		}
+		userEntries[anime.UserID] = append(userEntries[anime.UserID], anime)
+	}
+	if err := cur.Err(); err != nil {
+		return 0, err
+	}
+
+	norms := make([]float64, len(itemKeys))
+	dots := make(map[uint64]float64)
+	support := make(map[uint64]int)
+
+	for _, entries := range userEntries {
+		ratings := userRatings(entries, itemIndex)
+		for _, r := range ratings {
+			norms[r.item] += r.rating * r.rating
+		}
+
+		for a := 0; a < len(ratings); a++ {
+			for b := a + 1; b < len(ratings); b++ {
+				i, j := ratings[a].item, ratings[b].item
+				if i == j {
+					continue
+				}
+				if i > j {
+					i, j = j, i
+				}
+				pair := uint64(i)<<32 | uint64(j)
+				dots[pair] += ratings[a].rating * ratings[b].rating
+				support[pair]++
+			}
+		}
+	}
+
+	neighbors := make([][]model.AnimeNeighbor, len(itemKeys))
+	for pair, dot := range dots {
+		count := support[pair]
+		if count < SIMILARITY_MIN_SUPPORT || dot <= 0 {
+			continue
+		}
+		i, j := int(pair>>32), int(pair&0xffffffff)
+		if norms[i] == 0 || norms[j] == 0 {
+			continue
+		}
+
+		similarity := dot / (math.Sqrt(norms[i]) * math.Sqrt(norms[j]))
+		similarity *= float64(count) / float64(count+SIMILARITY_SHRINKAGE)
+		similarity = math.Round(similarity*10000) / 10000
+
+		neighbors[i] = append(neighbors[i], model.AnimeNeighbor{Key: itemKeys[j], Name: itemNames[j], Score: similarity, Support: count})
+		neighbors[j] = append(neighbors[j], model.AnimeNeighbor{Key: itemKeys[i], Name: itemNames[i], Score: similarity, Support: count})
+	}
+
+	var writes []mongo.WriteModel
+	stored := 0
+	for i, list := range neighbors {
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(a, b int) bool {
+			return list[a].Score > list[b].Score
+		})
+		if len(list) > SIMILARITY_NEIGHBORS {
+			list = list[:SIMILARITY_NEIGHBORS]
+		}
+
+		doc := model.AnimeNeighbors{Key: itemKeys[i], Name: itemNames[i], Neighbors: list, UpdatedAt: start}
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.Key}).
+			SetReplacement(doc).
+			SetUpsert(true))
+		stored++
+
+		if len(writes) >= SIMILARITY_WRITE_BATCH {
+			if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+				return 0, err
+			}
+			writes = writes[:0]
+		}
+	}
+	if len(writes) > 0 {
+		if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return 0, err
+		}
+	}
+
+	// Anything not rewritten in this run no longer has neighbors
+	if _, err := collection.DeleteMany(ctx, bson.M{"updated_at": bson.M{"$lt": start}}); err != nil {
+		return stored, err
+	}
+
+	log.Printf("Computed item similarities for %d anime from %d lists in %v", stored, len(userEntries), time.Since(start))
+	return stored, nil
+}
+
+// userRatings centres a user's scores on their own average so generous and harsh raters
+// are comparable. Unscored entries, and lists without any score spread, fall back to status.
+func userRatings(entries []model.Anime, itemIndex map[string]int) []listRating {
+	total, count := 0.0, 0
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, anime := range entries {
+		if anime.Score > 0 {
+			total += anime.Score
+			count++
+			minScore = math.Min(minScore, anime.Score)
+			maxScore = math.Max(maxScore, anime.Score)
+		}
+	}
+	spread := count >= 2 && maxScore > minScore
+	mean := 0.0
+	if count > 0 {
+		mean = total / float64(count)
+	}
+
+	seen := make(map[int]bool)
+	var ratings []listRating
+	for _, anime := range entries {
+		item := itemIndex[animeKey(anime)]
+		if seen[item] {
+			continue
+		}
+
+		rating := statusRatings[anime.Status]
+		if anime.Score > 0 && spread {
+			rating = (anime.Score - mean) / 2
+		}
+		if rating == 0 {
+			continue
+		}
+		seen[item] = true
+		ratings = append(ratings, listRating{item: item, rating: rating})
+	}
+
+	if len(ratings) > SIMILARITY_MAX_ITEMS_PER_USER {
+		sort.Slice(ratings, func(a, b int) bool {
+			return math.Abs(ratings[a].rating) > math.Abs(ratings[b].rating)
+		})
+		ratings = ratings[:SIMILARITY_MAX_ITEMS_PER_USER]
+	}
+	return ratings
+}
+
+// getItemNeighbors loads the stored neighbor lists for a set of anime keys
+func getItemNeighbors(keys []string) (map[string]model.AnimeNeighbors, error) {
+	collection, err := getItemSimilarityCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cur, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	result := make(map[string]model.AnimeNeighbors)
+	for cur.Next(ctx) {
+		var doc model.AnimeNeighbors
+		if err := cur.Decode(&doc); err == nil {
+			result[doc.Key] = doc
+		}
+	}
+	return result, cur.Err()
+}
+
+// getCatalogAnime finds a catalog anime by its ID
+func getCatalogAnime(animeID string) (*model.Anime, error) {
+	objID, err := primitive.ObjectIDFromHex(animeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anime ID")
+	}
+
+	var anime model.Anime
+	if err := config.Collection.FindOne(context.Background(), PublicCatalogFilter(bson.M{"_id": objID})).Decode(&anime); err != nil {
+		return nil, err
+	}
+	return &anime, nil
+}
+
+// findCatalogByKeys loads catalog anime for a set of animeKey values
+func findCatalogByKeys(keys []string) (map[string]model.Anime, error) {
+	anilistIDs := []int{}
+	for _, key := range keys {
+		if id, ok := strings.CutPrefix(key, "anilist:"); ok {
+			if n, err := strconv.Atoi(id); err == nil {
+				anilistIDs = append(anilistIDs, n)
+			}
+		}
+	}
+
+	// Name keys are lower-cased, so match them case-insensitively
+	var nameFilters []bson.M
+	for _, key := range keys {
+		if name, ok := strings.CutPrefix(key, "name:"); ok {
+			nameFilters = append(nameFilters, bson.M{"name": ExactText(name)})
+		}
+	}
+
+	or := []bson.M{{"anilist_id": bson.M{"$in": anilistIDs}}}
+	or = append(or, nameFilters...)
+
+	opts := options.Find().SetProjection(bson.M{"characters": 0, "related": 0, "themes": 0})
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(bson.M{"$or": or}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	result := make(map[string]model.Anime, len(keys))
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		result[animeKey(anime)] = anime
+		result["name:"+strings.ToLower(strings.TrimSpace(anime.Name))] = anime
+	}
+	return result, cur.Err()
+}
+
+// AlsoLikedAnime is a catalog anime that fans of another anime also liked
+type AlsoLikedAnime struct {
+	Anime   model.Anime `json:"anime"`
+	Score   float64     `json:"score"`
+	Support int         `json:"support"`
+}
+
+// GetAlsoLiked returns "users who liked this also liked" picks for a catalog anime
+func GetAlsoLiked(animeID string, limit int) ([]AlsoLikedAnime, error) {
+	anime, err := getCatalogAnime(animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// List copies of an anime without an AniList ID are keyed by name
+	keys := []string{animeKey(*anime), "name:" + strings.ToLower(strings.TrimSpace(anime.Name))}
+	stored, err := getItemNeighbors(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var neighbors []model.AnimeNeighbor
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		for _, neighbor := range stored[key].Neighbors {
+			if !seen[neighbor.Key] {
+				seen[neighbor.Key] = true
+				neighbors = append(neighbors, neighbor)
+			}
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Score > neighbors[j].Score
+	})
+
+	result := []AlsoLikedAnime{}
+	if len(neighbors) == 0 {
+		return result, nil
+	}
+
+	neighborKeys := make([]string, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		neighborKeys = append(neighborKeys, neighbor.Key)
+	}
+	catalog, err := findCatalogByKeys(neighborKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, neighbor := range neighbors {
+		match, ok := catalog[neighbor.Key]
+		if !ok || match.ID == anime.ID {
+			continue
+		}
+		match.Staff = nil
+		result = append(result, AlsoLikedAnime{Anime: match, Score: neighbor.Score, Support: neighbor.Support})
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
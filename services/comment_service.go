@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	COMMENT_MAX_LENGTH = 1000
+	COMMENT_MAX_DEPTH  = 5
+)
+
+// ErrCommentRateLimited is returned when a user exceeds CommentLimiter
+var ErrCommentRateLimited = fmt.Errorf("too many comments, please slow down")
+
+// AllowedReactions lists the reactions users can leave on comments
+var AllowedReactions = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+var commentCollection *mongo.Collection
+var commentReactionCollection *mongo.Collection
+
+// CommentPage represents one page of comments
+type CommentPage struct {
+	Comments []model.Comment `json:"comments"`
+}
+
+// InitCommentCollections initializes the comment and reaction collections
+func InitCommentCollections() {
+	if config.DB == nil {
+		return
+	}
+
+	ctx := context.Background()
+	commentCollection = config.GetCollection(config.DB, "comments")
+	commentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "anime_id", Value: 1}, {Key: "episode", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+
+	commentReactionCollection = config.GetCollection(config.DB, "comment_reactions")
+	commentReactionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "comment_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "reaction", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
+
+func getCommentCollections() (*mongo.Collection, *mongo.Collection, error) {
+	if commentCollection == nil || commentReactionCollection == nil {
+		InitCommentCollections()
+	}
+	if commentCollection == nil || commentReactionCollection == nil {
+		return nil, nil, fmt.Errorf("comment collections not initialized")
+	}
+	return commentCollection, commentReactionCollection, nil
+}
+
+func validateCommentContent(content string) error {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fmt.Errorf("comment cannot be empty")
+	}
+	if len(content) > COMMENT_MAX_LENGTH {
+		return fmt.Errorf("comment must be at most %d characters", COMMENT_MAX_LENGTH)
+	}
+	return nil
+}
+
+func isAllowedReaction(reaction string) bool {
+	for _, allowed := range AllowedReactions {
+		if reaction == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateComment posts a comment on an anime or episode, or a reply when parentID is set
+func CreateComment(userID, userName, animeID string, episode int, parentID, content string, spoiler bool) (*model.Comment, error) {
+	comments, _, err := getCommentCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCommentContent(content); err != nil {
+		return nil, err
+	}
+
+	if allowed, err := CommentLimiter.Allow(userID); err != nil {
+		log.Printf("Comment rate limiter unavailable: %v", err)
+	} else if !allowed {
+		return nil, ErrCommentRateLimited
+	}
+
+	comment := model.Comment{
+		UserID:    userID,
+		UserName:  userName,
+		Content:   strings.TrimSpace(content),
+		Spoiler:   spoiler,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	ctx := context.Background()
+	parentAuthor := ""
+	if parentID != "" {
+		parent, err := GetComment(parentID)
+		if err != nil {
+			return nil, fmt.Errorf("parent comment not found")
+		}
+		if parent.Depth+1 > COMMENT_MAX_DEPTH {
+			return nil, fmt.Errorf("replies cannot be nested more than %d levels", COMMENT_MAX_DEPTH)
+		}
+		comment.AnimeID = parent.AnimeID
+		comment.Episode = parent.Episode
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+		if !parent.Deleted {
+			parentAuthor = parent.UserID
+		}
+	} else {
+		objID, err := primitive.ObjectIDFromHex(animeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid anime id")
+		}
+		count, err := config.Collection.CountDocuments(ctx, PublicCatalogFilter(bson.M{"_id": objID}))
+		if err != nil || count == 0 {
+			return nil, fmt.Errorf("anime not found")
+		}
+		comment.AnimeID = objID
+		comment.Episode = episode
+	}
+
+	result, err := comments.InsertOne(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	comment.ID = result.InsertedID.(primitive.ObjectID)
+
+	if comment.ParentID != nil {
+		comments.UpdateOne(ctx, bson.M{"_id": *comment.ParentID}, bson.M{"$inc": bson.M{"reply_count": 1}})
+	}
+
+	FlagContent(model.ReportComment, comment.ID.Hex(), comment.Content)
+
+	if comment.ParentID != nil && parentAuthor != "" && parentAuthor != userID {
+		NotifyUsers([]string{parentAuthor}, model.Notification{
+			Type:    model.NotificationReply,
+			Title:   "New reply",
+			Message: fmt.Sprintf("%s replied to your comment", userName),
+			AnimeID: comment.AnimeID.Hex(),
+			ActorID: userID,
+		})
+	}
+
+	return &comment, nil
+}
+
+// GetComment returns a single comment by ID
+func GetComment(commentID string) (*model.Comment, error) {
+	comments, _, err := getCommentCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment model.Comment
+	if err := comments.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetComments returns a page of top-level comments on an anime or episode, newest first,
+// and the cursor of the next page
+func GetComments(animeID string, episode int, page PageRequest) (*CommentPage, string, error) {
+	objID, err := primitive.ObjectIDFromHex(animeID)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid anime id")
+	}
+
+	filter := bson.M{
+		"anime_id":  objID,
+		"parent_id": bson.M{"$exists": false},
+	}
+	if episode > 0 {
+		filter["episode"] = episode
+	} else {
+		filter["episode"] = bson.M{"$exists": false}
+	}
+
+	return findCommentPage(filter, page)
+}
+
+// GetReplies returns a page of replies to a comment, oldest first, and the cursor of the next page
+func GetReplies(commentID string, page PageRequest) (*CommentPage, string, error) {
+	objID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid comment id")
+	}
+
+	return findCommentPage(bson.M{"parent_id": objID}, page)
+}
+
+func findCommentPage(filter bson.M, page PageRequest) (*CommentPage, string, error) {
+	comments, _, err := getCommentCollections()
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := &CommentPage{Comments: []model.Comment{}}
+	next, err := FindPageInto(context.Background(), comments, filter, page, nil, &result.Comments)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i := range result.Comments {
+		if result.Comments[i].Deleted || result.Comments[i].Hidden {
+			result.Comments[i].Content = ""
+		}
+	}
+
+	return result, next, nil
+}
+
+// UpdateComment edits a user's own comment
+func UpdateComment(userID, commentID, content string, spoiler bool) (*model.Comment, error) {
+	comments, _, err := getCommentCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCommentContent(content); err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": objID, "user_id": userID, "deleted": bson.M{"$ne": true}, "hidden": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{
+		"content":    strings.TrimSpace(content),
+		"spoiler":    spoiler,
+		"edited":     true,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := comments.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("comment not found")
+	}
+
+	FlagContent(model.ReportComment, commentID, content)
+
+	return GetComment(commentID)
+}
+
+// DeleteComment soft-deletes a user's own comment so its replies stay threaded
+func DeleteComment(userID, commentID string) error {
+	comments, _, err := getCommentCollections()
+	if err != nil {
+		return err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return err
+	}
+
+	result, err := comments.UpdateOne(context.Background(),
+		bson.M{"_id": objID, "user_id": userID},
+		bson.M{"$set": bson.M{"deleted": true, "content": "", "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("comment not found")
+	}
+	return nil
+}
+
+// ReactToComment adds or removes a user's reaction on a comment
+func ReactToComment(userID, commentID, reaction string, add bool) (*model.Comment, error) {
+	comments, reactions, err := getCommentCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAllowedReaction(reaction) {
+		return nil, fmt.Errorf("unsupported reaction: %s", reaction)
+	}
+
+	comment, err := GetComment(commentID)
+	if err != nil || comment.Deleted || comment.Hidden {
+		return nil, fmt.Errorf("comment not found")
+	}
+
+	ctx := context.Background()
+	key := bson.M{"comment_id": comment.ID, "user_id": userID, "reaction": reaction}
+
+	delta := 0
+	if add {
+		result, err := reactions.UpdateOne(ctx, key, bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}}, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, err
+		}
+		if result.UpsertedCount > 0 {
+			delta = 1
+		}
+	} else {
+		result, err := reactions.DeleteOne(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		delta = -int(result.DeletedCount)
+	}
+
+	if delta != 0 {
+		comments.UpdateOne(ctx, bson.M{"_id": comment.ID}, bson.M{"$inc": bson.M{"reactions." + reaction: delta}})
+	}
+
+	return GetComment(commentID)
+}
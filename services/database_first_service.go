@@ -51,13 +51,13 @@ func GetAnimeWithFallback(animeName string) (*models.Anime, error) {
 }
 
 func findAnimeInDatabase(animeName string) (*models.Anime, error) {
-	filter := bson.M{
+	filter := PublicCatalogFilter(bson.M{
 		"$or": []bson.M{
-			{"name": bson.M{"$regex": animeName, "$options": "i"}},
-			{"alternative_titles.english": bson.M{"$regex": animeName, "$options": "i"}},
+			{"name": ContainsText(animeName)},
+			{"alternative_titles.english": ContainsText(animeName)},
 			{"alternative_titles.synonyms": bson.M{"$in": []string{animeName}}},
 		},
-	}
+	})
 
 	var anime models.Anime
 	err := config.Collection.FindOne(context.Background(), filter).Decode(&anime)
@@ -118,12 +118,14 @@ func enhanceExistingAnime(anime *models.Anime, animeName string) (*models.Anime,
 		anime.Staff = enhanced.Staff
 	}
 
+	previousRelated := anime.Related
 	anime.AlternativeTitles = enhanced.AlternativeTitles
 	anime.Statistics = enhanced.Statistics
 	anime.Related = enhanced.Related
 
 	// Update in database
 	updateAnimeInDatabase(anime)
+	NotifySequelAnnouncements(*anime, previousRelated)
 
 	return anime, nil
 }
@@ -168,6 +170,7 @@ func createAnimeFromAniList(animeName string) (*models.Anime, error) {
 		return nil, err
 	}
 
+	PublishCatalogChange(CatalogAnimeAdded, *anime)
 	return anime, nil
 }
 
@@ -195,6 +198,9 @@ func updateAnimeInDatabase(anime *models.Anime) error {
 		bson.M{"_id": anime.ID},
 		update,
 	)
+	if err == nil {
+		PublishCatalogUpdate(anime.ID)
+	}
 
 	return err
 }
@@ -288,12 +294,13 @@ func getSpotlightFromDatabase() ([]SpotlightAnime, error) {
 
 func saveSpotlightToDatabase(spotlight []SpotlightAnime) {
 	for _, item := range spotlight {
-		// Check if already exists
-		filter := bson.M{"name": item.Name}
-		var existing models.Anime
-		err := config.Collection.FindOne(context.Background(), filter).Decode(&existing)
-		
+		// Check if already exists in the catalog, under this title or a spelling variant
+		existing, err := FindAnimeByName(item.Name)
 		if err != nil {
+			continue
+		}
+		
+		if existing == nil {
 			// Create new anime
 			anime := models.Anime{
 				ID:        primitive.NewObjectID(),
@@ -307,7 +314,10 @@ func saveSpotlightToDatabase(spotlight []SpotlightAnime) {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			config.Collection.InsertOne(context.Background(), anime)
+			anime.NameKey, anime.TitleKeys = buildTitleKeys(anime)
+			if _, err := config.Collection.InsertOne(context.Background(), anime); err == nil {
+				PublishCatalogChange(CatalogAnimeAdded, anime)
+			}
 		} else {
 			// Update existing with high-quality images
 			update := bson.M{
@@ -318,7 +328,9 @@ func saveSpotlightToDatabase(spotlight []SpotlightAnime) {
 					"updated_at": time.Now(),
 				},
 			}
-			config.Collection.UpdateOne(context.Background(), filter, update)
+			if _, err := config.Collection.UpdateOne(context.Background(), bson.M{"_id": existing.ID}, update); err == nil {
+				PublishCatalogUpdate(existing.ID)
+			}
 		}
 	}
 }
\ No newline at end of file
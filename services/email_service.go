@@ -0,0 +1,531 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"animeverse/cache"
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	EMAIL_CATEGORY_DIGEST = "digest"
+	DIGEST_MAX_ITEMS      = 10
+)
+
+// ErrEmailDisabled is returned when SMTP_HOST is not configured
+var ErrEmailDisabled = fmt.Errorf("email is not configured")
+
+// Email is a rendered message ready for the SMTP transport
+type Email struct {
+	To             string `json:"to"`
+	Subject        string `json:"subject"`
+	Text           string `json:"text"`
+	HTML           string `json:"html"`
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+}
+
+// EmailConfig holds SMTP settings read from the environment
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	BaseURL  string
+}
+
+// LoadEmailConfig reads SMTP settings from the environment
+func LoadEmailConfig() EmailConfig {
+	cfg := EmailConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		BaseURL:  strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"),
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.From == "" {
+		cfg.From = "AnimeVerse <no-reply@animeverse.local>"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:8000"
+	}
+	return cfg
+}
+
+// SendEmailNow delivers an email synchronously over SMTP
+func SendEmailNow(email Email) error {
+	cfg := LoadEmailConfig()
+	if cfg.Host == "" {
+		return ErrEmailDisabled
+	}
+	if strings.ContainsAny(email.To, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	message, err := buildMIMEMessage(cfg, email)
+	if err != nil {
+		return err
+	}
+
+	// Local SMTP sinks usually take no auth; smtp.SendMail upgrades to STARTTLS when offered
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return smtp.SendMail(cfg.Host+":"+cfg.Port, auth, envelopeAddress(cfg.From), []string{email.To}, message)
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" From header
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
+
+// buildMIMEMessage builds a multipart/alternative message with text and HTML parts
+func buildMIMEMessage(cfg EmailConfig, email Email) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+	boundary := "animeverse-" + hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	headers := []string{
+		"From: " + cfg.From,
+		"To: " + email.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", email.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf(`Content-Type: multipart/alternative; boundary="%s"`, boundary),
+	}
+	if email.UnsubscribeURL != "" {
+		headers = append(headers, "List-Unsubscribe: <"+email.UnsubscribeURL+">")
+	}
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", email.Text},
+		{"text/html", email.HTML},
+	} {
+		fmt.Fprintf(&buf, "--%s\r\nContent-Type: %s; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", boundary, part.contentType)
+		writer := quotedprintable.NewWriter(&buf)
+		if _, err := writer.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		writer.Close()
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
+
+// QueueEmail hands an email to the job queue, which retries failed sends.
+// If the queue is unavailable the email is sent in the background instead.
+func QueueEmail(email *Email) error {
+	if LoadEmailConfig().Host == "" {
+		return ErrEmailDisabled
+	}
+
+	var err error
+	if cache.RedisClient == nil {
+		err = fmt.Errorf("redis not initialized")
+	} else {
+		err = GlobalJobQueue.Enqueue(SendEmail, map[string]interface{}{"email": email})
+	}
+
+	if err != nil {
+		log.Printf("Email queue unavailable, sending directly: %v", err)
+		go func() {
+			if err := SendEmailNow(*email); err != nil {
+				log.Printf("Failed to send email to %s: %v", email.To, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// decodeEmailJob reads the email out of a job payload
+func decodeEmailJob(payload map[string]interface{}) (Email, error) {
+	var job struct {
+		Email Email `json:"email"`
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return job.Email, err
+	}
+	if err := json.Unmarshal(data, &job); err != nil {
+		return job.Email, err
+	}
+	if job.Email.To == "" {
+		return job.Email, fmt.Errorf("missing recipient")
+	}
+	return job.Email, nil
+}
+
+// emailTokenSecret returns the key used to sign unsubscribe tokens. It has its own setting so
+// that tokens never share a key with login sessions, and there is no fallback: without it no
+// token is signed or accepted.
+func emailTokenSecret() ([]byte, error) {
+	secret := os.Getenv("EMAIL_TOKEN_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("EMAIL_TOKEN_SECRET is not set")
+	}
+	return []byte(secret), nil
+}
+
+// UnsubscribeToken signs a user and email category so unsubscribe links need no login
+func UnsubscribeToken(userID, category string) (string, error) {
+	secret, err := emailTokenSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userID + ":" + category))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// UnsubscribeURL builds the one-click unsubscribe link for a user and category
+func UnsubscribeURL(userID, category string) (string, error) {
+	token, err := UnsubscribeToken(userID, category)
+	if err != nil {
+		return "", err
+	}
+	values := url.Values{}
+	values.Set("u", userID)
+	values.Set("c", category)
+	values.Set("t", token)
+	return LoadEmailConfig().BaseURL + "/email/unsubscribe?" + values.Encode(), nil
+}
+
+// Unsubscribe verifies an unsubscribe token and opts the user out of that category
+func Unsubscribe(userID, category, token string) error {
+	if category != EMAIL_CATEGORY_DIGEST {
+		return fmt.Errorf("unknown email category")
+	}
+	expected, err := UnsubscribeToken(userID, category)
+	if err != nil {
+		log.Printf("Cannot verify unsubscribe link: %v", err)
+		return fmt.Errorf("unsubscribe links are unavailable")
+	}
+	if !hmac.Equal([]byte(token), []byte(expected)) {
+		return fmt.Errorf("invalid unsubscribe link")
+	}
+
+	return SetDigestSubscription(userID, false)
+}
+
+// SetDigestSubscription opts a user in or out of weekly digest emails
+func SetDigestSubscription(userID string, subscribed bool) error {
+	result, err := config.UserCollection.UpdateOne(context.Background(),
+		bson.M{"supabase_id": userID},
+		bson.M{"$set": bson.M{"email_prefs.digest_unsubscribed": !subscribed, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// displayName picks the friendliest name available for a user
+func displayName(user model.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	if user.Username != "" {
+		return user.Username
+	}
+	return "there"
+}
+
+// SendWelcomeEmail queues the welcome email for a newly created user
+func SendWelcomeEmail(user model.User) {
+	if user.Email == "" {
+		return
+	}
+
+	email, err := renderEmail("welcome", user.Email, WelcomeEmailData{
+		Name:    displayName(user),
+		BaseURL: LoadEmailConfig().BaseURL,
+	}, "")
+	if err != nil {
+		log.Printf("Failed to render welcome email: %v", err)
+		return
+	}
+
+	if err := QueueEmail(email); err != nil && err != ErrEmailDisabled {
+		log.Printf("Failed to queue welcome email: %v", err)
+	}
+}
+
+// SendAccountNotice queues a transactional account email to a user
+func SendAccountNotice(userID, heading, message string) {
+	user, err := GetUserBySupabaseID(userID)
+	if err != nil || user.Email == "" {
+		return
+	}
+
+	email, err := renderEmail("account_notice", user.Email, AccountNoticeData{
+		Name:    displayName(*user),
+		Heading: heading,
+		Message: message,
+		BaseURL: LoadEmailConfig().BaseURL,
+	}, "")
+	if err != nil {
+		log.Printf("Failed to render account notice: %v", err)
+		return
+	}
+
+	if err := QueueEmail(email); err != nil && err != ErrEmailDisabled {
+		log.Printf("Failed to queue account notice: %v", err)
+	}
+}
+
+// isoWeek formats the ISO week of t, e.g. "2025-W07"
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// BuildWeeklyDigest collects this week's airing episodes from a user's list and their friends' recent activity
+func BuildWeeklyDigest(user model.User) (*WeeklyDigestData, error) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// A digest always carries a working unsubscribe link, so none is built without one
+	unsubscribeURL, err := UnsubscribeURL(user.SupabaseID, EMAIL_CATEGORY_DIGEST)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &WeeklyDigestData{
+		Name:           displayName(user),
+		BaseURL:        LoadEmailConfig().BaseURL,
+		UnsubscribeURL: unsubscribeURL,
+	}
+
+	list, err := GetUserList(user.SupabaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	anilistIDs := []int{}
+	for _, entry := range list {
+		if entry.Status != model.Watching && entry.Status != model.PlanToWatch {
+			continue
+		}
+		names = append(names, entry.Name)
+		if entry.AniListID > 0 {
+			anilistIDs = append(anilistIDs, entry.AniListID)
+		}
+	}
+
+	if len(names) > 0 {
+		filter := PublicCatalogFilter(bson.M{
+			"airing.next_airing_at": bson.M{"$gte": now, "$lt": now.Add(7 * 24 * time.Hour)},
+			"$or": []bson.M{
+				{"name": bson.M{"$in": names}},
+				{"anilist_id": bson.M{"$in": anilistIDs}},
+			},
+		})
+		opts := options.Find().SetSort(bson.D{{Key: "airing.next_airing_at", Value: 1}}).SetLimit(DIGEST_MAX_ITEMS)
+
+		cur, err := config.Collection.Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		var airing []model.Anime
+		err = cur.All(ctx, &airing)
+		cur.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, anime := range airing {
+			digest.Airing = append(digest.Airing, DigestAiringItem{
+				AnimeName: anime.Name,
+				Episode:   anime.Airing.NextEpisode,
+				AiringAt:  anime.Airing.NextAiringAt.UTC().Format("Mon Jan 2, 15:04 UTC"),
+			})
+		}
+	}
+
+	following, err := GetFollowing(user.SupabaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []string{}
+	for _, friendID := range following {
+		if CanViewList(friendID, user.SupabaseID) {
+			visible = append(visible, friendID)
+		}
+	}
+
+	if len(visible) > 0 {
+		friends, err := GetUsersBySupabaseIDs(visible)
+		if err != nil {
+			return nil, err
+		}
+		friendNames := map[string]string{}
+		for _, friend := range friends {
+			friendNames[friend.SupabaseID] = displayName(friend)
+		}
+
+		filter := bson.M{
+			"user_id":    bson.M{"$in": visible},
+			"updated_at": bson.M{"$gte": now.Add(-7 * 24 * time.Hour)},
+		}
+		opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetLimit(DIGEST_MAX_ITEMS)
+
+		cur, err := config.Collection.Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		var entries []model.Anime
+		err = cur.All(ctx, &entries)
+		cur.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			digest.Activity = append(digest.Activity, DigestActivityItem{
+				UserName:  friendNames[entry.UserID],
+				AnimeName: entry.Name,
+				Status:    string(entry.Status),
+				Score:     entry.Score,
+			})
+		}
+	}
+
+	return digest, nil
+}
+
+// SendWeeklyDigests queues this week's digest for every subscribed user who hasn't had one yet
+func SendWeeklyDigests() (int, error) {
+	if LoadEmailConfig().Host == "" {
+		return 0, ErrEmailDisabled
+	}
+
+	ctx := context.Background()
+	week := isoWeek(time.Now().UTC())
+	filter := bson.M{
+		"email":                           bson.M{"$nin": bson.A{"", nil}},
+		"email_prefs.digest_unsubscribed": bson.M{"$ne": true},
+		"email_prefs.last_digest_week":    bson.M{"$ne": week},
+	}
+
+	cur, err := config.UserCollection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	sent := 0
+	for cur.Next(ctx) {
+		var user model.User
+		if err := cur.Decode(&user); err != nil {
+			continue
+		}
+
+		// Claim the week first so a restart mid-run never sends a second digest
+		claim, err := config.UserCollection.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "email_prefs.last_digest_week": bson.M{"$ne": week}},
+			bson.M{"$set": bson.M{"email_prefs.last_digest_week": week}},
+		)
+		if err != nil || claim.ModifiedCount == 0 {
+			continue
+		}
+
+		digest, err := BuildWeeklyDigest(user)
+		if err != nil {
+			log.Printf("Failed to build digest for %s: %v", user.SupabaseID, err)
+			releaseDigestWeek(user, week)
+			continue
+		}
+		if len(digest.Airing) == 0 && len(digest.Activity) == 0 {
+			continue
+		}
+
+		email, err := renderEmail("weekly_digest", user.Email, digest, digest.UnsubscribeURL)
+		if err != nil {
+			log.Printf("Failed to render digest for %s: %v", user.SupabaseID, err)
+			releaseDigestWeek(user, week)
+			continue
+		}
+		if err := QueueEmail(email); err != nil {
+			log.Printf("Failed to queue digest for %s: %v", user.SupabaseID, err)
+			releaseDigestWeek(user, week)
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("Queued %d weekly digests for %s", sent, week)
+	return sent, nil
+}
+
+// releaseDigestWeek gives back a week claimed for a digest that could not be queued, so the
+// next run tries the user again
+func releaseDigestWeek(user model.User, week string) {
+	update := bson.M{"$unset": bson.M{"email_prefs.last_digest_week": ""}}
+	if previous := user.EmailPrefs.LastDigestWeek; previous != "" {
+		update = bson.M{"$set": bson.M{"email_prefs.last_digest_week": previous}}
+	}
+	_, err := config.UserCollection.UpdateOne(context.Background(),
+		bson.M{"_id": user.ID, "email_prefs.last_digest_week": week}, update)
+	if err != nil {
+		log.Printf("Failed to release digest week for %s: %v", user.SupabaseID, err)
+	}
+}
+
+// StartWeeklyDigestScheduler sends weekly digests every Monday from 09:00 UTC
+func StartWeeklyDigestScheduler() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now().UTC()
+		if now.Weekday() != time.Monday || now.Hour() < 9 {
+			continue
+		}
+		// Users already sent this week are skipped, so hourly retries are safe
+		if _, err := SendWeeklyDigests(); err != nil && err != ErrEmailDisabled {
+			log.Printf("Weekly digest run failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// emailTemplate holds the subject, plain-text and HTML bodies of one email
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// WelcomeEmailData is rendered by the "welcome" template
+type WelcomeEmailData struct {
+	Name    string
+	BaseURL string
+}
+
+// AccountNoticeData is rendered by the "account_notice" template
+type AccountNoticeData struct {
+	Name    string
+	Heading string
+	Message string
+	BaseURL string
+}
+
+// DigestAiringItem is an episode airing this week from the user's list
+type DigestAiringItem struct {
+	AnimeName string
+	Episode   int
+	AiringAt  string
+}
+
+// DigestActivityItem is a recent list update by someone the user follows
+type DigestActivityItem struct {
+	UserName  string
+	AnimeName string
+	Status    string
+	Score     float64
+}
+
+// WeeklyDigestData is rendered by the "weekly_digest" template
+type WeeklyDigestData struct {
+	Name           string
+	Airing         []DigestAiringItem
+	Activity       []DigestActivityItem
+	BaseURL        string
+	UnsubscribeURL string
+}
+
+// layoutData is passed to the shared HTML layout around each template's content
+type layoutData struct {
+	Data           interface{}
+	UnsubscribeURL string
+}
+
+const emailLayoutHTML = `<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:0;background:#f3f4f6;font-family:Arial,sans-serif;color:#1f2937">
+	<div style="max-width:560px;margin:0 auto;padding:24px">
+		<h1 style="color:#4f46e5;font-size:22px">AnimeVerse</h1>
+		<div style="background:#ffffff;border-radius:12px;padding:24px">{{template "content" .Data}}</div>
+		<p style="font-size:12px;color:#6b7280;margin-top:16px">
+			You are receiving this because you have an AnimeVerse account.
+			{{if .UnsubscribeURL}}<a href="{{.UnsubscribeURL}}" style="color:#6b7280">Unsubscribe from these emails</a>{{end}}
+		</p>
+	</div>
+</body>
+</html>`
+
+var emailTemplates = map[string]emailTemplate{
+	"welcome": mustEmailTemplate(
+		`Welcome to AnimeVerse, {{.Name}}!`,
+		`Hi {{.Name}},
+
+Welcome to AnimeVerse! Start building your list at {{.BaseURL}}/my-list.html and follow friends to see what they're watching.
+
+See you around,
+AnimeVerse`,
+		`<h2 style="font-size:18px">Hi {{.Name}},</h2>
+<p>Welcome to AnimeVerse! Start building your list and follow friends to see what they're watching.</p>
+<p><a href="{{.BaseURL}}/my-list.html" style="background:#4f46e5;color:#fff;padding:10px 16px;border-radius:8px;text-decoration:none">Open my list</a></p>`,
+	),
+	"account_notice": mustEmailTemplate(
+		`{{.Heading}}`,
+		`Hi {{.Name}},
+
+{{.Message}}
+
+AnimeVerse`,
+		`<h2 style="font-size:18px">{{.Heading}}</h2>
+<p>Hi {{.Name}},</p>
+<p>{{.Message}}</p>`,
+	),
+	"weekly_digest": mustEmailTemplate(
+		`Your AnimeVerse week{{if .Airing}}: {{len .Airing}} episodes airing{{end}}`,
+		`Hi {{.Name}},
+{{if .Airing}}
+Airing this week from your list:
+{{range .Airing}}- {{.AnimeName}} episode {{.Episode}} ({{.AiringAt}})
+{{end}}{{end}}{{if .Activity}}
+What your friends have been watching:
+{{range .Activity}}- {{.UserName}}: {{.AnimeName}} ({{.Status}}{{if .Score}}, {{printf "%.0f" .Score}}/10{{end}})
+{{end}}{{end}}
+Unsubscribe from weekly digests: {{.UnsubscribeURL}}`,
+		`<h2 style="font-size:18px">Hi {{.Name}}, here's your week</h2>
+{{if .Airing}}<h3 style="font-size:16px">Airing this week from your list</h3>
+<ul>{{range .Airing}}<li><strong>{{.AnimeName}}</strong> episode {{.Episode}} &middot; {{.AiringAt}}</li>{{end}}</ul>{{end}}
+{{if .Activity}}<h3 style="font-size:16px">What your friends have been watching</h3>
+<ul>{{range .Activity}}<li>{{.UserName}}: <strong>{{.AnimeName}}</strong> ({{.Status}}{{if .Score}}, {{printf "%.0f" .Score}}/10{{end}})</li>{{end}}</ul>{{end}}`,
+	),
+}
+
+// mustEmailTemplate parses a template set at startup, wrapping the HTML body in the shared layout
+func mustEmailTemplate(subject, text, html string) emailTemplate {
+	layout := htmltemplate.Must(htmltemplate.New("layout").Parse(emailLayoutHTML))
+	return emailTemplate{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(text)),
+		html:    htmltemplate.Must(layout.New("content").Parse(html)),
+	}
+}
+
+// renderEmail renders a named template into a ready-to-send email
+func renderEmail(name, to string, data interface{}, unsubscribeURL string) (*Email, error) {
+	tmpl, ok := emailTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template: %s", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	// The layout reads UnsubscribeURL, so wrap the data alongside it
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", layoutData{Data: data, UnsubscribeURL: unsubscribeURL}); err != nil {
+		return nil, err
+	}
+
+	return &Email{
+		To:             to,
+		Subject:        subject.String(),
+		Text:           text.String(),
+		HTML:           html.String(),
+		UnsubscribeURL: unsubscribeURL,
+	}, nil
+}
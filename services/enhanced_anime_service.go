@@ -1,11 +1,8 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 
 	"animeverse/cache"
@@ -52,6 +49,7 @@ func EnhanceAnimeWithFullData(animeID string, animeName string) (*models.Anime,
 	}
 
 	// Merge enhanced data
+	previousRelated := anime.Related
 	anime.AlternativeTitles = enhanced.AlternativeTitles
 	anime.Information = enhanced.Information
 	anime.Statistics = enhanced.Statistics
@@ -75,6 +73,8 @@ func EnhanceAnimeWithFullData(animeID string, animeName string) (*models.Anime,
 	}
 
 	config.Collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, updateData)
+	NotifySequelAnnouncements(anime, previousRelated)
+	PublishCatalogUpdate(objectID)
 
 	// Cache for 24 hours
 	cache.Set(cacheKey, anime, 24*time.Hour)
@@ -83,9 +83,9 @@ func EnhanceAnimeWithFullData(animeID string, animeName string) (*models.Anime,
 }
 
 func fetchFullAnimeDataFromAniList(animeName string) (*EnhancedAnimeData, error) {
-	query := fmt.Sprintf(`{
+	query := `query ($search: String) {
 		Page(page: 1, perPage: 1) {
-			media(search: "%s", type: ANIME) {
+			media(search: $search, type: ANIME) {
 				title {
 					romaji
 					english
@@ -174,22 +174,7 @@ func fetchFullAnimeDataFromAniList(animeName string) (*EnhancedAnimeData, error)
 				}
 			}
 		}
-	}`, animeName)
-
-	requestBody := map[string]interface{}{
-		"query": query,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	}`
 
 	var anilistResp struct {
 		Data struct {
@@ -286,7 +271,7 @@ func fetchFullAnimeDataFromAniList(animeName string) (*EnhancedAnimeData, error)
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&anilistResp); err != nil {
+	if err := QueryAniList(query, map[string]interface{}{"search": animeName}, &anilistResp); err != nil {
 		return nil, err
 	}
 
@@ -367,6 +352,7 @@ func fetchFullAnimeDataFromAniList(animeName string) (*EnhancedAnimeData, error)
 		}
 	}
 
+	ParseAnimeInformation(&enhanced.Information)
 	return enhanced, nil
 }
 
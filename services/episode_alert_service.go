@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ALERT_MODE_INSTANT  = "instant"
+	ALERT_MODE_DIGEST   = "digest"
+	DEFAULT_DIGEST_HOUR = 9
+)
+
+var episodeAlertCollection *mongo.Collection
+
+// pendingEpisodeAlert is a new-episode alert held back by quiet hours or digest mode
+type pendingEpisodeAlert struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	UserID       string             `bson:"user_id"`
+	AnimeID      string             `bson:"anime_id"`
+	AnimeName    string             `bson:"anime_name"`
+	Episode      int                `bson:"episode"`
+	DeliverAfter time.Time          `bson:"deliver_after"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+// InitEpisodeAlertCollection initializes the pending episode alert collection
+func InitEpisodeAlertCollection() {
+	if config.DB == nil {
+		return
+	}
+
+	episodeAlertCollection = config.GetCollection(config.DB, "episode_alerts")
+	episodeAlertCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "anime_id", Value: 1}, {Key: "episode", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "deliver_after", Value: 1}}},
+	})
+}
+
+func getEpisodeAlertCollection() (*mongo.Collection, error) {
+	if episodeAlertCollection == nil {
+		InitEpisodeAlertCollection()
+	}
+	if episodeAlertCollection == nil {
+		return nil, fmt.Errorf("episode alert collection not initialized")
+	}
+	return episodeAlertCollection, nil
+}
+
+// StartEpisodeAlertScheduler periodically detects aired episodes and flushes held-back alerts
+func StartEpisodeAlertScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := CheckAiredEpisodes(); err != nil {
+			log.Printf("Episode check failed: %v", err)
+		}
+		if err := FlushEpisodeAlerts(); err != nil {
+			log.Printf("Episode alert flush failed: %v", err)
+		}
+	}
+}
+
+// RecordAiring stores the latest airing data for a catalog anime and announces newly aired episodes.
+// The stored episode only moves forward, so repeated refreshes never announce the same episode twice.
+func RecordAiring(animeID primitive.ObjectID, lastAired, nextEpisode int, nextAiringAt *time.Time) error {
+	now := time.Now()
+	airing := model.AiringInfo{
+		LastAiredEpisode: lastAired,
+		NextEpisode:      nextEpisode,
+		NextAiringAt:     nextAiringAt,
+		UpdatedAt:        now,
+	}
+
+	filter := PublicCatalogFilter(bson.M{
+		"_id": animeID,
+		"$or": []bson.M{
+			{"airing": bson.M{"$exists": false}},
+			{"airing.last_aired_episode": bson.M{"$lt": lastAired}},
+		},
+	})
+
+	var previous model.Anime
+	err := config.Collection.FindOneAndUpdate(context.Background(), filter,
+		bson.M{"$set": bson.M{"airing": airing}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+
+	if err == mongo.ErrNoDocuments {
+		// No new episode; just keep the upcoming episode data current
+		_, err = config.Collection.UpdateOne(context.Background(),
+			PublicCatalogFilter(bson.M{"_id": animeID}),
+			bson.M{"$set": bson.M{
+				"airing.next_episode":   nextEpisode,
+				"airing.next_airing_at": nextAiringAt,
+				"airing.updated_at":     now,
+			}},
+		)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// The first airing data seen for an anime is a baseline, not a new release
+	if previous.Airing == nil || lastAired == 0 {
+		return nil
+	}
+
+	previous.Airing = &airing
+	announceEpisode(previous, lastAired)
+	return nil
+}
+
+// CheckAiredEpisodes announces episodes whose scheduled air time has passed since the last season refresh
+func CheckAiredEpisodes() error {
+	if config.Collection == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	ctx := context.Background()
+	filter := PublicCatalogFilter(bson.M{
+		"airing.next_airing_at": bson.M{"$lte": time.Now()},
+		"$expr":                 bson.M{"$gt": bson.A{"$airing.next_episode", "$airing.last_aired_episode"}},
+	})
+
+	cur, err := config.Collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"airing": 1, "progress": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return err
+	}
+
+	for _, anime := range animes {
+		aired := anime.Airing.NextEpisode
+
+		// Assume a weekly release until the next refresh says otherwise
+		var nextAt *time.Time
+		next := 0
+		if anime.Progress.Total == 0 || aired < anime.Progress.Total {
+			t := anime.Airing.NextAiringAt.Add(7 * 24 * time.Hour)
+			nextAt = &t
+			next = aired + 1
+		}
+
+		if err := RecordAiring(anime.ID, aired, next, nextAt); err != nil {
+			log.Printf("Failed to record episode %d of %s: %v", aired, anime.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// announceEpisode alerts users watching or planning to watch an anime, respecting their preferences
+func announceEpisode(anime model.Anime, episode int) {
+	recipients, err := GetListHolders(anime, []model.WatchStatus{model.Watching, model.PlanToWatch})
+	if err != nil {
+		log.Printf("Failed to find watchers of %s: %v", anime.Name, err)
+		return
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	prefs, err := getAlertPreferences(recipients)
+	if err != nil {
+		log.Printf("Failed to load alert preferences: %v", err)
+	}
+
+	animeID := anime.ID.Hex()
+	now := time.Now()
+	instant := []string{}
+
+	for _, userID := range recipients {
+		pref := prefs[userID]
+		if containsString(pref.MutedAnime, animeID) {
+			continue
+		}
+
+		deliverAt := alertDeliveryTime(pref, now)
+		if !deliverAt.After(now) {
+			instant = append(instant, userID)
+			continue
+		}
+
+		if err := holdEpisodeAlert(userID, anime, episode, deliverAt); err != nil {
+			log.Printf("Failed to hold episode alert for %s: %v", userID, err)
+		}
+	}
+
+	NotifyUsers(instant, newEpisodeNotification(animeID, anime.Name, episode))
+}
+
+func newEpisodeNotification(animeID, animeName string, episode int) model.Notification {
+	return model.Notification{
+		Type:     model.NotificationNewEpisode,
+		Title:    "New episode",
+		Message:  fmt.Sprintf("Episode %d of %s is out", episode, animeName),
+		AnimeID:  animeID,
+		DedupKey: fmt.Sprintf("episode:%s:%d", animeID, episode),
+	}
+}
+
+func holdEpisodeAlert(userID string, anime model.Anime, episode int, deliverAt time.Time) error {
+	collection, err := getEpisodeAlertCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(context.Background(),
+		bson.M{"user_id": userID, "anime_id": anime.ID.Hex(), "episode": episode},
+		bson.M{"$setOnInsert": bson.M{
+			"anime_name":    anime.Name,
+			"deliver_after": deliverAt,
+			"created_at":    time.Now(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// FlushEpisodeAlerts delivers held-back alerts that are due, one notification or digest per user
+func FlushEpisodeAlerts() error {
+	collection, err := getEpisodeAlertCollection()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cur, err := collection.Find(ctx, bson.M{"deliver_after": bson.M{"$lte": time.Now()}},
+		options.Find().SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	var pending []pendingEpisodeAlert
+	if err := cur.All(ctx, &pending); err != nil {
+		return err
+	}
+
+	byUser := map[string][]pendingEpisodeAlert{}
+	for _, alert := range pending {
+		byUser[alert.UserID] = append(byUser[alert.UserID], alert)
+	}
+
+	for userID, alerts := range byUser {
+		notification := newEpisodeNotification(alerts[0].AnimeID, alerts[0].AnimeName, alerts[0].Episode)
+		if len(alerts) > 1 {
+			notification = episodeDigestNotification(alerts)
+		}
+
+		// Delivery is deduplicated, so a crash before the delete below cannot double-notify
+		if err := DeliverNotification([]string{userID}, notification); err != nil {
+			log.Printf("Failed to deliver episode alerts to %s: %v", userID, err)
+			continue
+		}
+
+		ids := make([]primitive.ObjectID, len(alerts))
+		for i, alert := range alerts {
+			ids[i] = alert.ID
+		}
+		collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	}
+	return nil
+}
+
+func episodeDigestNotification(alerts []pendingEpisodeAlert) model.Notification {
+	lines := make([]string, len(alerts))
+	ids := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = fmt.Sprintf("%s episode %d", alert.AnimeName, alert.Episode)
+		ids[i] = alert.ID.Hex()
+	}
+	sort.Strings(ids)
+	hash := sha1.Sum([]byte(strings.Join(ids, ",")))
+
+	return model.Notification{
+		Type:     model.NotificationNewEpisode,
+		Title:    fmt.Sprintf("%d new episodes", len(alerts)),
+		Message:  strings.Join(lines, ", "),
+		DedupKey: "episode-digest:" + hex.EncodeToString(hash[:]),
+	}
+}
+
+// alertDeliveryTime returns when an alert created at now should reach a user
+func alertDeliveryTime(pref model.AlertPreferences, now time.Time) time.Time {
+	loc := time.UTC
+	if pref.Timezone != "" {
+		if l, err := time.LoadLocation(pref.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	deliverAt := now.In(loc)
+	if pref.Mode == ALERT_MODE_DIGEST {
+		hour := DEFAULT_DIGEST_HOUR
+		if pref.DigestHour != nil {
+			hour = *pref.DigestHour
+		}
+		deliverAt = nextLocalHour(deliverAt, hour)
+	}
+
+	if pref.QuietHoursStart != nil && pref.QuietHoursEnd != nil &&
+		inQuietHours(deliverAt.Hour(), *pref.QuietHoursStart, *pref.QuietHoursEnd) {
+		deliverAt = nextLocalHour(deliverAt, *pref.QuietHoursEnd)
+	}
+
+	if deliverAt.Equal(now.In(loc)) {
+		return now
+	}
+	return deliverAt
+}
+
+// nextLocalHour returns the next time after t at the start of the given hour in t's location
+func nextLocalHour(t time.Time, hour int) time.Time {
+	candidate := time.Date(t.Year(), t.Month(), t.Day(), hour, 0, 0, 0, t.Location())
+	if !candidate.After(t) {
+		candidate = candidate.AddDate(0, 0, 1)
+	}
+	return candidate
+}
+
+// inQuietHours reports whether hour falls in [start, end), wrapping past midnight
+func inQuietHours(hour, start, end int) bool {
+	if start == end {
+		return false
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func getAlertPreferences(userIDs []string) (map[string]model.AlertPreferences, error) {
+	prefs := map[string]model.AlertPreferences{}
+
+	ctx := context.Background()
+	cur, err := config.UserCollection.Find(ctx,
+		bson.M{"supabase_id": bson.M{"$in": userIDs}},
+		options.Find().SetProjection(bson.M{"supabase_id": 1, "alerts": 1}),
+	)
+	if err != nil {
+		return prefs, err
+	}
+	defer cur.Close(ctx)
+
+	var users []model.User
+	if err := cur.All(ctx, &users); err != nil {
+		return prefs, err
+	}
+	for _, user := range users {
+		prefs[user.SupabaseID] = user.Alerts
+	}
+	return prefs, nil
+}
+
+// UpdateAlertPreferences replaces a user's delivery preferences, keeping their muted anime
+func UpdateAlertPreferences(userID string, pref model.AlertPreferences) (*model.User, error) {
+	if pref.Mode == "" {
+		pref.Mode = ALERT_MODE_INSTANT
+	}
+	if pref.Mode != ALERT_MODE_INSTANT && pref.Mode != ALERT_MODE_DIGEST {
+		return nil, fmt.Errorf("mode must be %s or %s", ALERT_MODE_INSTANT, ALERT_MODE_DIGEST)
+	}
+	for _, hour := range []*int{pref.DigestHour, pref.QuietHoursStart, pref.QuietHoursEnd} {
+		if hour != nil && (*hour < 0 || *hour > 23) {
+			return nil, fmt.Errorf("hours must be between 0 and 23")
+		}
+	}
+	if (pref.QuietHoursStart == nil) != (pref.QuietHoursEnd == nil) {
+		return nil, fmt.Errorf("quiet hours need both a start and an end")
+	}
+	if pref.Timezone != "" {
+		if _, err := time.LoadLocation(pref.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone: %s", pref.Timezone)
+		}
+	}
+
+	_, err := config.UserCollection.UpdateOne(context.Background(),
+		bson.M{"supabase_id": userID},
+		bson.M{"$set": bson.M{
+			"alerts.mode":              pref.Mode,
+			"alerts.digest_hour":       pref.DigestHour,
+			"alerts.quiet_hours_start": pref.QuietHoursStart,
+			"alerts.quiet_hours_end":   pref.QuietHoursEnd,
+			"alerts.timezone":          pref.Timezone,
+			"updated_at":               time.Now(),
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetUserBySupabaseID(userID)
+}
+
+// SetAnimeAlertsMuted mutes or unmutes new-episode alerts for one anime
+func SetAnimeAlertsMuted(userID, animeID string, muted bool) (*model.User, error) {
+	if _, err := primitive.ObjectIDFromHex(animeID); err != nil {
+		return nil, fmt.Errorf("invalid anime id")
+	}
+
+	update := bson.M{"$pull": bson.M{"alerts.muted_anime": animeID}}
+	if muted {
+		update = bson.M{"$addToSet": bson.M{"alerts.muted_anime": animeID}}
+	}
+
+	if _, err := config.UserCollection.UpdateOne(context.Background(), bson.M{"supabase_id": userID}, update); err != nil {
+		return nil, err
+	}
+
+	return GetUserBySupabaseID(userID)
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
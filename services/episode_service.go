@@ -25,6 +25,9 @@ func IncrementEpisode(animeId string) (*model.Anime, error) {
 
 	var anime model.Anime
 	err = config.Collection.FindOne(context.Background(), filter).Decode(&anime)
+	if err == nil && anime.UserID != "" {
+		EmitEpisodeWatched(anime.UserID, anime, anime.Progress.Watched-1)
+	}
 	return &anime, err
 }
 
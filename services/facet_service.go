@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"animeverse/cache"
+	"animeverse/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	FACET_CACHE_PREFIX = "facets:"
+	FACET_VALUE_LIMIT  = 50 // Values listed per facet, most common first
+)
+
+// facetFields are the facets counted for search and browse results. Each is counted with
+// every filter of the query except those on its own query field, so choosing another value
+// of the same facet shows how many results it would give.
+var facetFields = []facetField{
+	{Name: "genre", Field: "genre", Path: "genre", Array: true},
+	{Name: "year", Field: "year", Path: "year", ByValue: true},
+	{Name: "season", Field: "season", Path: "season"},
+	{Name: "format", Field: "type", Path: "type"},
+	{Name: "studio", Field: "studio", Path: "information.studios", Array: true},
+	{Name: "status", Field: "airing", Path: "information.status"},
+	{Name: "score", Field: "score", Path: "score", Bucket: true, ByValue: true},
+}
+
+type facetField struct {
+	Name    string // Key in the response
+	Field   string // Query language field the facet ignores
+	Path    string
+	Array   bool // Count each element rather than the whole value
+	Bucket  bool // Count whole-number buckets of a numeric value
+	ByValue bool // Order by value, highest first, rather than by count
+}
+
+// FacetCount is the number of results having one facet value. Score buckets are the whole
+// number a score rounds down to, so 8 counts scores from 8 up to but not including 9.
+type FacetCount struct {
+	Value interface{} `json:"value" bson:"_id"`
+	Count int64       `json:"count" bson:"count"`
+}
+
+// Facets holds the counts of each facet, keyed by facet name, and the number of results
+// with every filter applied
+type Facets struct {
+	Total  int64                   `json:"total"`
+	Counts map[string][]FacetCount `json:"counts"`
+}
+
+// CountFacets counts the anime matching base and the query's field filters for each facet in
+// one $facet aggregation. base holds everything that is not a field filter of the query, such
+// as the text match and the catalog or user scope, and is applied to every facet.
+func CountFacets(base bson.M, query *SearchQuery) (*Facets, error) {
+	stages := bson.M{
+		"total": bson.A{bson.M{"$match": query.Filter()}, bson.M{"$count": "count"}},
+	}
+	for _, f := range facetFields {
+		stages[f.Name] = f.pipeline(query.FilterExcept(f.Field))
+	}
+
+	ctx := context.Background()
+	cur, err := config.Collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: base}},
+		{{Key: "$facet", Value: stages}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []map[string][]FacetCount
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	facets := &Facets{Counts: make(map[string][]FacetCount)}
+	for _, f := range facetFields {
+		facets.Counts[f.Name] = []FacetCount{}
+	}
+	if len(out) == 0 {
+		return facets, nil
+	}
+	for name, counts := range out[0] {
+		if name == "total" {
+			if len(counts) > 0 {
+				facets.Total = counts[0].Count
+			}
+			continue
+		}
+		facets.Counts[name] = counts
+	}
+	return facets, nil
+}
+
+// pipeline counts the facet's values among the documents matching filter
+func (f facetField) pipeline(filter bson.M) bson.A {
+	pipeline := bson.A{}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+	if f.Array {
+		pipeline = append(pipeline, bson.M{"$unwind": "$" + f.Path})
+	}
+	pipeline = append(pipeline, bson.M{"$match": bson.M{f.Path: bson.M{"$nin": bson.A{nil, "", 0}}}})
+
+	var value interface{} = "$" + f.Path
+	if f.Bucket {
+		value = bson.M{"$floor": "$" + f.Path}
+	}
+	pipeline = append(pipeline, bson.M{"$group": bson.M{"_id": value, "count": bson.M{"$sum": 1}}})
+
+	if f.ByValue {
+		pipeline = append(pipeline, bson.M{"$sort": bson.D{{Key: "_id", Value: -1}}})
+	} else {
+		pipeline = append(pipeline, bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}})
+	}
+	return append(pipeline, bson.M{"$limit": FACET_VALUE_LIMIT})
+}
+
+// Facets counts the facets of a search. The text is matched the way Search matches it,
+// including the fall back to title fragments when no whole word matches.
+func (s *SearchService) Facets(q *SearchQuery) (*Facets, error) {
+	cacheKey := FACET_CACHE_PREFIX + "search:" + q.String()
+	var facets Facets
+	if err := cache.Get(cacheKey, &facets); err == nil {
+		return &facets, nil
+	}
+
+	query := NormalizeTitle(q.Text)
+	fragment := titleFragmentMatch(query)
+	var match bson.M
+	if query != "" {
+		match = fragment
+		if terms := searchTerms(query); len(terms) > 0 {
+			match = searchTextMatch(query, terms)
+		}
+	}
+
+	result, err := CountFacets(PublicCatalogFilter(withFilter(match, nil)), q)
+	if err == nil && result.Total == 0 && query != "" && match["title_keys"] == nil {
+		result, err = CountFacets(PublicCatalogFilter(withFilter(fragment, nil)), q)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Set(cacheKey, result, s.CacheDuration)
+	return result, nil
+}
+
+// BrowseFacets counts the facets of FilterAnimes results, over the user's list when userID is set
+func BrowseFacets(query *SearchQuery, userID string, info InformationFilters) (*Facets, error) {
+	cacheKey := ""
+	if userID == "" {
+		cacheKey = fmt.Sprintf("%sbrowse:%s:%+v", FACET_CACHE_PREFIX, query.String(), info)
+		var facets Facets
+		if err := cache.Get(cacheKey, &facets); err == nil {
+			return &facets, nil
+		}
+	}
+
+	facets, err := CountFacets(browseFilter(query, userID, info), query)
+	if err != nil {
+		return nil, err
+	}
+	if cacheKey != "" {
+		cache.Set(cacheKey, facets, 10*time.Minute)
+	}
+	return facets, nil
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DIVERGENCE_THRESHOLD is the score gap at which a shared title counts as divergent
+const DIVERGENCE_THRESHOLD = 3.0
+
+var followCollection *mongo.Collection
+
+// InitFollowCollection initializes the follower graph collection
+func InitFollowCollection() {
+	if config.DB != nil {
+		followCollection = config.GetCollection(config.DB, "follows")
+		followCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+	}
+}
+
+func getFollowCollection() (*mongo.Collection, error) {
+	if followCollection == nil {
+		InitFollowCollection()
+	}
+	if followCollection == nil {
+		return nil, fmt.Errorf("follow collection not initialized")
+	}
+	return followCollection, nil
+}
+
+// FollowUser makes followerID follow followeeID
+func FollowUser(followerID, followeeID string) error {
+	if followerID == followeeID {
+		return fmt.Errorf("cannot follow yourself")
+	}
+
+	follower, err := GetUserBySupabaseID(followerID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if _, err := GetUserBySupabaseID(followeeID); err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	collection, err := getFollowCollection()
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"follower_id": followerID, "followee_id": followeeID}
+	update := bson.M{"$setOnInsert": bson.M{
+		"follower_id": followerID,
+		"followee_id": followeeID,
+		"created_at":  time.Now(),
+	}}
+
+	result, err := collection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	// Only notify on a new follow, not when re-following is a no-op
+	if result.UpsertedCount > 0 {
+		name := follower.Username
+		if name == "" {
+			name = follower.Name
+		}
+		link := ""
+		if follower.Username != "" {
+			link = "/u/" + follower.Username
+		}
+		NotifyUsers([]string{followeeID}, model.Notification{
+			Type:    model.NotificationFollow,
+			Title:   "New follower",
+			Message: fmt.Sprintf("%s started following you", name),
+			Link:    link,
+			ActorID: followerID,
+		})
+	}
+	return nil
+}
+
+// UnfollowUser removes a follower relationship
+func UnfollowUser(followerID, followeeID string) error {
+	collection, err := getFollowCollection()
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteOne(context.Background(), bson.M{"follower_id": followerID, "followee_id": followeeID})
+	return err
+}
+
+// IsFollowing reports whether followerID follows followeeID
+func IsFollowing(followerID, followeeID string) bool {
+	collection, err := getFollowCollection()
+	if err != nil || followerID == "" {
+		return false
+	}
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{"follower_id": followerID, "followee_id": followeeID})
+	return err == nil && count > 0
+}
+
+// GetFollowers returns the Supabase IDs of users following userID
+func GetFollowers(userID string) ([]string, error) {
+	return listFollowEdges(bson.M{"followee_id": userID}, "follower_id")
+}
+
+// GetFollowing returns the Supabase IDs of users that userID follows
+func GetFollowing(userID string) ([]string, error) {
+	return listFollowEdges(bson.M{"follower_id": userID}, "followee_id")
+}
+
+// GetFollowersPage returns a page of the users following userID, most recent first
+func GetFollowersPage(userID string, page PageRequest) ([]model.User, string, error) {
+	return pageFollowEdges(bson.M{"followee_id": userID}, page, func(f model.Follow) string { return f.FollowerID })
+}
+
+// GetFollowingPage returns a page of the users that userID follows, most recent first
+func GetFollowingPage(userID string, page PageRequest) ([]model.User, string, error) {
+	return pageFollowEdges(bson.M{"follower_id": userID}, page, func(f model.Follow) string { return f.FolloweeID })
+}
+
+// pageFollowEdges loads a page of follows and the users at their other end, in follow order
+func pageFollowEdges(filter bson.M, page PageRequest, other func(model.Follow) string) ([]model.User, string, error) {
+	collection, err := getFollowCollection()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var follows []model.Follow
+	next, err := FindPageInto(context.Background(), collection, filter, page, nil, &follows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ids := make([]string, len(follows))
+	for i, follow := range follows {
+		ids[i] = other(follow)
+	}
+	users, err := GetUsersBySupabaseIDs(ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	byID := make(map[string]model.User, len(users))
+	for _, user := range users {
+		byID[user.SupabaseID] = user
+	}
+	ordered := make([]model.User, 0, len(users))
+	for _, id := range ids {
+		if user, ok := byID[id]; ok {
+			ordered = append(ordered, user)
+		}
+	}
+	return ordered, next, nil
+}
+
+func listFollowEdges(filter bson.M, field string) ([]string, error) {
+	collection, err := getFollowCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	ids := []string{}
+	for cur.Next(ctx) {
+		var follow model.Follow
+		if err := cur.Decode(&follow); err != nil {
+			continue
+		}
+		if field == "follower_id" {
+			ids = append(ids, follow.FollowerID)
+		} else {
+			ids = append(ids, follow.FolloweeID)
+		}
+	}
+	return ids, nil
+}
+
+// GetUsersBySupabaseIDs loads user documents for a list of Supabase IDs
+func GetUsersBySupabaseIDs(ids []string) ([]model.User, error) {
+	users := []model.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	ctx := context.Background()
+	cur, err := config.UserCollection.Find(ctx, bson.M{"supabase_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetFriendsEntriesForAnime returns the scores and statuses of followed users for an anime
+func GetFriendsEntriesForAnime(userID, animeName string) ([]model.FriendEntry, error) {
+	following, err := GetFollowing(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only include friends whose list privacy lets this user see it
+	visible := []string{}
+	for _, friendID := range following {
+		if CanViewList(friendID, userID) {
+			visible = append(visible, friendID)
+		}
+	}
+
+	entries := []model.FriendEntry{}
+	if len(visible) == 0 {
+		return entries, nil
+	}
+
+	ctx := context.Background()
+	filter := bson.M{
+		"user_id": bson.M{"$in": visible},
+		"name":    ExactText(animeName),
+	}
+	cur, err := config.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	users, _ := GetUsersBySupabaseIDs(visible)
+	names := make(map[string]string)
+	for _, u := range users {
+		names[u.SupabaseID] = u.Name
+	}
+
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		entries = append(entries, model.FriendEntry{
+			UserID:   anime.UserID,
+			Name:     names[anime.UserID],
+			Score:    anime.Score,
+			Status:   anime.Status,
+			Progress: anime.Progress,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+	return entries, nil
+}
+
+// animeKey identifies the same title across different users' list copies
+func animeKey(anime model.Anime) string {
+	if anime.AniListID > 0 {
+		return fmt.Sprintf("anilist:%d", anime.AniListID)
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(anime.Name))
+}
+
+// GetUserListPage returns a page of the entries on a user's list
+func GetUserListPage(userID string, page PageRequest) ([]model.Anime, string, error) {
+	animes := []model.Anime{}
+	next, err := FindPageInto(context.Background(), config.Collection, bson.M{"user_id": userID}, page, searchProjection, &animes)
+	if err != nil {
+		return nil, "", err
+	}
+	return animes, next, nil
+}
+
+// GetUserList returns every entry on a user's list
+func GetUserList(userID string) ([]model.Anime, error) {
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return nil, err
+	}
+	return animes, nil
+}
+
+// CompareUsers computes taste compatibility between two users' lists
+func CompareUsers(userA, userB string) (*model.Compatibility, error) {
+	listA, err := GetUserList(userA)
+	if err != nil {
+		return nil, err
+	}
+	listB, err := GetUserList(userB)
+	if err != nil {
+		return nil, err
+	}
+	return compareLists(userA, userB, listA, listB), nil
+}
+
+// compareLists blends how alike two users score the titles they share with how many of their
+// completed titles they share
+func compareLists(userA, userB string, listA, listB []model.Anime) *model.Compatibility {
+	entriesB := make(map[string]model.Anime)
+	for _, anime := range listB {
+		entriesB[animeKey(anime)] = anime
+	}
+
+	result := &model.Compatibility{
+		UserA:     userA,
+		UserB:     userB,
+		Shared:    []model.SharedTitle{},
+		Divergent: []model.SharedTitle{},
+	}
+
+	var scoresA, scoresB []float64
+	completedA, completedB := 0, 0
+	for _, anime := range listB {
+		if anime.Status == model.Completed {
+			completedB++
+		}
+	}
+
+	for _, a := range listA {
+		if a.Status == model.Completed {
+			completedA++
+		}
+
+		b, ok := entriesB[animeKey(a)]
+		if !ok {
+			continue
+		}
+
+		shared := model.SharedTitle{
+			Name:    a.Name,
+			ScoreA:  a.Score,
+			ScoreB:  b.Score,
+			StatusA: a.Status,
+			StatusB: b.Status,
+		}
+
+		if a.Score > 0 && b.Score > 0 {
+			scoresA = append(scoresA, a.Score)
+			scoresB = append(scoresB, b.Score)
+			shared.Difference = math.Abs(a.Score - b.Score)
+			if shared.Difference >= DIVERGENCE_THRESHOLD {
+				result.Divergent = append(result.Divergent, shared)
+			}
+		}
+
+		if a.Status == model.Completed && b.Status == model.Completed {
+			result.SharedComplete++
+		}
+
+		result.Shared = append(result.Shared, shared)
+	}
+
+	result.SharedScored = len(scoresA)
+	result.Correlation = pearsonCorrelation(scoresA, scoresB)
+
+	// Overlap of completed titles (Jaccard index)
+	overlap := 0.0
+	if union := completedA + completedB - result.SharedComplete; union > 0 {
+		overlap = float64(result.SharedComplete) / float64(union)
+	}
+
+	// Score correlation dominates once there is enough shared data
+	percentage := overlap
+	if result.SharedScored >= 2 {
+		percentage = 0.7*((result.Correlation+1)/2) + 0.3*overlap
+	}
+	result.Percentage = math.Round(percentage*1000) / 10
+
+	sort.Slice(result.Shared, func(i, j int) bool {
+		return result.Shared[i].Difference < result.Shared[j].Difference
+	})
+	sort.Slice(result.Divergent, func(i, j int) bool {
+		return result.Divergent[i].Difference > result.Divergent[j].Difference
+	})
+
+	return result
+}
+
+// pearsonCorrelation returns the correlation coefficient of two equal-length samples
+func pearsonCorrelation(xs, ys []float64) float64 {
+	n := len(xs)
+	if n < 2 || n != len(ys) {
+		return 0
+	}
+
+	var sumX, sumY float64
+	for i := 0; i < n; i++ {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var cov, varX, varY, absDiff float64
+	for i := 0; i < n; i++ {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+		absDiff += math.Abs(xs[i] - ys[i])
+	}
+
+	// Constant scores have no variance; fall back to how close the scores are
+	if varX == 0 || varY == 0 {
+		return 1 - 2*(absDiff/float64(n))/9
+	}
+
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	model "animeverse/models"
+)
+
+func listEntry(name string, score float64, status model.WatchStatus) model.Anime {
+	return model.Anime{Name: name, Score: score, Status: status}
+}
+
+func TestPearsonCorrelation(t *testing.T) {
+	tests := []struct {
+		xs, ys []float64
+		want   float64
+	}{
+		{[]float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{[]float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{[]float64{1, 2, 3}, []float64{3, 2, 1}, -1},
+		{[]float64{1, 2, 3, 4}, []float64{2, 1, 4, 3}, 0.6},
+		{[]float64{7}, []float64{7}, 0},          // Too few to correlate
+		{[]float64{1, 2}, []float64{1}, 0},       // Unequal samples
+		{[]float64{8, 8}, []float64{8, 8}, 1},    // No variance: the same scores agree fully
+		{[]float64{10, 10}, []float64{1, 1}, -1}, // and scores at opposite ends not at all
+		{[]float64{8, 8}, []float64{6, 7}, 1 - 2*1.5/9},
+	}
+
+	for _, tt := range tests {
+		if got := pearsonCorrelation(tt.xs, tt.ys); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("pearsonCorrelation(%v, %v) = %v, want %v", tt.xs, tt.ys, got, tt.want)
+		}
+	}
+}
+
+func TestCompareListsBlend(t *testing.T) {
+	done := model.Completed
+	tests := []struct {
+		name         string
+		listA, listB []model.Anime
+		percentage   float64
+		scored       int
+		completed    int
+	}{
+		{
+			"same scores, same titles",
+			[]model.Anime{listEntry("A", 9, done), listEntry("B", 5, done), listEntry("C", 1, done)},
+			[]model.Anime{listEntry("A", 9, done), listEntry("B", 5, done), listEntry("C", 1, done)},
+			100, 3, 3,
+		},
+		{
+			// Correlation counts for 70%, so opposite tastes keep only the overlap's 30%
+			"opposite scores, same titles",
+			[]model.Anime{listEntry("A", 9, done), listEntry("B", 5, done), listEntry("C", 1, done)},
+			[]model.Anime{listEntry("A", 1, done), listEntry("B", 5, done), listEntry("C", 9, done)},
+			30, 3, 3,
+		},
+		{
+			"same scores, half the completed titles",
+			[]model.Anime{listEntry("A", 9, done), listEntry("B", 5, done), listEntry("C", 0, done)},
+			[]model.Anime{listEntry("A", 9, done), listEntry("B", 5, done), listEntry("D", 0, done)},
+			85, 2, 2, // 0.7 + 0.3 * 2/4
+		},
+		{
+			// One shared score is too little to correlate, so only the overlap counts
+			"one shared score",
+			[]model.Anime{listEntry("A", 8, done), listEntry("B", 0, done)},
+			[]model.Anime{listEntry("A", 9, done), listEntry("C", 0, done)},
+			33.3, 1, 1,
+		},
+		{
+			"shared titles not completed",
+			[]model.Anime{listEntry("A", 0, model.Watching), listEntry("B", 0, done)},
+			[]model.Anime{listEntry("A", 0, model.PlanToWatch), listEntry("C", 0, done)},
+			0, 0, 0,
+		},
+		{
+			"nothing shared",
+			[]model.Anime{listEntry("A", 9, done)},
+			[]model.Anime{listEntry("B", 9, done)},
+			0, 0, 0,
+		},
+		{"empty lists", nil, nil, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		result := compareLists("a", "b", tt.listA, tt.listB)
+		if result.Percentage != tt.percentage {
+			t.Errorf("%s: percentage = %v, want %v", tt.name, result.Percentage, tt.percentage)
+		}
+		if result.SharedScored != tt.scored || result.SharedComplete != tt.completed {
+			t.Errorf("%s: shared scored %d and completed %d, want %d and %d",
+				tt.name, result.SharedScored, result.SharedComplete, tt.scored, tt.completed)
+		}
+		if result.Shared == nil || result.Divergent == nil {
+			t.Errorf("%s: shared or divergent titles are nil", tt.name)
+		}
+	}
+}
+
+func TestCompareListsMatchesTitles(t *testing.T) {
+	listA := []model.Anime{
+		{Name: "Naruto", AniListID: 20, Score: 10},
+		{Name: "Bleach", Score: 7},
+		{Name: "Monster", Score: 9},
+		{Name: "Mushishi", Score: 8},
+	}
+	listB := []model.Anime{
+		{Name: "NARUTO (TV)", AniListID: 20, Score: 2}, // Same AniList entry under another name
+		{Name: " bleach ", Score: 6},
+		{Name: "Monster", AniListID: 19, Score: 4}, // Keyed by AniList ID, which the other copy lacks
+		{Name: "Mushishi", Score: 5},
+	}
+
+	result := compareLists("a", "b", listA, listB)
+	var names []string
+	for _, shared := range result.Shared {
+		names = append(names, shared.Name)
+	}
+	if len(names) != 3 || names[0] != "Bleach" || names[1] != "Mushishi" || names[2] != "Naruto" {
+		t.Errorf("shared titles = %q, want Bleach, Mushishi and Naruto closest first", names)
+	}
+	if len(result.Divergent) != 2 || result.Divergent[0].Name != "Naruto" || result.Divergent[1].Name != "Mushishi" {
+		t.Errorf("divergent titles = %+v, want Naruto then Mushishi", result.Divergent)
+	}
+}
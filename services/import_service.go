@@ -43,7 +43,9 @@ func ImportTrendingAnime() (int, error) {
 		return 0, err
 	}
 
-	return importAnimeList(jikanResp.Data)
+	count, err := importAnimeList(jikanResp.Data)
+	EmitImportFinished("trending", count, err)
+	return count, err
 }
 
 func ImportSeasonalAnime(year, season string) (int, error) {
@@ -59,7 +61,9 @@ func ImportSeasonalAnime(year, season string) (int, error) {
 		return 0, err
 	}
 
-	return importAnimeList(jikanResp.Data)
+	count, err := importAnimeList(jikanResp.Data)
+	EmitImportFinished("seasonal", count, err)
+	return count, err
 }
 
 func importAnimeList(jikanAnimes []JikanAnime) (int, error) {
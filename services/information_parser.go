@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	broadcastPattern = regexp.MustCompile(`(?i)^\s*(mon|tues|wednes|thurs|fri|satur|sun)days?\b(?:\s+at\s+(\d{1,2}):(\d{2}))?`)
+	numericDate      = regexp.MustCompile(`^(\d{4})(?:-(\d{1,2}))?(?:-(\d{1,2}))?$`)
+	durationHours    = regexp.MustCompile(`(?i)(\d+)\s*(?:hr|hour)`)
+	durationMinutes  = regexp.MustCompile(`(?i)(\d+)\s*min`)
+	durationSeconds  = regexp.MustCompile(`(?i)(\d+)\s*sec`)
+	premieredPattern = regexp.MustCompile(`(?i)^\s*(winter|spring|summer|fall|autumn)\s+(\d{4})\s*$`)
+
+	// Written dates as they appear in MyAnimeList-style "Apr 7, 2013 to Sep 29, 2013"
+	textDateLayouts = []string{"Jan 2, 2006", "January 2, 2006", "Jan 2006", "January 2006"}
+)
+
+func init() {
+	// Writers that change the free-text fields without parsing them are caught here
+	OnCatalogChange(func(event CatalogEvent) {
+		if event.Type == CatalogAnimeRemoved {
+			return
+		}
+		info := event.Anime.Information
+		if info.Aired == "" && info.Broadcast == "" && info.Duration == "" && info.Premiered == "" {
+			return
+		}
+		if !ParseAnimeInformation(&info) {
+			return
+		}
+		config.Collection.UpdateOne(context.Background(), PublicCatalogFilter(bson.M{"_id": event.Anime.ID}),
+			bson.M{"$set": bson.M{"information": info}})
+	})
+}
+
+// ParseAnimeInformation fills the structured fields of info from its free-text Aired,
+// Broadcast, Duration and Premiered values. It reports whether anything changed.
+func ParseAnimeInformation(info *model.AnimeInformation) bool {
+	before := *info
+
+	info.StartDate, info.EndDate = parseAired(info.Aired)
+	info.BroadcastDay, info.BroadcastTime = parseBroadcast(info.Broadcast)
+	info.DurationMinutes = parseDuration(info.Duration)
+	info.PremieredSeason, info.PremieredYear = parsePremiered(info.Premiered)
+
+	changed := before.ParsedAt == nil ||
+		!sameDate(before.StartDate, info.StartDate) ||
+		!sameDate(before.EndDate, info.EndDate) ||
+		before.BroadcastDay != info.BroadcastDay ||
+		before.BroadcastTime != info.BroadcastTime ||
+		before.DurationMinutes != info.DurationMinutes ||
+		before.PremieredSeason != info.PremieredSeason ||
+		before.PremieredYear != info.PremieredYear
+	if changed {
+		now := time.Now()
+		info.ParsedAt = &now
+	}
+	return changed
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// parseAired reads "2013-04-07 to 2013-09-29", "Apr 7, 2013 to ?" or a single date.
+// A single date is a one-off release such as a movie, so it both starts and ends then.
+func parseAired(aired string) (*time.Time, *time.Time) {
+	aired = strings.TrimSpace(aired)
+	if aired == "" || strings.EqualFold(aired, "unknown") || strings.EqualFold(aired, "not available") {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(aired, " to ", 2)
+	start := parseLooseDate(parts[0])
+	if len(parts) == 1 {
+		return start, start
+	}
+	return start, parseLooseDate(parts[1])
+}
+
+// parseLooseDate parses a date that may lack its month or day, which then default to the first
+func parseLooseDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "?" {
+		return nil
+	}
+
+	if m := numericDate.FindStringSubmatch(value); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if year == 0 {
+			return nil
+		}
+		// formatAirDate writes unknown parts as zero
+		if month < 1 || month > 12 {
+			month, day = 1, 1
+		}
+		if day < 1 || day > 31 {
+			day = 1
+		}
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	for _, layout := range textDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+// parseBroadcast reads "Sundays at 17:00 (JST)" into a weekday and JST time.
+// Broadcast times are always given in JST, and the time is left empty when unknown.
+func parseBroadcast(broadcast string) (string, string) {
+	m := broadcastPattern.FindStringSubmatch(broadcast)
+	if m == nil {
+		return "", ""
+	}
+
+	day := strings.ToUpper(m[1][:1]) + strings.ToLower(m[1][1:]) + "day"
+	if m[2] == "" {
+		return day, ""
+	}
+
+	hour, _ := strconv.Atoi(m[2])
+	minute, _ := strconv.Atoi(m[3])
+	if hour > 23 || minute > 59 {
+		return day, ""
+	}
+	return day, fmt.Sprintf("%02d:%02d", hour, minute)
+}
+
+// parseDuration reads "24 min per ep", "1 hr 30 min" or "24 min" into minutes
+func parseDuration(duration string) int {
+	minutes := 0
+	if m := durationHours.FindStringSubmatch(duration); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes += hours * 60
+	}
+	if m := durationMinutes.FindStringSubmatch(duration); m != nil {
+		mins, _ := strconv.Atoi(m[1])
+		minutes += mins
+	}
+	if minutes == 0 {
+		// Very short clips are listed in seconds; count them as a minute
+		if m := durationSeconds.FindStringSubmatch(duration); m != nil {
+			if secs, _ := strconv.Atoi(m[1]); secs > 0 {
+				minutes = 1
+			}
+		}
+	}
+	return minutes
+}
+
+// parsePremiered reads "Spring 2013" or AniList's "SPRING 2013"
+func parsePremiered(premiered string) (model.Season, int) {
+	m := premieredPattern.FindStringSubmatch(premiered)
+	if m == nil {
+		return "", 0
+	}
+
+	year, _ := strconv.Atoi(m[2])
+	switch strings.ToLower(m[1]) {
+	case "winter":
+		return model.Winter, year
+	case "spring":
+		return model.Spring, year
+	case "summer":
+		return model.Summer, year
+	default:
+		return model.Fall, year
+	}
+}
+
+// BackfillAnimeInformation parses a batch of anime, catalog and list copies alike, that have
+// never been parsed. It returns how many were converted and whether more remain.
+func BackfillAnimeInformation() (int, bool, error) {
+	if config.Collection == nil {
+		return 0, false, fmt.Errorf("database not initialized")
+	}
+
+	filter := bson.M{
+		"information.parsed_at": bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"information.aired": bson.M{"$exists": true}},
+			{"information.broadcast": bson.M{"$exists": true}},
+			{"information.duration": bson.M{"$exists": true}},
+			{"information.premiered": bson.M{"$exists": true}},
+		},
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"information": 1}).
+		SetLimit(BACKFILL_BATCH)
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, false, err
+	}
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return 0, false, err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(animes))
+	for _, anime := range animes {
+		info := anime.Information
+		ParseAnimeInformation(&info)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": anime.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"information": info}}))
+	}
+	if len(writes) > 0 {
+		if _, err := config.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return len(animes), len(animes) == BACKFILL_BATCH, nil
+}
+
+// RunInformationBackfill converts every unparsed anime, batch by batch
+func RunInformationBackfill() (int, error) {
+	return runBackfillStep(informationBackfill)
+}
+
+// InformationFilters narrows anime by their parsed broadcast, airing and duration data
+type InformationFilters struct {
+	AiredFrom     string // YYYY, YYYY-MM or YYYY-MM-DD, start date on or after
+	AiredTo       string // YYYY, YYYY-MM or YYYY-MM-DD, start date within or before
+	AiringOn      string // YYYY-MM-DD, airing on that date
+	BroadcastDay  string // Weekday name
+	BroadcastFrom string // HH:MM JST, inclusive
+	BroadcastTo   string // HH:MM JST, exclusive
+	MinDuration   string // Minutes per episode
+	MaxDuration   string
+}
+
+// applyInformationFilters adds the parsed-field conditions to a Mongo filter
+func applyInformationFilters(filter bson.M, f InformationFilters) {
+	if date := parseLooseDate(f.AiredFrom); date != nil {
+		addRange(filter, "information.start_date", "$gte", *date)
+	}
+	if date := parseLooseDate(f.AiredTo); date != nil {
+		addRange(filter, "information.start_date", "$lt", periodEnd(f.AiredTo, *date))
+	}
+	if date := parseLooseDate(f.AiringOn); date != nil {
+		filter["$and"] = append(andClauses(filter),
+			bson.M{"information.start_date": bson.M{"$lte": *date}},
+			bson.M{"$or": []bson.M{
+				{"information.end_date": bson.M{"$gte": *date}},
+				{"information.end_date": bson.M{"$exists": false}},
+			}},
+		)
+	}
+
+	if f.BroadcastDay != "" {
+		if day, _ := parseBroadcast(f.BroadcastDay); day != "" {
+			filter["information.broadcast_day"] = day
+		}
+	}
+	if _, from := parseBroadcast("Mondays at " + f.BroadcastFrom); from != "" {
+		addRange(filter, "information.broadcast_time", "$gte", from)
+	}
+	if _, to := parseBroadcast("Mondays at " + f.BroadcastTo); to != "" {
+		addRange(filter, "information.broadcast_time", "$lt", to)
+	}
+
+	if minutes, err := strconv.Atoi(f.MinDuration); err == nil && minutes > 0 {
+		addRange(filter, "information.duration_minutes", "$gte", minutes)
+	}
+	if minutes, err := strconv.Atoi(f.MaxDuration); err == nil && minutes > 0 {
+		addRange(filter, "information.duration_minutes", "$lte", minutes)
+	}
+}
+
+// periodEnd returns the end of the period a loose date names, so "2020" covers the whole year
+func periodEnd(value string, start time.Time) time.Time {
+	m := numericDate.FindStringSubmatch(strings.TrimSpace(value))
+	switch {
+	case m != nil && m[2] == "":
+		return start.AddDate(1, 0, 0)
+	case m != nil && m[3] == "":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// addRange merges a comparison into any existing conditions on a field
+func addRange(filter bson.M, field, op string, value interface{}) {
+	conditions, ok := filter[field].(bson.M)
+	if !ok {
+		conditions = bson.M{}
+		filter[field] = conditions
+	}
+	conditions[op] = value
+}
+
+func andClauses(filter bson.M) []bson.M {
+	if clauses, ok := filter["$and"].([]bson.M); ok {
+		return clauses
+	}
+	return []bson.M{}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func utcDate(year int, month time.Month, day int) *time.Time {
+	d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &d
+}
+
+func TestParseAired(t *testing.T) {
+	tests := []struct {
+		aired      string
+		start, end *time.Time
+	}{
+		{"2013-04-07 to 2013-09-29", utcDate(2013, 4, 7), utcDate(2013, 9, 29)},
+		{"Apr 7, 2013 to Sep 29, 2013", utcDate(2013, 4, 7), utcDate(2013, 9, 29)},
+		{"April 7, 2013 to ?", utcDate(2013, 4, 7), nil},
+		{"Oct 2006 to Mar 2007", utcDate(2006, 10, 1), utcDate(2007, 3, 1)},
+		{"2016-08-26", utcDate(2016, 8, 26), utcDate(2016, 8, 26)},
+		{"2019", utcDate(2019, 1, 1), utcDate(2019, 1, 1)},
+		{"2019-04", utcDate(2019, 4, 1), utcDate(2019, 4, 1)},
+		{"2019-00-00 to 2020-03-00", utcDate(2019, 1, 1), utcDate(2020, 3, 1)},
+		{"? to 2020-01-01", nil, utcDate(2020, 1, 1)},
+		{"Unknown", nil, nil},
+		{"Not available", nil, nil},
+		{"sometime soon", nil, nil},
+		{"", nil, nil},
+	}
+
+	for _, tt := range tests {
+		start, end := parseAired(tt.aired)
+		if !sameDate(start, tt.start) || !sameDate(end, tt.end) {
+			t.Errorf("parseAired(%q) = %v, %v, want %v, %v", tt.aired, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestParseBroadcast(t *testing.T) {
+	tests := []struct {
+		broadcast, day, time string
+	}{
+		{"Sundays at 17:00 (JST)", "Sunday", "17:00"},
+		{"saturdays at 1:30 (JST)", "Saturday", "01:30"},
+		{"THURSDAYS at 23:59", "Thursday", "23:59"},
+		{"Wednesday at 25:00 (JST)", "Wednesday", ""},
+		{"Fridays at Unknown", "Friday", ""},
+		{"Mondays", "Monday", ""},
+		{"Unknown", "", ""},
+		{"Not scheduled once per week", "", ""},
+		{"Sundaysx at 17:00", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		day, at := parseBroadcast(tt.broadcast)
+		if day != tt.day || at != tt.time {
+			t.Errorf("parseBroadcast(%q) = %q, %q, want %q, %q", tt.broadcast, day, at, tt.day, tt.time)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		minutes  int
+	}{
+		{"24 min per ep", 24},
+		{"23 min. per ep.", 23},
+		{"1 hr 30 min", 90},
+		{"1 hr. 55 min.", 115},
+		{"2 hours", 120},
+		{"30 sec", 1},
+		{"0 sec", 0},
+		{"Unknown", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseDuration(tt.duration); got != tt.minutes {
+			t.Errorf("parseDuration(%q) = %d, want %d", tt.duration, got, tt.minutes)
+		}
+	}
+}
+
+func TestParsePremiered(t *testing.T) {
+	tests := []struct {
+		premiered string
+		season    model.Season
+		year      int
+	}{
+		{"Spring 2013", model.Spring, 2013},
+		{"SPRING 2013", model.Spring, 2013},
+		{" winter 2021 ", model.Winter, 2021},
+		{"Summer 1999", model.Summer, 1999},
+		{"Fall 2020", model.Fall, 2020},
+		{"autumn 2020", model.Fall, 2020},
+		{"Fall", "", 0},
+		{"2013 Spring", "", 0},
+		{"?", "", 0},
+		{"", "", 0},
+	}
+
+	for _, tt := range tests {
+		season, year := parsePremiered(tt.premiered)
+		if season != tt.season || year != tt.year {
+			t.Errorf("parsePremiered(%q) = %q, %d, want %q, %d", tt.premiered, season, year, tt.season, tt.year)
+		}
+	}
+}
+
+func TestParseAnimeInformationReportsChanges(t *testing.T) {
+	info := model.AnimeInformation{
+		Aired:     "Apr 7, 2013 to Sep 29, 2013",
+		Broadcast: "Sundays at 17:00 (JST)",
+		Duration:  "24 min per ep",
+		Premiered: "Spring 2013",
+	}
+	if !ParseAnimeInformation(&info) {
+		t.Fatal("the first parse reported no change")
+	}
+	if info.ParsedAt == nil || info.BroadcastDay != "Sunday" || info.DurationMinutes != 24 || info.PremieredYear != 2013 {
+		t.Errorf("parsed information = %+v", info)
+	}
+	if ParseAnimeInformation(&info) {
+		t.Error("parsing again reported a change")
+	}
+
+	info.Duration = "1 hr"
+	if !ParseAnimeInformation(&info) || info.DurationMinutes != 60 {
+		t.Errorf("a new duration was not picked up: %d", info.DurationMinutes)
+	}
+}
+
+func TestApplyInformationFilters(t *testing.T) {
+	filter := bson.M{}
+	applyInformationFilters(filter, InformationFilters{
+		AiredFrom:     "2019",
+		AiredTo:       "2020-03",
+		BroadcastDay:  "sunday",
+		BroadcastFrom: "18:00",
+		BroadcastTo:   "not a time",
+		MinDuration:   "20",
+		MaxDuration:   "-5",
+	})
+
+	want := bson.M{
+		"information.start_date":       bson.M{"$gte": *utcDate(2019, 1, 1), "$lt": *utcDate(2020, 4, 1)},
+		"information.broadcast_day":    "Sunday",
+		"information.broadcast_time":   bson.M{"$gte": "18:00"},
+		"information.duration_minutes": bson.M{"$gte": 20},
+	}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("filter = %v, want %v", filter, want)
+	}
+}
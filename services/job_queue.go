@@ -8,11 +8,14 @@ import (
 	"time"
 
 	"animeverse/cache"
+	model "animeverse/models"
+	"github.com/redis/go-redis/v9"
 )
 
 const (
 	JOB_QUEUE_KEY = "job_queue"
 	JOB_PROCESSING_KEY = "job_processing"
+	MAX_RETRY_DELAY = time.Hour
 )
 
 type JobType string
@@ -21,6 +24,9 @@ const (
 	FetchAnimeImages JobType = "fetch_anime_images"
 	UpdateAnimeData  JobType = "update_anime_data"
 	SendNotification JobType = "send_notification"
+	SendEmail        JobType = "send_email"
+	DeliverWebhook   JobType = "deliver_webhook"
+	ParseInformation JobType = "parse_information"
 )
 
 type Job struct {
@@ -30,6 +36,7 @@ type Job struct {
 	CreatedAt time.Time              `json:"created_at"`
 	Attempts  int                    `json:"attempts"`
 	MaxRetries int                   `json:"max_retries"`
+	ExponentialBackoff bool          `json:"exponential_backoff,omitempty"` // Double the delay after each failed attempt
 }
 
 type JobQueue struct {
@@ -43,15 +50,30 @@ func NewJobQueue() *JobQueue {
 }
 
 func (jq *JobQueue) Enqueue(jobType JobType, payload map[string]interface{}) error {
-	job := Job{
+	return jq.push(Job{
 		ID:         fmt.Sprintf("%s_%d", jobType, time.Now().UnixNano()),
 		Type:       jobType,
 		Payload:    payload,
 		CreatedAt:  time.Now(),
 		Attempts:   0,
 		MaxRetries: 3,
-	}
-	
+	})
+}
+
+// EnqueueWithBackoff queues a job that is retried up to maxRetries times with exponentially growing delays
+func (jq *JobQueue) EnqueueWithBackoff(jobType JobType, payload map[string]interface{}, maxRetries int) error {
+	return jq.push(Job{
+		ID:                 fmt.Sprintf("%s_%d", jobType, time.Now().UnixNano()),
+		Type:               jobType,
+		Payload:            payload,
+		CreatedAt:          time.Now(),
+		Attempts:           0,
+		MaxRetries:         maxRetries,
+		ExponentialBackoff: true,
+	})
+}
+
+func (jq *JobQueue) push(job Job) error {
 	jobData, err := json.Marshal(job)
 	if err != nil {
 		return err
@@ -102,6 +124,12 @@ func (jq *JobQueue) RetryJob(job *Job) error {
 	
 	// Add delay based on attempt count
 	delay := time.Duration(job.Attempts) * 30 * time.Second
+	if job.ExponentialBackoff {
+		delay = 30 * time.Second << uint(job.Attempts-1)
+		if delay > MAX_RETRY_DELAY {
+			delay = MAX_RETRY_DELAY
+		}
+	}
 	
 	go func() {
 		time.Sleep(delay)
@@ -118,6 +146,10 @@ func (jq *JobQueue) ProcessJobs() {
 	for {
 		job, err := jq.Dequeue()
 		if err != nil {
+			// Back off when Redis is unreachable instead of spinning
+			if err != redis.Nil {
+				time.Sleep(5 * time.Second)
+			}
 			continue
 		}
 		
@@ -141,6 +173,12 @@ func (jq *JobQueue) processJob(job *Job) error {
 		return jq.processUpdateAnimeData(job)
 	case SendNotification:
 		return jq.processSendNotification(job)
+	case SendEmail:
+		return jq.processSendEmail(job)
+	case DeliverWebhook:
+		return jq.processDeliverWebhook(job)
+	case ParseInformation:
+		return jq.processParseInformation(job)
 	default:
 		return fmt.Errorf("unknown job type: %s", job.Type)
 	}
@@ -173,12 +211,44 @@ func (jq *JobQueue) processUpdateAnimeData(job *Job) error {
 }
 
 func (jq *JobQueue) processSendNotification(job *Job) error {
-	message, ok := job.Payload["message"].(string)
+	userIDs, notification, err := decodeNotificationJob(job.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid notification payload: %v", err)
+	}
+	
+	return DeliverNotification(userIDs, notification)
+}
+
+func (jq *JobQueue) processSendEmail(job *Job) error {
+	email, err := decodeEmailJob(job.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid email payload: %v", err)
+	}
+	
+	return SendEmailNow(email)
+}
+
+func (jq *JobQueue) processDeliverWebhook(job *Job) error {
+	deliveryID, ok := job.Payload["delivery_id"].(string)
 	if !ok {
-		return fmt.Errorf("invalid message in payload")
+		return fmt.Errorf("invalid delivery_id in payload")
+	}
+	
+	// The last attempt settles the delivery as failed instead of asking for a retry
+	final := job.Attempts+1 >= job.MaxRetries
+	return AttemptWebhookDelivery(deliveryID, final)
+}
+
+func (jq *JobQueue) processParseInformation(job *Job) error {
+	_, more, err := BackfillAnimeInformation()
+	if err != nil {
+		return err
 	}
 	
-	log.Printf("Notification: %s", message)
+	// Work through the catalog one batch per job so other jobs aren't starved
+	if more {
+		return EnqueueInformationBackfill()
+	}
 	return nil
 }
 
@@ -232,8 +302,29 @@ func EnqueueDataUpdate(animeName string) error {
 	})
 }
 
-func EnqueueNotification(message string) error {
+func EnqueueNotification(userIDs []string, notification model.Notification) error {
+	if cache.RedisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
 	return GlobalJobQueue.Enqueue(SendNotification, map[string]interface{}{
-		"message": message,
+		"user_ids":     userIDs,
+		"notification": notification,
+		"dedup_key":    notification.DedupKey,
 	})
+}
+
+func EnqueueWebhookDelivery(deliveryID string) error {
+	if cache.RedisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	return GlobalJobQueue.EnqueueWithBackoff(DeliverWebhook, map[string]interface{}{
+		"delivery_id": deliveryID,
+	}, WEBHOOK_MAX_ATTEMPTS)
+}
+
+func EnqueueInformationBackfill() error {
+	if cache.RedisClient == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	return GlobalJobQueue.Enqueue(ParseInformation, map[string]interface{}{})
 }
\ No newline at end of file
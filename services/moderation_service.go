@@ -0,0 +1,659 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	REPORT_STATUS_OPEN      = "open"
+	REPORT_STATUS_RESOLVED  = "resolved"
+	REPORT_STATUS_DISMISSED = "dismissed"
+
+	REPORT_SOURCE_USER   = "user"
+	REPORT_SOURCE_FILTER = "filter"
+
+	WORD_FILTER_SETTING     = "word_filter"
+	MODERATION_STATE_TTL    = 30 * time.Second
+	MODERATION_STATE_SIZE   = 10000 // Users whose moderation state is cached at once
+	REPORT_DETAILS_MAX_SIZE = 1000
+)
+
+// ReportReasons lists the reasons users can pick when reporting content
+var ReportReasons = []string{"spam", "harassment", "spoiler", "inappropriate", "other"}
+
+// ModerationActions lists the actions a moderator can take on reported content
+var ModerationActions = []string{"hide", "unhide", "delete", "warn", "ban", "unban", "mute", "unmute", "dismiss"}
+
+// permanentSanction is used as the expiry of bans and mutes without a duration
+var permanentSanction = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var reportCollection *mongo.Collection
+var moderationLogCollection *mongo.Collection
+var moderationSettingsCollection *mongo.Collection
+
+var (
+	wordFilterMutex   sync.RWMutex
+	wordFilterWords   []string
+	wordFilterPattern *regexp.Regexp
+)
+
+type moderationState struct {
+	banned    bool
+	muted     bool
+	fetchedAt time.Time
+}
+
+var (
+	moderationStateMutex sync.RWMutex
+	moderationStateCache = map[string]moderationState{}
+)
+
+// ModerationInput describes a moderation decision
+type ModerationInput struct {
+	TargetType    model.ReportTargetType `json:"target_type,omitempty"`
+	TargetID      string                 `json:"target_id,omitempty"`
+	Action        string                 `json:"action"`
+	DurationHours int                    `json:"duration_hours,omitempty"` // 0 means permanent for ban/mute
+	Note          string                 `json:"note,omitempty"`
+}
+
+// ModerationQueueItem is a report together with a preview of the reported content
+type ModerationQueueItem struct {
+	model.Report `bson:",inline"`
+	AuthorID     string `json:"author_id,omitempty"`
+	Preview      string `json:"preview,omitempty"`
+}
+
+// InitModerationCollections initializes the report, log and settings collections and loads the word filter
+func InitModerationCollections() {
+	if config.DB == nil {
+		return
+	}
+
+	ctx := context.Background()
+	reportCollection = config.GetCollection(config.DB, "reports")
+	reportCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "status", Value: 1}}},
+	})
+
+	moderationLogCollection = config.GetCollection(config.DB, "moderation_log")
+	moderationLogCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+
+	moderationSettingsCollection = config.GetCollection(config.DB, "moderation_settings")
+	loadWordFilter()
+}
+
+func getModerationCollections() (*mongo.Collection, *mongo.Collection, error) {
+	if reportCollection == nil || moderationLogCollection == nil {
+		InitModerationCollections()
+	}
+	if reportCollection == nil || moderationLogCollection == nil {
+		return nil, nil, fmt.Errorf("moderation collections not initialized")
+	}
+	return reportCollection, moderationLogCollection, nil
+}
+
+// loadWordFilter reads the word filter from the database, seeding it from MODERATION_WORD_FILTER on first run
+func loadWordFilter() {
+	var setting struct {
+		Words []string `bson:"words"`
+	}
+
+	err := moderationSettingsCollection.FindOne(context.Background(), bson.M{"_id": WORD_FILTER_SETTING}).Decode(&setting)
+	if err == mongo.ErrNoDocuments {
+		seed := os.Getenv("MODERATION_WORD_FILTER")
+		if seed == "" {
+			return
+		}
+		if err := SetWordFilter(strings.Split(seed, ",")); err != nil {
+			log.Printf("Failed to seed word filter: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load word filter: %v", err)
+		return
+	}
+
+	compileWordFilter(setting.Words)
+}
+
+// compileWordFilter builds a single case-insensitive whole-word pattern from the filter words
+func compileWordFilter(words []string) []string {
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		cleaned = append(cleaned, word)
+	}
+
+	var pattern *regexp.Regexp
+	if len(cleaned) > 0 {
+		quoted := make([]string, len(cleaned))
+		for i, word := range cleaned {
+			quoted[i] = regexp.QuoteMeta(word)
+		}
+		pattern = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	}
+
+	wordFilterMutex.Lock()
+	wordFilterWords = cleaned
+	wordFilterPattern = pattern
+	wordFilterMutex.Unlock()
+
+	return cleaned
+}
+
+// GetWordFilter returns the words that automatically flag content
+func GetWordFilter() []string {
+	wordFilterMutex.RLock()
+	defer wordFilterMutex.RUnlock()
+	return append([]string{}, wordFilterWords...)
+}
+
+// SetWordFilter replaces the words that automatically flag content
+func SetWordFilter(words []string) error {
+	if moderationSettingsCollection == nil {
+		return fmt.Errorf("moderation collections not initialized")
+	}
+
+	cleaned := compileWordFilter(words)
+	_, err := moderationSettingsCollection.UpdateOne(context.Background(),
+		bson.M{"_id": WORD_FILTER_SETTING},
+		bson.M{"$set": bson.M{"words": cleaned, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// matchWordFilter returns the filter words found in text
+func matchWordFilter(text string) []string {
+	wordFilterMutex.RLock()
+	pattern := wordFilterPattern
+	wordFilterMutex.RUnlock()
+
+	if pattern == nil {
+		return nil
+	}
+
+	matches := []string{}
+	seen := map[string]bool{}
+	for _, match := range pattern.FindAllString(text, -1) {
+		match = strings.ToLower(match)
+		if !seen[match] {
+			seen[match] = true
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// FlagContent files an automatic report when text matches the word filter
+func FlagContent(targetType model.ReportTargetType, targetID, text string) {
+	matches := matchWordFilter(text)
+	if len(matches) == 0 {
+		return
+	}
+
+	reports, _, err := getModerationCollections()
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	// Keep a single open filter report per target so edits don't pile up duplicates
+	_, err = reports.UpdateOne(ctx,
+		bson.M{"target_type": targetType, "target_id": targetID, "source": REPORT_SOURCE_FILTER, "status": REPORT_STATUS_OPEN},
+		bson.M{
+			"$set":         bson.M{"details": "matched: " + strings.Join(matches, ", ")},
+			"$setOnInsert": bson.M{"reason": "word_filter", "created_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Failed to flag %s %s: %v", targetType, targetID, err)
+	}
+}
+
+func isValidReportReason(reason string) bool {
+	for _, allowed := range ReportReasons {
+		if reason == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidModerationAction(action string) bool {
+	for _, allowed := range ModerationActions {
+		if action == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveReportTarget checks a report target exists and returns its canonical ID, author and a content preview
+func resolveReportTarget(targetType model.ReportTargetType, targetID string) (string, string, string, error) {
+	switch targetType {
+	case model.ReportComment:
+		comment, err := GetComment(targetID)
+		if err != nil {
+			return "", "", "", fmt.Errorf("comment not found")
+		}
+		return comment.ID.Hex(), comment.UserID, comment.Content, nil
+	case model.ReportReview:
+		review, err := GetReview(targetID)
+		if err != nil {
+			return "", "", "", fmt.Errorf("review not found")
+		}
+		return review.ID.Hex(), review.UserID, review.Body, nil
+	case model.ReportProfile, model.ReportList:
+		user, err := ResolveUser(targetID)
+		if err != nil {
+			return "", "", "", fmt.Errorf("user not found")
+		}
+		preview := user.Bio
+		if targetType == model.ReportList {
+			preview = fmt.Sprintf("%d anime on list", user.Stats.TotalAnimes)
+		}
+		return user.SupabaseID, user.SupabaseID, preview, nil
+	}
+	return "", "", "", fmt.Errorf("unsupported report target: %s", targetType)
+}
+
+// CreateReport files a user report against a comment, review, profile or list
+func CreateReport(reporterID string, targetType model.ReportTargetType, targetID, reason, details string) (*model.Report, error) {
+	reports, _, err := getModerationCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidReportReason(reason) {
+		return nil, fmt.Errorf("reason must be one of: %s", strings.Join(ReportReasons, ", "))
+	}
+	if len(details) > REPORT_DETAILS_MAX_SIZE {
+		return nil, fmt.Errorf("details must be at most %d characters", REPORT_DETAILS_MAX_SIZE)
+	}
+
+	canonicalID, authorID, _, err := resolveReportTarget(targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if authorID == reporterID {
+		return nil, fmt.Errorf("you cannot report your own content")
+	}
+
+	ctx := context.Background()
+	existing, err := reports.CountDocuments(ctx, bson.M{
+		"target_type": targetType,
+		"target_id":   canonicalID,
+		"reporter_id": reporterID,
+		"status":      REPORT_STATUS_OPEN,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("you have already reported this")
+	}
+
+	report := model.Report{
+		TargetType: targetType,
+		TargetID:   canonicalID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		Details:    strings.TrimSpace(details),
+		Source:     REPORT_SOURCE_USER,
+		Status:     REPORT_STATUS_OPEN,
+		CreatedAt:  time.Now(),
+	}
+
+	result, err := reports.InsertOne(ctx, report)
+	if err != nil {
+		return nil, err
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return &report, nil
+}
+
+// GetModerationQueue lists reports with the given status, oldest first
+func GetModerationQueue(status string, page PageRequest) ([]ModerationQueueItem, string, error) {
+	reports, _, err := getModerationCollections()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if status == "" {
+		status = REPORT_STATUS_OPEN
+	}
+
+	results := []model.Report{}
+	next, err := FindPageInto(context.Background(), reports, bson.M{"status": status}, page, nil, &results)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]ModerationQueueItem, len(results))
+	for i, report := range results {
+		items[i].Report = report
+		if _, authorID, preview, err := resolveReportTarget(report.TargetType, report.TargetID); err == nil {
+			items[i].AuthorID = authorID
+			items[i].Preview = preview
+		}
+	}
+	return items, next, nil
+}
+
+// GetReport returns a single report by ID
+func GetReport(reportID string) (*model.Report, error) {
+	reports, _, err := getModerationCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	var report model.Report
+	if err := reports.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ResolveReport applies a moderation action to the target of a report and closes it
+func ResolveReport(moderatorID, reportID string, input ModerationInput) (*model.ModerationLogEntry, error) {
+	report, err := GetReport(reportID)
+	if err != nil {
+		return nil, fmt.Errorf("report not found")
+	}
+	if report.Status != REPORT_STATUS_OPEN {
+		return nil, fmt.Errorf("report is already %s", report.Status)
+	}
+
+	input.TargetType = report.TargetType
+	input.TargetID = report.TargetID
+	return moderate(moderatorID, report.ID.Hex(), input)
+}
+
+// ModerateTarget applies a moderation action directly, without a report
+func ModerateTarget(moderatorID string, input ModerationInput) (*model.ModerationLogEntry, error) {
+	if input.Action == "dismiss" {
+		return nil, fmt.Errorf("dismiss only applies to reports")
+	}
+	return moderate(moderatorID, "", input)
+}
+
+func moderate(moderatorID, reportID string, input ModerationInput) (*model.ModerationLogEntry, error) {
+	reports, moderationLog, err := getModerationCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidModerationAction(input.Action) {
+		return nil, fmt.Errorf("action must be one of: %s", strings.Join(ModerationActions, ", "))
+	}
+	if input.DurationHours < 0 {
+		return nil, fmt.Errorf("duration cannot be negative")
+	}
+
+	targetID, authorID, _, err := resolveReportTarget(input.TargetType, input.TargetID)
+	if err != nil {
+		// Reports about content that has since disappeared can still be dismissed
+		if input.Action != "dismiss" {
+			return nil, err
+		}
+		targetID = input.TargetID
+	}
+
+	if err := applyModerationAction(input, targetID, authorID); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	entry := model.ModerationLogEntry{
+		ModeratorID: moderatorID,
+		Action:      input.Action,
+		TargetType:  input.TargetType,
+		TargetID:    targetID,
+		UserID:      authorID,
+		ReportID:    reportID,
+		Note:        input.Note,
+		CreatedAt:   time.Now(),
+	}
+	result, err := moderationLog.InsertOne(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	notifyModeratedUser(input, authorID)
+
+	// Close every open report about this target, not just the one acted on
+	status := REPORT_STATUS_RESOLVED
+	filter := bson.M{"target_type": input.TargetType, "target_id": targetID, "status": REPORT_STATUS_OPEN}
+	if input.Action == "dismiss" {
+		status = REPORT_STATUS_DISMISSED
+		reportObjID, _ := primitive.ObjectIDFromHex(reportID)
+		filter = bson.M{"_id": reportObjID}
+	}
+	now := time.Now()
+	reports.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"status":      status,
+		"resolved_by": moderatorID,
+		"resolved_at": now,
+	}})
+
+	return &entry, nil
+}
+
+// applyModerationAction changes the moderated content or its author's account
+func applyModerationAction(input ModerationInput, targetID, authorID string) error {
+	ctx := context.Background()
+	now := time.Now()
+
+	switch input.Action {
+	case "dismiss":
+		return nil
+	case "warn":
+		return updateUserModeration(authorID, bson.M{"$inc": bson.M{"moderation.warnings": 1}})
+	case "ban", "mute":
+		until := permanentSanction
+		if input.DurationHours > 0 {
+			until = now.Add(time.Duration(input.DurationHours) * time.Hour)
+		}
+		field := "moderation.banned_until"
+		if input.Action == "mute" {
+			field = "moderation.muted_until"
+		}
+		return updateUserModeration(authorID, bson.M{"$set": bson.M{field: until}})
+	case "unban":
+		return updateUserModeration(authorID, bson.M{"$unset": bson.M{"moderation.banned_until": ""}})
+	case "unmute":
+		return updateUserModeration(authorID, bson.M{"$unset": bson.M{"moderation.muted_until": ""}})
+	}
+
+	// hide, unhide and delete act on the content itself
+	hidden := input.Action == "hide"
+	switch input.TargetType {
+	case model.ReportComment:
+		comments, _, err := getCommentCollections()
+		if err != nil {
+			return err
+		}
+		objID, _ := primitive.ObjectIDFromHex(targetID)
+		set := bson.M{"hidden": hidden}
+		if input.Action == "delete" {
+			set = bson.M{"deleted": true, "content": "", "updated_at": now}
+		}
+		_, err = comments.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": set})
+		return err
+	case model.ReportReview:
+		reviews, votes, err := getReviewCollections()
+		if err != nil {
+			return err
+		}
+		objID, _ := primitive.ObjectIDFromHex(targetID)
+		if input.Action == "delete" {
+			if _, err := reviews.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+				return err
+			}
+			_, err = votes.DeleteMany(ctx, bson.M{"review_id": objID})
+			return err
+		}
+		_, err = reviews.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"hidden": hidden}})
+		return err
+	case model.ReportProfile:
+		if input.Action == "delete" {
+			return updateUserModeration(targetID, bson.M{"$set": bson.M{
+				"bio":       "",
+				"avatarUrl": "",
+				"favorites": []string{},
+			}})
+		}
+		return updateUserModeration(targetID, bson.M{"$set": bson.M{"moderation.profile_hidden": hidden}})
+	case model.ReportList:
+		if input.Action == "delete" {
+			return fmt.Errorf("lists cannot be deleted, hide them instead")
+		}
+		return updateUserModeration(targetID, bson.M{"$set": bson.M{"moderation.list_hidden": hidden}})
+	}
+	return fmt.Errorf("unsupported report target: %s", input.TargetType)
+}
+
+func updateUserModeration(supabaseID string, update bson.M) error {
+	result, err := config.UserCollection.UpdateOne(context.Background(), bson.M{"supabase_id": supabaseID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	moderationStateMutex.Lock()
+	delete(moderationStateCache, supabaseID)
+	moderationStateMutex.Unlock()
+	return nil
+}
+
+// GetModerationLog lists moderation decisions, newest first, optionally for one user
+func GetModerationLog(userID string, page PageRequest) ([]model.ModerationLogEntry, string, error) {
+	_, moderationLog, err := getModerationCollections()
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := bson.M{}
+	if userID != "" {
+		filter["user_id"] = userID
+	}
+
+	entries := []model.ModerationLogEntry{}
+	next, err := FindPageInto(context.Background(), moderationLog, filter, page, nil, &entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, next, nil
+}
+
+// GetModerationState reports whether a user is currently banned or muted, cached briefly per user
+func GetModerationState(supabaseID string) (bool, bool) {
+	if config.UserCollection == nil || supabaseID == "" {
+		return false, false
+	}
+
+	moderationStateMutex.RLock()
+	state, ok := moderationStateCache[supabaseID]
+	moderationStateMutex.RUnlock()
+
+	if !ok || time.Since(state.fetchedAt) > MODERATION_STATE_TTL {
+		var user model.User
+		opts := options.FindOne().SetProjection(bson.M{"moderation": 1})
+		err := config.UserCollection.FindOne(context.Background(), bson.M{"supabase_id": supabaseID}, opts).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			// Fail open so a database hiccup doesn't lock everyone out
+			log.Printf("Failed to load moderation state for %s: %v", supabaseID, err)
+			return false, false
+		}
+
+		now := time.Now()
+		state = moderationState{
+			banned:    user.Moderation.BannedUntil != nil && user.Moderation.BannedUntil.After(now),
+			muted:     user.Moderation.MutedUntil != nil && user.Moderation.MutedUntil.After(now),
+			fetchedAt: now,
+		}
+
+		moderationStateMutex.Lock()
+		storeModerationState(supabaseID, state)
+		moderationStateMutex.Unlock()
+	}
+
+	return state.banned, state.muted
+}
+
+// storeModerationState caches a user's moderation state. A full cache first drops its expired
+// states, and is emptied if every state is still fresh, so it never holds more than
+// MODERATION_STATE_SIZE users. The caller holds the write lock.
+func storeModerationState(supabaseID string, state moderationState) {
+	if _, ok := moderationStateCache[supabaseID]; !ok && len(moderationStateCache) >= MODERATION_STATE_SIZE {
+		for id, cached := range moderationStateCache {
+			if time.Since(cached.fetchedAt) > MODERATION_STATE_TTL {
+				delete(moderationStateCache, id)
+			}
+		}
+		if len(moderationStateCache) >= MODERATION_STATE_SIZE {
+			moderationStateCache = map[string]moderationState{}
+		}
+	}
+	moderationStateCache[supabaseID] = state
+}
+
+// notifyModeratedUser emails the author about warnings and account restrictions
+func notifyModeratedUser(input ModerationInput, userID string) {
+	duration := "until further notice"
+	if input.DurationHours > 0 {
+		duration = fmt.Sprintf("for %d hours", input.DurationHours)
+	}
+
+	reason := ""
+	if input.Note != "" {
+		reason = " Moderator note: " + input.Note
+	}
+
+	switch input.Action {
+	case "warn":
+		SendAccountNotice(userID, "You received a warning",
+			"A moderator reviewed content you posted and issued a warning. Please review the community guidelines."+reason)
+	case "mute":
+		SendAccountNotice(userID, "Your account has been muted",
+			fmt.Sprintf("You cannot post comments, reviews or profile changes %s.%s", duration, reason))
+	case "ban":
+		SendAccountNotice(userID, "Your account has been suspended",
+			fmt.Sprintf("Your account has been suspended %s.%s", duration, reason))
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NOTIFICATION_BATCH_SIZE caps the recipients handled by a single fan-out job
+const NOTIFICATION_BATCH_SIZE = 500
+
+var notificationCollection *mongo.Collection
+
+// NotificationPage represents one page of a user's inbox
+type NotificationPage struct {
+	Notifications []model.Notification `json:"notifications"`
+	UnreadCount   int64                `json:"unread_count"`
+}
+
+// InitNotificationCollection initializes the notifications collection
+func InitNotificationCollection() {
+	if config.DB == nil {
+		return
+	}
+
+	notificationCollection = config.GetCollection(config.DB, "notifications")
+	notificationCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "read", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "dedup_key", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"dedup_key": bson.M{"$type": "string"}}),
+		},
+	})
+}
+
+func getNotificationCollection() (*mongo.Collection, error) {
+	if notificationCollection == nil {
+		InitNotificationCollection()
+	}
+	if notificationCollection == nil {
+		return nil, fmt.Errorf("notification collection not initialized")
+	}
+	return notificationCollection, nil
+}
+
+// DeliverNotification writes a notification into each recipient's inbox.
+// Recipients that already received the same dedup key are skipped.
+func DeliverNotification(userIDs []string, notification model.Notification) error {
+	collection, err := getNotificationCollection()
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		n := notification
+		n.ID = primitive.NilObjectID
+		n.UserID = userID
+		n.Read = false
+		n.CreatedAt = now
+		if notification.DedupKey != "" {
+			n.DedupKey = notification.DedupKey + ":" + userID
+		}
+		docs = append(docs, n)
+	}
+
+	// Unordered so one duplicate doesn't stop delivery to everyone else
+	_, err = collection.InsertMany(context.Background(), docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// NotifyUsers fans a notification out to recipients through the job queue,
+// delivering inline if the queue is unavailable
+func NotifyUsers(userIDs []string, notification model.Notification) {
+	for start := 0; start < len(userIDs); start += NOTIFICATION_BATCH_SIZE {
+		end := start + NOTIFICATION_BATCH_SIZE
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		batch := userIDs[start:end]
+
+		if err := EnqueueNotification(batch, notification); err != nil {
+			log.Printf("Notification queue unavailable, delivering inline: %v", err)
+			if err := DeliverNotification(batch, notification); err != nil {
+				log.Printf("Failed to deliver %s notification: %v", notification.Type, err)
+			}
+		}
+	}
+}
+
+// decodeNotificationJob reads the recipients and notification out of a job payload
+func decodeNotificationJob(payload map[string]interface{}) ([]string, model.Notification, error) {
+	var job struct {
+		UserIDs      []string           `json:"user_ids"`
+		Notification model.Notification `json:"notification"`
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, job.Notification, err
+	}
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, job.Notification, err
+	}
+	// DedupKey is hidden from JSON responses, so it travels separately
+	if key, ok := payload["dedup_key"].(string); ok {
+		job.Notification.DedupKey = key
+	}
+	return job.UserIDs, job.Notification, nil
+}
+
+// GetNotifications returns a page of a user's notifications, newest first, and the cursor of
+// the next page
+func GetNotifications(userID string, unreadOnly bool, page PageRequest) (*NotificationPage, string, error) {
+	collection, err := getNotificationCollection()
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	ctx := context.Background()
+	result := &NotificationPage{Notifications: []model.Notification{}}
+	next, err := FindPageInto(ctx, collection, filter, page, nil, &result.Notifications)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result.UnreadCount, err = collection.CountDocuments(ctx, bson.M{"user_id": userID, "read": false})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return result, next, nil
+}
+
+// MarkNotificationRead marks one of a user's notifications as read
+func MarkNotificationRead(userID, notificationID string) error {
+	collection, err := getNotificationCollection()
+	if err != nil {
+		return err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(notificationID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(context.Background(),
+		bson.M{"_id": objID, "user_id": userID},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("notification not found")
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every notification of a user as read
+func MarkAllNotificationsRead(userID string) (int64, error) {
+	collection, err := getNotificationCollection()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.UpdateMany(context.Background(),
+		bson.M{"user_id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetListHolders returns the users who have an anime on their list with one of the given statuses
+func GetListHolders(anime model.Anime, statuses []model.WatchStatus) ([]string, error) {
+	match := []bson.M{{"name": ExactText(anime.Name)}}
+	if anime.AniListID > 0 {
+		match = append(match, bson.M{"anilist_id": anime.AniListID})
+	}
+
+	filter := bson.M{
+		"user_id": bson.M{"$exists": true},
+		"$or":     match,
+	}
+	if len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+
+	values, err := config.Collection.Distinct(context.Background(), "user_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok && id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+	return userIDs, nil
+}
+
+// NotifySequelAnnouncements notifies fans of an anime about sequels that weren't known before
+func NotifySequelAnnouncements(anime model.Anime, previous []model.RelatedAnime) {
+	// The first time relations are fetched there is nothing to compare against
+	if len(previous) == 0 {
+		return
+	}
+
+	known := map[string]bool{}
+	for _, related := range previous {
+		known[strings.ToLower(related.Name)] = true
+	}
+
+	for _, related := range anime.Related {
+		if related.RelationType != "SEQUEL" || related.Name == "" || known[strings.ToLower(related.Name)] {
+			continue
+		}
+
+		fans, err := GetListHolders(anime, []model.WatchStatus{model.Completed, model.Watching})
+		if err != nil {
+			log.Printf("Failed to find fans of %s: %v", anime.Name, err)
+			return
+		}
+
+		NotifyUsers(fans, model.Notification{
+			Type:     model.NotificationSequel,
+			Title:    "Sequel announced",
+			Message:  fmt.Sprintf("%s is getting a sequel: %s", anime.Name, related.Name),
+			AnimeID:  anime.ID.Hex(),
+			DedupKey: fmt.Sprintf("sequel:%s:%s", anime.ID.Hex(), strings.ToLower(related.Name)),
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Every list endpoint pages the same way. A request takes "limit", "sort" and "cursor"; the
+// response carries the cursor of the next page in a Link header and in X-Next-Cursor, and
+// has neither on the last page. Cursors are opaque and only valid with the sort that issued
+// them. Catalog and list pages continue after the last item they returned, so inserts and
+// deletes between requests never repeat or skip items; search relevance and external sources
+// continue at an offset. "page" and "offset" are still read when there is no cursor.
+
+const (
+	DEFAULT_PAGE_LIMIT = 24
+	MAX_PAGE_LIMIT     = 100
+
+	SORT_RELEVANCE = "relevance"
+)
+
+// pageSorts are the sort keys offered by list endpoints. Ties are broken by _id.
+var pageSorts = map[string]pageSort{
+	"score":      {Field: "score", Desc: true, AniList: "SCORE_DESC"},
+	"popularity": {Field: "statistics.members", Desc: true, AniList: "POPULARITY_DESC"},
+	"year":       {Field: "year", Desc: true, AniList: "START_DATE_DESC"},
+	"title":      {Field: "name_key", AniList: "TITLE_ROMAJI"},
+	"recent":     {Field: "created_at", Desc: true, AniList: "ID_DESC"},
+}
+
+// listOrders are the fixed orders of lists that are not of anime, such as reviews and follows.
+// Their endpoints take no sort parameter.
+var listOrders = map[string]pageSort{
+	"newest":  {Field: "created_at", Desc: true},
+	"oldest":  {Field: "created_at"},
+	"helpful": {Field: "wilson_score", Desc: true},
+}
+
+type pageSort struct {
+	Field   string
+	Desc    bool
+	AniList string // The AniList MediaSort with the same meaning
+}
+
+// PageRequest is one request for a page of a list
+type PageRequest struct {
+	Limit  int
+	Sort   string
+	Offset int // Items before this page, for offset paging
+	After  *PageCursor
+}
+
+// PageCursor is the decoded form of a cursor: the position after the last item of a page
+type PageCursor struct {
+	Sort   string             `bson:"s"`
+	Offset int                `bson:"o"`
+	Value  interface{}        `bson:"v,omitempty"` // Sort value of the last item, for keyset paging
+	ID     primitive.ObjectID `bson:"id,omitempty"`
+}
+
+// PageSortKeys lists the sort keys of list endpoints
+func PageSortKeys() []string {
+	keys := make([]string, 0, len(pageSorts))
+	for key := range pageSorts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParsePageRequest reads limit, sort and cursor from query parameters, holding pages to
+// maxLimit items. A cursor's sort is held to the same rules as the sort parameter.
+func ParsePageRequest(values url.Values, defaultSort string, defaultLimit, maxLimit int) (PageRequest, error) {
+	page := PageRequest{Limit: min(defaultLimit, maxLimit), Sort: defaultSort}
+	if err := parsePageLimit(values, &page, maxLimit); err != nil {
+		return page, err
+	}
+
+	if raw := strings.ToLower(values.Get("sort")); raw != "" {
+		if err := checkPageSort(raw, defaultSort); err != nil {
+			return page, err
+		}
+		page.Sort = raw
+	}
+
+	cursor, err := parsePagePosition(values, &page)
+	if err != nil || cursor == nil {
+		return page, err
+	}
+	if values.Get("sort") != "" && cursor.Sort != page.Sort {
+		return page, fmt.Errorf("cursor was issued for sort %q", cursor.Sort)
+	}
+	if err := checkPageSort(cursor.Sort, defaultSort); err != nil {
+		return page, fmt.Errorf("invalid cursor: %v", err)
+	}
+	page.Sort = cursor.Sort
+	return page, nil
+}
+
+// ParseListPage reads limit and cursor for a list in one of the fixed listOrders
+func ParseListPage(values url.Values, order string, defaultLimit int) (PageRequest, error) {
+	page := PageRequest{Limit: min(defaultLimit, MAX_PAGE_LIMIT), Sort: order}
+	if err := parsePageLimit(values, &page, MAX_PAGE_LIMIT); err != nil {
+		return page, err
+	}
+
+	cursor, err := parsePagePosition(values, &page)
+	if err != nil || cursor == nil {
+		return page, err
+	}
+	if cursor.Sort != order {
+		return page, fmt.Errorf("cursor was issued for sort %q", cursor.Sort)
+	}
+	return page, nil
+}
+
+// checkPageSort reports whether an anime list offers a sort. The endpoint's default is always
+// offered, which is how search offers relevance and AniList lists their own order. Sorting by
+// title waits until every anime has its title keys.
+func checkPageSort(key, defaultSort string) error {
+	if key == "title" && !TitleKeysReady() {
+		return fmt.Errorf("sorting by title is unavailable while titles are being indexed")
+	}
+	if _, ok := pageSorts[key]; ok || key == defaultSort {
+		return nil
+	}
+	return fmt.Errorf("sort must be one of %s", strings.Join(PageSortKeys(), ", "))
+}
+
+func parsePageLimit(values url.Values, page *PageRequest, maxLimit int) error {
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return fmt.Errorf("limit must be a positive number")
+		}
+		page.Limit = min(limit, maxLimit)
+	}
+	return nil
+}
+
+// parsePagePosition reads where the page starts from the cursor, or from the page or offset of
+// older clients. It returns the cursor, if there is one, for the caller to check its sort.
+func parsePagePosition(values url.Values, page *PageRequest) (*PageCursor, error) {
+	if raw := values.Get("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		page.Offset = cursor.Offset
+		if cursor.Value != nil || !cursor.ID.IsZero() {
+			page.After = cursor
+		}
+		return cursor, nil
+	}
+
+	if n, err := strconv.Atoi(values.Get("offset")); err == nil && n > 0 {
+		page.Offset = n
+	} else if n, err := strconv.Atoi(values.Get("page")); err == nil && n > 1 {
+		page.Offset = (n - 1) * page.Limit
+	}
+	return nil, nil
+}
+
+// AniListPage is the AniList page number of this page when pages are Limit long. It is only
+// exact when Offset is a multiple of Limit; see PageAligned.
+func (p PageRequest) AniListPage() int {
+	return p.Offset/p.Limit + 1
+}
+
+// PageAligned reports whether the page starts on a page boundary, as sources that page by
+// number, such as AniList, need
+func (p PageRequest) PageAligned() bool {
+	return p.Offset%p.Limit == 0
+}
+
+// AniListSort is the AniList sort for the request, or fallback when it has none
+func (p PageRequest) AniListSort(fallback string) string {
+	if s, ok := pageSorts[p.Sort]; ok {
+		return s.AniList
+	}
+	return fallback
+}
+
+// NextOffset returns the cursor of the page after one that returned count items at the
+// request's offset, or "" when that was the last page
+func (p PageRequest) NextOffset(count int) string {
+	if count < p.Limit {
+		return ""
+	}
+	return encodeCursor(PageCursor{Sort: p.Sort, Offset: p.Offset + count})
+}
+
+// SortDocument is the Mongo sort for the request, with _id as the tie-breaker
+func (p PageRequest) SortDocument() bson.D {
+	s, ok := lookupSort(p.Sort)
+	if !ok {
+		return bson.D{{Key: "_id", Value: 1}}
+	}
+	direction := 1
+	if s.Desc {
+		direction = -1
+	}
+	return bson.D{{Key: s.Field, Value: direction}, {Key: "_id", Value: 1}}
+}
+
+// lookupSort finds a sort key among the anime sorts and the fixed list orders
+func lookupSort(key string) (pageSort, bool) {
+	if s, ok := pageSorts[key]; ok {
+		return s, true
+	}
+	s, ok := listOrders[key]
+	return s, ok
+}
+
+// FindPage loads one page of the documents matching filter, continuing after the request's
+// cursor, and returns them with the cursor of the next page
+func FindPage(ctx context.Context, collection *mongo.Collection, filter bson.M, page PageRequest, projection bson.M) ([]primitive.M, string, error) {
+	var docs []primitive.M
+	next, err := FindPageInto(ctx, collection, filter, page, projection, &docs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The sort field is loaded for the next cursor even when the caller hides it
+	s, _ := lookupSort(page.Sort)
+	if v, ok := projection[s.Field]; ok && v == 0 && !strings.Contains(s.Field, ".") {
+		for _, doc := range docs {
+			delete(doc, s.Field)
+		}
+	}
+	return docs, next, nil
+}
+
+// FindPageInto is FindPage for typed documents: it decodes the page into results, a pointer
+// to a slice, and returns the cursor of the next page
+func FindPageInto(ctx context.Context, collection *mongo.Collection, filter bson.M, page PageRequest, projection bson.M, results interface{}) (string, error) {
+	s, ok := lookupSort(page.Sort)
+	if !ok {
+		return "", fmt.Errorf("sort must be one of %s", strings.Join(PageSortKeys(), ", "))
+	}
+
+	if v, ok := projection[s.Field]; ok && v == 0 {
+		shown := bson.M{}
+		for field, v := range projection {
+			if field != s.Field {
+				shown[field] = v
+			}
+		}
+		projection = shown
+	}
+
+	opts := options.Find().SetSort(page.SortDocument()).SetLimit(int64(page.Limit) + 1)
+	if len(projection) > 0 {
+		opts.SetProjection(projection)
+	}
+	if page.After != nil {
+		filter = withFilter(filter, afterCursor(s, page.After))
+	} else if page.Offset > 0 {
+		opts.SetSkip(int64(page.Offset))
+	}
+
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return "", err
+	}
+	var docs []bson.Raw
+	if err := cur.All(ctx, &docs); err != nil {
+		return "", err
+	}
+
+	next := ""
+	if len(docs) > page.Limit {
+		docs = docs[:page.Limit]
+		last := docs[len(docs)-1]
+		id, _ := last.Lookup("_id").ObjectIDOK()
+		next = encodeCursor(PageCursor{Sort: page.Sort, Offset: page.Offset + len(docs), Value: rawSortValue(last, s.Field), ID: id})
+	}
+
+	list := reflect.ValueOf(results).Elem()
+	list.Set(reflect.MakeSlice(list.Type(), 0, len(docs)))
+	for _, doc := range docs {
+		item := reflect.New(list.Type().Elem())
+		if err := bson.Unmarshal(doc, item.Interface()); err != nil {
+			return "", err
+		}
+		list.Set(reflect.Append(list, item.Elem()))
+	}
+	return next, nil
+}
+
+// afterCursor matches the documents that sort after the cursor. Documents without the sort
+// field sort before every value, so they come first in ascending order and last in descending.
+func afterCursor(s pageSort, cursor *PageCursor) bson.M {
+	tie := bson.M{s.Field: bson.M{"$eq": cursor.Value}, "_id": bson.M{"$gt": cursor.ID}}
+	if cursor.Value == nil {
+		if s.Desc {
+			return bson.M{s.Field: nil, "_id": bson.M{"$gt": cursor.ID}}
+		}
+		return bson.M{"$or": []bson.M{{s.Field: bson.M{"$ne": nil}}, {s.Field: nil, "_id": bson.M{"$gt": cursor.ID}}}}
+	}
+	if s.Desc {
+		return bson.M{"$or": []bson.M{{s.Field: bson.M{"$lt": cursor.Value}}, tie, {s.Field: nil}}}
+	}
+	return bson.M{"$or": []bson.M{{s.Field: bson.M{"$gt": cursor.Value}}, tie}}
+}
+
+// rawSortValue reads a dotted path such as "statistics.members" from a document, or nil when
+// the document lacks it
+func rawSortValue(doc bson.Raw, path string) interface{} {
+	raw, err := doc.LookupErr(strings.Split(path, ".")...)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := raw.Unmarshal(&value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// Cursors are BSON so sort values keep their types, dates and numbers alike
+func encodeCursor(cursor PageCursor) string {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cursor PageCursor
+	if err := bson.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	// An empty sort is the endpoint's own order, as for external sources
+	if _, ok := lookupSort(cursor.Sort); !ok && cursor.Sort != SORT_RELEVANCE && cursor.Sort != "" {
+		return nil, fmt.Errorf("unknown sort %q", cursor.Sort)
+	}
+	if cursor.Offset < 0 {
+		return nil, fmt.Errorf("negative offset")
+	}
+	// Cursors come back from clients, so only plain sort values may reach a filter
+	switch cursor.Value.(type) {
+	case nil, string, float64, int32, int64, primitive.DateTime:
+	default:
+		return nil, fmt.Errorf("invalid sort value")
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	values := []interface{}{
+		nil,
+		"shingeki no kyojin",
+		8.5,
+		int32(12),
+		int64(3000000),
+		primitive.NewDateTimeFromTime(time.Date(2013, 4, 7, 0, 0, 0, 0, time.UTC)),
+	}
+
+	for _, value := range values {
+		cursor := PageCursor{Sort: "score", Offset: 48, Value: value, ID: id}
+		decoded, err := decodeCursor(encodeCursor(cursor))
+		if err != nil {
+			t.Errorf("cursor with value %#v does not decode: %v", value, err)
+			continue
+		}
+		if !reflect.DeepEqual(*decoded, cursor) {
+			t.Errorf("cursor %+v decoded as %+v", cursor, *decoded)
+		}
+	}
+
+	for _, sort := range []string{"", SORT_RELEVANCE, "title", "newest", "helpful"} {
+		if _, err := decodeCursor(encodeCursor(PageCursor{Sort: sort})); err != nil {
+			t.Errorf("cursor with sort %q does not decode: %v", sort, err)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not BSON", base64.RawURLEncoding.EncodeToString([]byte("hello"))},
+		{"unknown sort", encodeCursor(PageCursor{Sort: "$natural"})},
+		{"negative offset", encodeCursor(PageCursor{Sort: "score", Offset: -24})},
+		{"operator value", encodeCursor(PageCursor{Sort: "score", Value: bson.M{"$gt": ""}})},
+		{"array value", encodeCursor(PageCursor{Sort: "score", Value: bson.A{1, 2}})},
+		{"regex value", encodeCursor(PageCursor{Sort: "title", Value: primitive.Regex{Pattern: ".*"}})},
+	}
+
+	for _, tt := range tests {
+		if cursor, err := decodeCursor(tt.raw); err == nil {
+			t.Errorf("%s: decodeCursor(%q) = %+v, want an error", tt.name, tt.raw, cursor)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	defer titleKeysReady.Store(titleKeysReady.Load())
+	titleKeysReady.Store(true)
+
+	after := encodeCursor(PageCursor{Sort: "year", Offset: 24, Value: int32(2013), ID: primitive.NewObjectID()})
+
+	tests := []struct {
+		query       string
+		defaultSort string
+		want        PageRequest // After is only checked for being set
+		after       bool
+		wantErr     bool
+	}{
+		{"", "score", PageRequest{Limit: 24, Sort: "score"}, false, false},
+		{"limit=10", "score", PageRequest{Limit: 10, Sort: "score"}, false, false},
+		{"limit=500", "score", PageRequest{Limit: 50, Sort: "score"}, false, false},
+		{"limit=0", "score", PageRequest{}, false, true},
+		{"limit=-5", "score", PageRequest{}, false, true},
+		{"limit=ten", "score", PageRequest{}, false, true},
+		{"sort=POPULARITY", "score", PageRequest{Limit: 24, Sort: "popularity"}, false, false},
+		{"sort=title", "score", PageRequest{Limit: 24, Sort: "title"}, false, false},
+		{"sort=bogus", "score", PageRequest{}, false, true},
+		{"sort=newest", "score", PageRequest{}, false, true},
+		{"sort=relevance", "score", PageRequest{}, false, true},
+		{"sort=relevance", SORT_RELEVANCE, PageRequest{Limit: 24, Sort: SORT_RELEVANCE}, false, false},
+		{"page=3&limit=10", "score", PageRequest{Limit: 10, Sort: "score", Offset: 20}, false, false},
+		{"page=0", "score", PageRequest{Limit: 24, Sort: "score"}, false, false},
+		{"offset=15&page=3", "score", PageRequest{Limit: 24, Sort: "score", Offset: 15}, false, false},
+		{"offset=-15", "score", PageRequest{Limit: 24, Sort: "score"}, false, false},
+		{"cursor=" + after, "score", PageRequest{Limit: 24, Sort: "year", Offset: 24}, true, false},
+		{"cursor=" + after + "&sort=year", "score", PageRequest{Limit: 24, Sort: "year", Offset: 24}, true, false},
+		{"cursor=" + after + "&sort=score", "score", PageRequest{}, false, true},
+		{"cursor=" + after + "&offset=100", "score", PageRequest{Limit: 24, Sort: "year", Offset: 24}, true, false},
+		{"cursor=" + encodeCursor(PageCursor{Sort: SORT_RELEVANCE, Offset: 24}), SORT_RELEVANCE, PageRequest{Limit: 24, Sort: SORT_RELEVANCE, Offset: 24}, false, false},
+		{"cursor=" + encodeCursor(PageCursor{Sort: SORT_RELEVANCE, Offset: 24}), "score", PageRequest{}, false, true},
+		{"cursor=" + encodeCursor(PageCursor{Sort: "helpful", Offset: 24}), "score", PageRequest{}, false, true},
+		{"cursor=garbage", "score", PageRequest{}, false, true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		page, err := ParsePageRequest(values, tt.defaultSort, DEFAULT_PAGE_LIMIT, 50)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePageRequest(%q) = %+v, want an error", tt.query, page)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePageRequest(%q) error = %v", tt.query, err)
+			continue
+		}
+		if (page.After != nil) != tt.after {
+			t.Errorf("ParsePageRequest(%q).After = %+v, want set %v", tt.query, page.After, tt.after)
+		}
+		page.After = nil
+		if page != tt.want {
+			t.Errorf("ParsePageRequest(%q) = %+v, want %+v", tt.query, page, tt.want)
+		}
+	}
+}
+
+func TestParsePageRequestWaitsForTitleKeys(t *testing.T) {
+	defer titleKeysReady.Store(titleKeysReady.Load())
+	titleKeysReady.Store(false)
+
+	for _, query := range []string{"sort=title", "cursor=" + encodeCursor(PageCursor{Sort: "title", Value: "naruto"})} {
+		values, _ := url.ParseQuery(query)
+		if _, err := ParsePageRequest(values, "score", DEFAULT_PAGE_LIMIT, MAX_PAGE_LIMIT); err == nil {
+			t.Errorf("ParsePageRequest(%q) sorted by title before title keys were ready", query)
+		}
+	}
+}
+
+func TestParseListPage(t *testing.T) {
+	newest := encodeCursor(PageCursor{Sort: "newest", Value: primitive.NewDateTimeFromTime(time.Now()), ID: primitive.NewObjectID()})
+
+	tests := []struct {
+		query   string
+		want    PageRequest
+		after   bool
+		wantErr bool
+	}{
+		{"", PageRequest{Limit: 20, Sort: "newest"}, false, false},
+		{"limit=1000", PageRequest{Limit: MAX_PAGE_LIMIT, Sort: "newest"}, false, false},
+		{"limit=0", PageRequest{}, false, true},
+		{"page=2", PageRequest{Limit: 20, Sort: "newest", Offset: 20}, false, false},
+		{"sort=oldest", PageRequest{Limit: 20, Sort: "newest"}, false, false}, // Fixed order
+		{"cursor=" + newest, PageRequest{Limit: 20, Sort: "newest"}, true, false},
+		{"cursor=" + encodeCursor(PageCursor{Sort: "oldest"}), PageRequest{}, false, true},
+		{"cursor=" + encodeCursor(PageCursor{Sort: "score"}), PageRequest{}, false, true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		page, err := ParseListPage(values, "newest", 20)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseListPage(%q) = %+v, want an error", tt.query, page)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseListPage(%q) error = %v", tt.query, err)
+			continue
+		}
+		if (page.After != nil) != tt.after {
+			t.Errorf("ParseListPage(%q).After = %+v, want set %v", tt.query, page.After, tt.after)
+		}
+		page.After = nil
+		if page != tt.want {
+			t.Errorf("ParseListPage(%q) = %+v, want %+v", tt.query, page, tt.want)
+		}
+	}
+}
+
+func TestPageOffsets(t *testing.T) {
+	tests := []struct {
+		limit, offset int
+		anilistPage   int
+		aligned       bool
+	}{
+		{50, 0, 1, true},
+		{50, 50, 2, true},
+		{50, 100, 3, true},
+		{50, 25, 1, false},
+		{24, 72, 4, true},
+		{24, 70, 3, false},
+	}
+
+	for _, tt := range tests {
+		page := PageRequest{Limit: tt.limit, Offset: tt.offset}
+		if got := page.AniListPage(); got != tt.anilistPage {
+			t.Errorf("AniListPage() at offset %d of limit %d = %d, want %d", tt.offset, tt.limit, got, tt.anilistPage)
+		}
+		if got := page.PageAligned(); got != tt.aligned {
+			t.Errorf("PageAligned() at offset %d of limit %d = %v, want %v", tt.offset, tt.limit, got, tt.aligned)
+		}
+	}
+}
+
+func TestNextOffset(t *testing.T) {
+	page := PageRequest{Limit: 20, Sort: SORT_RELEVANCE, Offset: 40}
+	if next := page.NextOffset(19); next != "" {
+		t.Errorf("a short page has a next page: %q", next)
+	}
+
+	cursor, err := decodeCursor(page.NextOffset(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Sort != SORT_RELEVANCE || cursor.Offset != 60 || cursor.Value != nil {
+		t.Errorf("next cursor = %+v, want relevance at offset 60", cursor)
+	}
+}
+
+func TestSortDocument(t *testing.T) {
+	tests := []struct {
+		sort string
+		want bson.D
+	}{
+		{"score", bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}},
+		{"title", bson.D{{Key: "name_key", Value: 1}, {Key: "_id", Value: 1}}},
+		{"oldest", bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{"helpful", bson.D{{Key: "wilson_score", Value: -1}, {Key: "_id", Value: 1}}},
+		{SORT_RELEVANCE, bson.D{{Key: "_id", Value: 1}}},
+	}
+
+	for _, tt := range tests {
+		if got := (PageRequest{Sort: tt.sort}).SortDocument(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortDocument(%q) = %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
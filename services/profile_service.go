@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MAX_FAVORITES = 10
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// ProfileUpdate represents the editable fields of a user's profile
+type ProfileUpdate struct {
+	Username  *string                `json:"username,omitempty"`
+	Bio       *string                `json:"bio,omitempty"`
+	AvatarUrl *string                `json:"avatarUrl,omitempty"`
+	Favorites []string               `json:"favorites,omitempty"`
+	Privacy   *model.PrivacySettings `json:"privacy,omitempty"`
+}
+
+// EnsureUserIndexes creates the unique username index on the users collection
+func EnsureUserIndexes() {
+	if config.UserCollection == nil {
+		return
+	}
+
+	// Partial index so users without a username yet don't collide
+	config.UserCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"username": bson.M{"$type": "string"}}),
+	})
+
+	config.UserCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "feed_token_hash", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"feed_token_hash": bson.M{"$type": "string"}}),
+	})
+}
+
+// GetUserByUsername finds a user by their unique username
+func GetUserByUsername(username string) (*model.User, error) {
+	var user model.User
+	err := config.UserCollection.FindOne(context.Background(), bson.M{"username": strings.ToLower(username)}).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ResolveUser finds a user by username, falling back to Supabase ID
+func ResolveUser(ref string) (*model.User, error) {
+	if user, err := GetUserByUsername(ref); err == nil {
+		return user, nil
+	}
+	return GetUserBySupabaseID(ref)
+}
+
+// UpdateUserProfile applies a profile update for the given user
+func UpdateUserProfile(supabaseID string, req ProfileUpdate) (*model.User, error) {
+	set := bson.M{"updated_at": time.Now()}
+
+	if req.Username != nil {
+		username := strings.ToLower(strings.TrimSpace(*req.Username))
+		if !usernamePattern.MatchString(username) {
+			return nil, fmt.Errorf("username must be 3-30 characters of a-z, 0-9 or _")
+		}
+		if existing, err := GetUserByUsername(username); err == nil && existing.SupabaseID != supabaseID {
+			return nil, fmt.Errorf("username already taken")
+		}
+		set["username"] = username
+	}
+
+	if req.Bio != nil {
+		if len(*req.Bio) > 500 {
+			return nil, fmt.Errorf("bio must be at most 500 characters")
+		}
+		set["bio"] = *req.Bio
+	}
+
+	if req.AvatarUrl != nil {
+		set["avatarUrl"] = *req.AvatarUrl
+	}
+
+	if req.Favorites != nil {
+		if len(req.Favorites) > MAX_FAVORITES {
+			return nil, fmt.Errorf("at most %d favorites allowed", MAX_FAVORITES)
+		}
+		set["favorites"] = req.Favorites
+	}
+
+	if req.Privacy != nil {
+		for _, level := range []model.PrivacyLevel{req.Privacy.Profile, req.Privacy.Favorites, req.Privacy.Stats, req.Privacy.List} {
+			if !isValidPrivacyLevel(level) {
+				return nil, fmt.Errorf("invalid privacy level: %s", level)
+			}
+		}
+		set["privacy"] = req.Privacy
+	}
+
+	_, err := config.UserCollection.UpdateOne(context.Background(), bson.M{"supabase_id": supabaseID}, bson.M{"$set": set})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("username already taken")
+		}
+		return nil, err
+	}
+
+	if req.Bio != nil {
+		FlagContent(model.ReportProfile, supabaseID, *req.Bio)
+	}
+
+	return GetUserBySupabaseID(supabaseID)
+}
+
+func isValidPrivacyLevel(level model.PrivacyLevel) bool {
+	switch level {
+	case "", model.PrivacyPublic, model.PrivacyFollowers, model.PrivacyPrivate:
+		return true
+	}
+	return false
+}
+
+// CanViewSection reports whether viewerID may see a section with the given privacy level
+func CanViewSection(owner *model.User, level model.PrivacyLevel, viewerID string) bool {
+	if viewerID != "" && viewerID == owner.SupabaseID {
+		return true
+	}
+
+	switch level {
+	case model.PrivacyPrivate:
+		return false
+	case model.PrivacyFollowers:
+		return IsFollowing(viewerID, owner.SupabaseID)
+	default:
+		return true
+	}
+}
+
+// CanViewList reports whether viewerID may see the entries on ownerID's list
+func CanViewList(ownerID, viewerID string) bool {
+	owner, err := GetUserBySupabaseID(ownerID)
+	if err != nil {
+		return false
+	}
+	if owner.Moderation.ListHidden && viewerID != owner.SupabaseID {
+		return false
+	}
+	return CanViewSection(owner, owner.Privacy.List, viewerID)
+}
+
+// GetPublicProfile builds the profile of a user as seen by viewerID, with one page of their list.
+// It returns the cursor of the list's next page too.
+func GetPublicProfile(ref, viewerID string, page PageRequest) (*model.PublicProfile, string, error) {
+	user, err := ResolveUser(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	profile := &model.PublicProfile{
+		UserID:    user.SupabaseID,
+		Username:  user.Username,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+	}
+
+	if followers, err := GetFollowers(user.SupabaseID); err == nil {
+		profile.Followers = len(followers)
+	}
+	if following, err := GetFollowing(user.SupabaseID); err == nil {
+		profile.Following = len(following)
+	}
+
+	listNext := ""
+
+	// Moderator-hidden sections stay visible to their owner only
+	isOwner := viewerID != "" && viewerID == user.SupabaseID
+	profileHidden := user.Moderation.ProfileHidden && !isOwner
+
+	if CanViewSection(user, user.Privacy.Profile, viewerID) && !profileHidden {
+		profile.Bio = user.Bio
+		profile.AvatarUrl = user.AvatarUrl
+	} else {
+		profile.Hidden = append(profile.Hidden, "profile")
+	}
+
+	if CanViewSection(user, user.Privacy.Favorites, viewerID) && !profileHidden {
+		profile.Favorites = user.Favorites
+	} else {
+		profile.Hidden = append(profile.Hidden, "favorites")
+	}
+
+	if CanViewSection(user, user.Privacy.Stats, viewerID) {
+		stats := user.Stats
+		profile.Stats = &stats
+	} else {
+		profile.Hidden = append(profile.Hidden, "stats")
+	}
+
+	if CanViewSection(user, user.Privacy.List, viewerID) && !(user.Moderation.ListHidden && !isOwner) {
+		list, next, err := GetUserListPage(user.SupabaseID, page)
+		if err != nil {
+			return nil, "", err
+		}
+		listNext = next
+		// Notes are personal and never shown to other users
+		if viewerID != user.SupabaseID {
+			for i := range list {
+				list[i].Notes = ""
+			}
+		}
+		profile.List = list
+	} else {
+		profile.Hidden = append(profile.Hidden, "list")
+	}
+
+	return profile, listNext, nil
+}
+
+// PublicCatalogFilter restricts a filter to shared catalog entries, excluding users' list copies
+func PublicCatalogFilter(filter bson.M) bson.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if _, ok := filter["user_id"]; !ok {
+		filter["user_id"] = bson.M{"$exists": false}
+	}
+	return filter
+}
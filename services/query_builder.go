@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User input never reaches a Mongo $regex, an AniList query or a Jikan URL as syntax. Regexes
+// are built here from escaped literals, AniList queries take user values as GraphQL variables
+// and Jikan search terms are URL-encoded.
+
+const MAX_PATTERN_LENGTH = 200 // Longest text matched by regex, in characters; names are capped at 200 too
+
+var aniListGraphQLURL = "https://graphql.anilist.co"
+
+// ExactText matches the whole value, ignoring case
+func ExactText(value string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + literalPattern(value) + "$", Options: "i"}
+}
+
+// ContainsText matches the value anywhere, ignoring case
+func ContainsText(value string) primitive.Regex {
+	return primitive.Regex{Pattern: literalPattern(value), Options: "i"}
+}
+
+// PrefixText matches values that start with value. It is case-sensitive so the
+// match can use an index; callers pass already lower-cased terms.
+func PrefixText(value string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + literalPattern(value)}
+}
+
+// literalPattern turns user text into a regex that only matches that text. Control
+// characters are dropped and the text is cut to MAX_PATTERN_LENGTH characters, so the
+// pattern has no operators and its cost is bounded by its length.
+func literalPattern(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+
+	if utf8.RuneCountInString(value) > MAX_PATTERN_LENGTH {
+		value = string([]rune(value)[:MAX_PATTERN_LENGTH])
+	}
+	return regexp.QuoteMeta(value)
+}
+
+// QueryAniList runs a GraphQL query against AniList and decodes the response into out.
+// Values from users must be passed in variables, never formatted into the query.
+func QueryAniList(query string, variables map[string]interface{}, out interface{}) error {
+	requestBody := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(aniListGraphQLURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("anilist returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jikanSearchURL builds a Jikan anime search URL with the term encoded as a single parameter
+func jikanSearchURL(term string, limit int) string {
+	params := url.Values{}
+	params.Set("q", term)
+	params.Set("limit", fmt.Sprint(limit))
+	return "https://api.jikan.moe/v4/anime?" + params.Encode()
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var hostileInputs = []string{
+	`(a+)+$`,
+	`.*`,
+	`^`,
+	`$`,
+	`\`,
+	`[`,
+	`a{1,100000}`,
+	`(?i)x|y`,
+	`$where`,
+	`{"$gt": ""}`,
+	"null\x00byte",
+	`Re:Zero`,
+}
+
+// compile stands in for the server's regex engine; RE2 and PCRE agree on escaped literals
+func compile(t *testing.T, re primitive.Regex) *regexp.Regexp {
+	t.Helper()
+	if re.Options != "" && re.Options != "i" {
+		t.Fatalf("unexpected regex options %q", re.Options)
+	}
+	prefix := ""
+	if re.Options == "i" {
+		prefix = "(?i)"
+	}
+	compiled, err := regexp.Compile(prefix + re.Pattern)
+	if err != nil {
+		t.Fatalf("pattern %q does not compile: %v", re.Pattern, err)
+	}
+	return compiled
+}
+
+func TestExactTextMatchesOnlyTheLiteral(t *testing.T) {
+	for _, input := range hostileInputs {
+		re := compile(t, ExactText(input))
+		literal := strings.ReplaceAll(input, "\x00", "")
+		if !re.MatchString(strings.ToUpper(literal)) && !re.MatchString(literal) {
+			t.Errorf("ExactText(%q) does not match its own text", input)
+		}
+		for _, other := range []string{"", "Naruto", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaa!", literal + "x"} {
+			if re.MatchString(other) {
+				t.Errorf("ExactText(%q) matches %q", input, other)
+			}
+		}
+	}
+}
+
+func TestContainsTextHasNoOperators(t *testing.T) {
+	for _, input := range hostileInputs {
+		re := compile(t, ContainsText(input))
+		literal := strings.ReplaceAll(input, "\x00", "")
+		if !re.MatchString("before " + literal + " after") {
+			t.Errorf("ContainsText(%q) does not find its own text", input)
+		}
+		if re.MatchString("Naruto Shippuden") {
+			t.Errorf("ContainsText(%q) matches unrelated text", input)
+		}
+	}
+}
+
+func TestPrefixTextIsAnchoredLiteral(t *testing.T) {
+	re := compile(t, PrefixText(".*"))
+	if re.MatchString("naruto") || !re.MatchString(".*naruto") {
+		t.Errorf("PrefixText(%q) = %q is not an anchored literal", ".*", re.String())
+	}
+}
+
+func TestPatternLengthIsCapped(t *testing.T) {
+	long := strings.Repeat("(a+)+", 10000)
+	pattern := ExactText(long).Pattern
+
+	unescaped := strings.ReplaceAll(strings.Trim(pattern, "^$"), `\`, "")
+	if n := utf8.RuneCountInString(unescaped); n > MAX_PATTERN_LENGTH {
+		t.Errorf("pattern keeps %d characters, want at most %d", n, MAX_PATTERN_LENGTH)
+	}
+	if !utf8.ValidString(ExactText(strings.Repeat("進撃", 500)).Pattern) {
+		t.Error("truncation split a multi-byte character")
+	}
+}
+
+func TestControlCharactersAreDropped(t *testing.T) {
+	if pattern := ContainsText("na\x00ru\nto\xff").Pattern; pattern != "naruto" {
+		t.Errorf("ContainsText kept control or invalid bytes: %q", pattern)
+	}
+}
+
+func TestQueryLanguageValuesAreLiterals(t *testing.T) {
+	for _, input := range hostileInputs {
+		query, err := ParseSearchQuery(`studio:"` + strings.ReplaceAll(input, `"`, "") + `"`)
+		if err != nil {
+			continue // Rejected outright is safe too
+		}
+		filter := query.Filter()
+		clause := filter["$and"].([]bson.M)[0]
+		re, ok := clause["information.studios"].(primitive.Regex)
+		if !ok {
+			t.Fatalf("studio filter for %q is %T, want an escaped regex", input, clause["information.studios"])
+		}
+		if compile(t, re).MatchString("Madhouse") {
+			t.Errorf("studio:%q matches every studio", input)
+		}
+	}
+}
+
+func TestQueryLanguageRejectsOperatorValues(t *testing.T) {
+	for _, input := range []string{`year:{"$gt":0}`, `score:$where`, `year:1..$ne`, `season:.*`} {
+		_, err := ParseSearchQuery(input)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseSearchQuery(%q) error = %v, want a QueryError", input, err)
+		}
+	}
+}
+
+func TestQueryLanguageLengthIsCapped(t *testing.T) {
+	if _, err := ParseSearchQuery(strings.Repeat("a", MAX_SEARCH_QUERY_LENGTH+1)); err == nil {
+		t.Error("an overlong query was accepted")
+	}
+}
+
+func TestQueryAniListSendsInputAsVariables(t *testing.T) {
+	hostile := `naruto", type: MANGA) { id } } #`
+
+	var received struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"data":{"Page":{"media":[]}}}`))
+	}))
+	defer server.Close()
+
+	previous := aniListGraphQLURL
+	aniListGraphQLURL = server.URL
+	defer func() { aniListGraphQLURL = previous }()
+
+	GetHighQualityImages(hostile)
+
+	if strings.Contains(received.Query, hostile) || strings.Contains(received.Query, "MANGA") {
+		t.Errorf("user input was formatted into the query: %s", received.Query)
+	}
+	if received.Variables["search"] != hostile {
+		t.Errorf("search variable = %v, want the input unchanged", received.Variables["search"])
+	}
+}
+
+func TestJikanSearchURLKeepsTermInOneParameter(t *testing.T) {
+	raw := jikanSearchURL("naruto&sfw=false&limit=25#x", 10)
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := parsed.Query()
+	if params.Get("q") != "naruto&sfw=false&limit=25#x" || params.Get("limit") != "10" || params.Has("sfw") {
+		t.Errorf("jikanSearchURL leaked parameters: %s", raw)
+	}
+}
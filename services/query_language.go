@@ -0,0 +1,369 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MAX_SEARCH_QUERY_LENGTH caps the query language input in bytes
+const MAX_SEARCH_QUERY_LENGTH = 512
+
+// The search query language, as typed into the search box:
+//
+//	query   = { term }
+//	term    = [ "-" ] field ":" value | phrase | word
+//	field   = "genre" | "tag" | "studio" | "type" | "format" | "status" | "airing" | "season" | "year" | "score"
+//	value   = phrase | bare
+//	phrase  = '"' { any character except '"' } '"'
+//	number  = [ ">" | ">=" | "<" | "<=" | "=" ] num | num ".." num | num ".." | ".." num
+//
+// Terms are separated by white space and must all hold. A leading "-" excludes matches and is
+// only allowed on field filters. status is the watch status of a list entry and airing the
+// broadcast status, as in airing:"Currently Airing". year and score take a number; the other
+// fields match their value case-insensitively and in full. Words and phrases that are not
+// filters are the free text of the query, so titles with a colon such as "Re:Zero" are still
+// searched as text.
+//
+//	genre:action -genre:ecchi year:2015..2020 score:>=8 studio:"Madhouse" type:movie
+
+type queryFieldKind int
+
+const (
+	textField queryFieldKind = iota
+	seasonField
+	intField
+	floatField
+)
+
+type queryField struct {
+	Name string // Canonical field name, so aliases share one facet
+	Path string // Document path
+	Kind queryFieldKind
+}
+
+var queryFields = map[string]queryField{
+	"genre":  {"genre", "genre", textField},
+	"tag":    {"tag", "tags", textField},
+	"studio": {"studio", "information.studios", textField},
+	"type":   {"type", "type", textField},
+	"format": {"type", "type", textField},
+	"status": {"status", "status", textField},
+	"airing": {"airing", "information.status", textField},
+	"season": {"season", "season", seasonField},
+	"year":   {"year", "year", intField},
+	"score":  {"score", "score", floatField},
+}
+
+// QueryError reports where and why a search query could not be parsed
+type QueryError struct {
+	Position int    `json:"position"` // Byte offset into the query
+	Message  string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Position+1, e.Message)
+}
+
+// QueryClause is one compiled field filter of a search query
+type QueryClause struct {
+	Field  string // Canonical field name, e.g. "genre"
+	Negate bool
+	Source string // The clause as written, normalized
+	Filter bson.M
+}
+
+// SearchQuery is a parsed search query: field filters plus free text
+type SearchQuery struct {
+	Text    string
+	Clauses []QueryClause
+}
+
+// ParseSearchQuery parses the search query language into a SearchQuery.
+// Errors are *QueryError values that point at the offending part of the input.
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	if len(input) > MAX_SEARCH_QUERY_LENGTH {
+		return nil, &QueryError{Position: MAX_SEARCH_QUERY_LENGTH, Message: fmt.Sprintf("query is longer than %d characters", MAX_SEARCH_QUERY_LENGTH)}
+	}
+
+	p := &queryParser{input: input}
+	q := &SearchQuery{}
+	var text []string
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+
+		start := p.pos
+		negate := p.input[p.pos] == '-'
+		if negate {
+			p.pos++
+			if p.done() || p.atSpace() {
+				return nil, &QueryError{Position: start, Message: `expected a filter after "-"`}
+			}
+		}
+
+		if p.input[p.pos] == '"' {
+			phrase, err := p.phrase()
+			if err != nil {
+				return nil, err
+			}
+			if negate {
+				return nil, &QueryError{Position: start, Message: "only field filters can be excluded, as in -genre:ecchi"}
+			}
+			text = append(text, phrase)
+			continue
+		}
+
+		if field, ok := p.field(); ok {
+			valueStart := p.pos
+			var value string
+			if !p.done() && p.input[p.pos] == '"' {
+				phrase, err := p.phrase()
+				if err != nil {
+					return nil, err
+				}
+				value = phrase
+			} else {
+				value = p.bare()
+			}
+			if strings.TrimSpace(value) == "" {
+				return nil, &QueryError{Position: valueStart, Message: fmt.Sprintf("missing value for %s", field.Name)}
+			}
+
+			clause, err := compileClause(field, value, negate)
+			if err != nil {
+				return nil, &QueryError{Position: valueStart, Message: err.Error()}
+			}
+			q.Clauses = append(q.Clauses, clause)
+			continue
+		}
+
+		if negate {
+			return nil, &QueryError{Position: start, Message: "only field filters can be excluded, as in -genre:ecchi"}
+		}
+		text = append(text, p.bare())
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// Require adds a field filter from a separate request parameter, such as ?genre=Action
+func (q *SearchQuery) Require(name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	field, ok := queryFields[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown filter %q", name)
+	}
+	clause, err := compileClause(field, value, false)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	q.Clauses = append(q.Clauses, clause)
+	return nil
+}
+
+// Empty reports whether the query has neither text nor filters
+func (q *SearchQuery) Empty() bool {
+	return strings.TrimSpace(q.Text) == "" && len(q.Clauses) == 0
+}
+
+// Filter compiles the field filters into a new Mongo filter. The free text is not included.
+func (q *SearchQuery) Filter() bson.M {
+	return q.FilterExcept("")
+}
+
+// FilterExcept compiles every field filter except those on field
+func (q *SearchQuery) FilterExcept(field string) bson.M {
+	clauses := []bson.M{}
+	for _, clause := range q.Clauses {
+		if clause.Field != field {
+			clauses = append(clauses, clause.Filter)
+		}
+	}
+	if len(clauses) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": clauses}
+}
+
+// String returns the query in normalized form, suitable as a cache key
+func (q *SearchQuery) String() string {
+	parts := make([]string, 0, len(q.Clauses)+1)
+	for _, clause := range q.Clauses {
+		parts = append(parts, clause.Source)
+	}
+	if text := strings.Join(strings.Fields(strings.ToLower(q.Text)), " "); text != "" {
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func compileClause(field queryField, value string, negate bool) (QueryClause, error) {
+	value = strings.TrimSpace(value)
+
+	var condition interface{}
+	switch field.Kind {
+	case textField:
+		condition = ExactText(value)
+	case seasonField:
+		season := strings.ToLower(value)
+		if season == "autumn" {
+			season = "fall"
+		}
+		switch season {
+		case "winter", "spring", "summer", "fall":
+		default:
+			return QueryClause{}, fmt.Errorf("season must be winter, spring, summer or fall")
+		}
+		condition = ExactText(season)
+	case intField, floatField:
+		bounds, err := parseNumberFilter(value, field.Kind == intField)
+		if err != nil {
+			return QueryClause{}, err
+		}
+		condition = bounds
+	}
+
+	filter := bson.M{field.Path: condition}
+	source := field.Name + ":" + strconv.Quote(strings.ToLower(value))
+	if negate {
+		filter = bson.M{"$nor": []bson.M{filter}}
+		source = "-" + source
+	}
+	return QueryClause{Field: field.Name, Negate: negate, Source: source, Filter: filter}, nil
+}
+
+// parseNumberFilter reads "8", ">=8", "<8", "2015..2020", "2015.." or "..2020" into Mongo comparisons
+func parseNumberFilter(value string, integer bool) (interface{}, error) {
+	parse := func(s string) (interface{}, error) {
+		if integer {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a whole number", s)
+			}
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+	}
+
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		bounds := bson.M{}
+		if from != "" {
+			n, err := parse(from)
+			if err != nil {
+				return nil, err
+			}
+			bounds["$gte"] = n
+		}
+		if to != "" {
+			n, err := parse(to)
+			if err != nil {
+				return nil, err
+			}
+			bounds["$lte"] = n
+		}
+		if len(bounds) == 0 {
+			return nil, fmt.Errorf("a range needs at least one end")
+		}
+		if from != "" && to != "" {
+			low, _ := strconv.ParseFloat(from, 64)
+			high, _ := strconv.ParseFloat(to, 64)
+			if low > high {
+				return nil, fmt.Errorf("range %s starts after it ends", value)
+			}
+		}
+		return bounds, nil
+	}
+
+	for _, op := range []struct{ prefix, mongo string }{{">=", "$gte"}, {"<=", "$lte"}, {">", "$gt"}, {"<", "$lt"}, {"=", "$eq"}} {
+		if rest, ok := strings.CutPrefix(value, op.prefix); ok {
+			n, err := parse(rest)
+			if err != nil {
+				return nil, err
+			}
+			return bson.M{op.mongo: n}, nil
+		}
+	}
+
+	n, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+type queryParser struct {
+	input string
+	pos   int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// atSpace checks for ASCII white space only, as the input is scanned byte by byte
+func (p *queryParser) atSpace() bool {
+	switch p.input[p.pos] {
+	case ' ', '\t', '\n', '\r', '\f', '\v':
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.done() && p.atSpace() {
+		p.pos++
+	}
+}
+
+// field consumes "name:" when name is a known field and reports whether it did
+func (p *queryParser) field() (queryField, bool) {
+	end := p.pos
+	for end < len(p.input) && (p.input[end] >= 'a' && p.input[end] <= 'z' || p.input[end] >= 'A' && p.input[end] <= 'Z') {
+		end++
+	}
+	if end == len(p.input) || p.input[end] != ':' {
+		return queryField{}, false
+	}
+	field, ok := queryFields[strings.ToLower(p.input[p.pos:end])]
+	if !ok {
+		return queryField{}, false
+	}
+	p.pos = end + 1
+	return field, true
+}
+
+// phrase consumes a double-quoted phrase and returns its contents
+func (p *queryParser) phrase() (string, error) {
+	start := p.pos
+	end := strings.IndexByte(p.input[start+1:], '"')
+	if end < 0 {
+		return "", &QueryError{Position: start, Message: "unterminated quote"}
+	}
+	p.pos = start + 1 + end + 1
+	if !p.done() && !p.atSpace() {
+		return "", &QueryError{Position: p.pos, Message: "expected a space after the closing quote"}
+	}
+	return p.input[start+1 : start+1+end], nil
+}
+
+// bare consumes everything up to the next white space
+func (p *queryParser) bare() string {
+	start := p.pos
+	for !p.done() && !p.atSpace() {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		input  string
+		text   string
+		source string // Normalized form of the whole query
+	}{
+		{"", "", ""},
+		{"   ", "", ""},
+		{"naruto", "naruto", "naruto"},
+		{"  Attack   on Titan ", "Attack on Titan", "attack on titan"},
+		{`"one piece"`, "one piece", "one piece"},
+		{"genre:action", "", `genre:"action"`},
+		{"GENRE:Action", "", `genre:"action"`},
+		{"-genre:ecchi", "", `-genre:"ecchi"`},
+		{`studio:"Kyoto Animation"`, "", `studio:"kyoto animation"`},
+		{"format:movie", "", `type:"movie"`},
+		{"season:autumn", "", `season:"autumn"`},
+		{"genre:action year:2015..2020 score:>=8 naruto", "naruto", `genre:"action" year:"2015..2020" score:">=8" naruto`},
+		{"Re:Zero", "Re:Zero", "re:zero"},
+		{"title:naruto", "title:naruto", "title:naruto"},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseSearchQuery(tt.input)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q) error = %v", tt.input, err)
+			continue
+		}
+		if q.Text != tt.text {
+			t.Errorf("ParseSearchQuery(%q).Text = %q, want %q", tt.input, q.Text, tt.text)
+		}
+		if q.String() != tt.source {
+			t.Errorf("ParseSearchQuery(%q).String() = %q, want %q", tt.input, q.String(), tt.source)
+		}
+	}
+}
+
+func TestParseSearchQueryFilters(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bson.M
+	}{
+		{"year:2015", bson.M{"year": 2015}},
+		{"year:2015..2020", bson.M{"year": bson.M{"$gte": 2015, "$lte": 2020}}},
+		{"year:2015..", bson.M{"year": bson.M{"$gte": 2015}}},
+		{"year:..2020", bson.M{"year": bson.M{"$lte": 2020}}},
+		{"score:>=8", bson.M{"score": bson.M{"$gte": 8.0}}},
+		{"score:<7.5", bson.M{"score": bson.M{"$lt": 7.5}}},
+		{"score:=9", bson.M{"score": bson.M{"$eq": 9.0}}},
+		{"-year:2020", bson.M{"$nor": []bson.M{{"year": 2020}}}},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseSearchQuery(tt.input)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q) error = %v", tt.input, err)
+			continue
+		}
+		if len(q.Clauses) != 1 {
+			t.Errorf("ParseSearchQuery(%q) has %d clauses, want 1", tt.input, len(q.Clauses))
+			continue
+		}
+		if !reflect.DeepEqual(q.Clauses[0].Filter, tt.want) {
+			t.Errorf("ParseSearchQuery(%q) filter = %v, want %v", tt.input, q.Clauses[0].Filter, tt.want)
+		}
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{"-", 0},
+		{"naruto - genre:action", 7},
+		{`-"one piece"`, 0},
+		{"-naruto", 0},
+		{`"one piece`, 0},
+		{`naruto "one piece`, 7},
+		{`"one piece"x`, 11},
+		{"genre:", 6},
+		{`genre:""`, 6},
+		{"year:soon", 5},
+		{"year:2020..2015", 5},
+		{"year:..", 5},
+		{"score:>=high", 6},
+		{"season:monsoon", 7},
+		{`studio:"Madhouse`, 7},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSearchQuery(tt.input)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseSearchQuery(%q) error = %v, want a QueryError", tt.input, err)
+			continue
+		}
+		if queryErr.Position != tt.position {
+			t.Errorf("ParseSearchQuery(%q) error at %d (%s), want %d", tt.input, queryErr.Position, queryErr.Message, tt.position)
+		}
+	}
+}
+
+func TestSearchQueryFilterExcept(t *testing.T) {
+	q, err := ParseSearchQuery("genre:action genre:drama year:2020")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(q.Filter()["$and"].([]bson.M)); got != 3 {
+		t.Errorf("Filter() has %d clauses, want 3", got)
+	}
+	if got := len(q.FilterExcept("genre")["$and"].([]bson.M)); got != 1 {
+		t.Errorf("FilterExcept(genre) has %d clauses, want 1", got)
+	}
+	if got := q.FilterExcept("year"); len(got["$and"].([]bson.M)) != 2 {
+		t.Errorf("FilterExcept(year) = %v, want the two genre clauses", got)
+	}
+
+	text, _ := ParseSearchQuery("naruto")
+	if len(text.Filter()) != 0 {
+		t.Errorf("free text compiled into a filter: %v", text.Filter())
+	}
+}
+
+func TestSearchQueryRequire(t *testing.T) {
+	q := &SearchQuery{}
+	if err := q.Require("genre", "Action"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Require("year", ""); err != nil {
+		t.Errorf("an empty parameter was rejected: %v", err)
+	}
+	if err := q.Require("year", "soon"); err == nil {
+		t.Error("an invalid year was accepted")
+	}
+	if err := q.Require("rating", "pg"); err == nil {
+		t.Error("an unknown filter was accepted")
+	}
+	if len(q.Clauses) != 1 || q.Clauses[0].Source != `genre:"action"` {
+		t.Errorf("Require clauses = %+v", q.Clauses)
+	}
+	if q.Empty() {
+		t.Error("a query with a filter is empty")
+	}
+}
@@ -0,0 +1,725 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"animeverse/cache"
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	RECOMMENDATION_CANDIDATE_POOL = 3000 // Catalog titles scored per request
+	RECOMMENDATION_CACHE_TTL      = 30 * time.Minute
+	RECOMMENDATION_PROFILE_SIZE   = 15  // Strongest taste features returned alongside the results
+	RECOMMENDATION_CF_WEIGHT      = 0.4 // Share of the final score taken from collaborative filtering
+	RECOMMENDATION_CF_CANDIDATES  = 200 // Collaborative picks pulled in beyond the content candidate pool
+
+	RECOMMENDATION_SOURCE_CONTENT       = "content"
+	RECOMMENDATION_SOURCE_COLLABORATIVE = "collaborative"
+	RECOMMENDATION_SOURCE_POPULAR       = "popular"
+)
+
+// recommendationFeatureWeights sets how much each kind of feature counts towards similarity
+var recommendationFeatureWeights = map[string]float64{
+	"genre":  1.0,
+	"tag":    0.8,
+	"studio": 0.6,
+	"staff":  0.4,
+	"format": 0.3,
+	"era":    0.3,
+}
+
+// recommendationStaffRoles are the staff credits that shape a show enough to count as taste
+var recommendationStaffRoles = []string{"director", "original creator", "series composition", "music", "character design"}
+
+// Recommendation is a catalog anime suggested to a user
+type Recommendation struct {
+	Anime       model.Anime `json:"anime"`
+	Score       float64     `json:"score"`
+	Because     []string    `json:"because,omitempty"` // Titles from the user's list that led to this pick
+	Matched     []string    `json:"matched,omitempty"` // Features shared with the user's taste
+	Source      string      `json:"source"`
+	Explanation string      `json:"explanation"`
+}
+
+// TasteFeature is one weighted entry of a user's taste profile
+type TasteFeature struct {
+	Kind   string  `json:"kind"`
+	Value  string  `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// RecommendationResult is the response of the recommendations endpoint
+type RecommendationResult struct {
+	Recommendations []Recommendation `json:"recommendations"`
+	Profile         []TasteFeature   `json:"profile"`
+	ScoredEntries   int              `json:"scored_entries"`
+}
+
+// tasteEntry is a scored list entry with its feature vector and preference weight
+type tasteEntry struct {
+	key      string
+	name     string
+	weight   float64
+	features map[string]float64
+}
+
+// InvalidateRecommendations drops a user's cached recommendations after their list changes
+func InvalidateRecommendations(userID string) {
+	if cache.RedisClient == nil {
+		return
+	}
+	cache.Delete("recommendations:" + userID)
+}
+
+// GetRecommendations ranks unseen catalog anime by similarity to the user's scored entries
+func GetRecommendations(userID string, limit int) (*RecommendationResult, error) {
+	cacheKey := "recommendations:" + userID
+	var cached RecommendationResult
+	if cache.RedisClient != nil {
+		if err := cache.Get(cacheKey, &cached); err == nil && len(cached.Recommendations) >= limit {
+			cached.Recommendations = cached.Recommendations[:limit]
+			return &cached, nil
+		}
+	}
+
+	list, err := GetUserList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := buildTasteEntries(list)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RecommendationResult{
+		Recommendations: []Recommendation{},
+		Profile:         []TasteFeature{},
+		ScoredEntries:   len(entries),
+	}
+	if len(entries) == 0 {
+		// Cold start: nothing to learn from yet, so suggest what's popular
+		popular, err := popularRecommendations(list, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Recommendations = popular
+		return result, nil
+	}
+
+	profile := buildTasteProfile(entries)
+	result.Profile = topTasteFeatures(profile, RECOMMENDATION_PROFILE_SIZE)
+
+	candidates, err := findRecommendationCandidates(profile, list)
+	if err != nil {
+		return nil, err
+	}
+
+	collaborative, err := collaborativeScores(entries)
+	if err != nil {
+		// The similarity table is an enhancement, content matching still works without it
+		log.Printf("Collaborative scores unavailable: %v", err)
+	}
+	candidates = append(candidates, collaborativeCandidates(collaborative, candidates, list)...)
+
+	maxCF := 0.0
+	for _, cf := range collaborative {
+		maxCF = math.Max(maxCF, cf.score)
+	}
+
+	profileNorm := vectorNorm(profile)
+	type scored struct {
+		anime    model.Anime
+		features map[string]float64
+		cf       *collaborativeScore
+		content  float64
+		score    float64
+	}
+	var ranked []scored
+	for _, anime := range candidates {
+		features := animeFeatures(anime)
+		content := math.Max(cosineSimilarity(profile, profileNorm, features), 0)
+
+		score := content
+		cf := lookupCollaborativeScore(collaborative, anime)
+		if cf != nil && maxCF > 0 {
+			score = (1-RECOMMENDATION_CF_WEIGHT)*content + RECOMMENDATION_CF_WEIGHT*cf.score/maxCF
+		}
+		if score <= 0 {
+			continue
+		}
+		// Nudge well-rated titles ahead of equally similar obscure ones
+		score *= 0.85 + 0.015*math.Min(anime.Score, 10)
+		ranked = append(ranked, scored{anime: anime, features: features, cf: cf, content: content, score: score})
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	// Cache a full page so smaller limits can be served from it
+	size := limit
+	if size < 50 {
+		size = 50
+	}
+	if len(ranked) > size {
+		ranked = ranked[:size]
+	}
+
+	for _, candidate := range ranked {
+		anime := candidate.anime
+		anime.Characters = nil
+		anime.Staff = nil
+
+		recommendation := Recommendation{
+			Anime:  anime,
+			Score:  math.Round(candidate.score*1000) / 1000,
+			Source: RECOMMENDATION_SOURCE_CONTENT,
+		}
+		recommendation.Matched = matchedFeatures(profile, candidate.features, 3)
+
+		// Explain with whichever signal contributed most
+		if candidate.cf != nil && RECOMMENDATION_CF_WEIGHT*candidate.cf.score/maxCF > (1-RECOMMENDATION_CF_WEIGHT)*candidate.content {
+			recommendation.Source = RECOMMENDATION_SOURCE_COLLABORATIVE
+			recommendation.Because = candidate.cf.because()
+			recommendation.Explanation = collaborativeExplanation(recommendation.Because)
+		} else {
+			recommendation.Because = explainRecommendation(entries, candidate.features)
+			recommendation.Explanation = recommendationExplanation(recommendation.Because, recommendation.Matched)
+		}
+
+		result.Recommendations = append(result.Recommendations, recommendation)
+	}
+
+	if cache.RedisClient != nil {
+		cache.Set(cacheKey, result, RECOMMENDATION_CACHE_TTL)
+	}
+
+	if len(result.Recommendations) > limit {
+		result.Recommendations = result.Recommendations[:limit]
+	}
+	return result, nil
+}
+
+// buildTasteEntries weights each scored entry by how far its score sits from the user's average,
+// using the catalog copy of the anime for features since list copies can be stale
+func buildTasteEntries(list []model.Anime) ([]tasteEntry, error) {
+	var scored []model.Anime
+	total := 0.0
+	for _, anime := range list {
+		if anime.Score > 0 {
+			scored = append(scored, anime)
+			total += anime.Score
+		}
+	}
+	if len(scored) == 0 {
+		return nil, nil
+	}
+	mean := total / float64(len(scored))
+
+	catalog, err := findCatalogCopies(scored)
+	if err != nil {
+		return nil, err
+	}
+
+	// With nothing to contrast against, everything scored counts as liked
+	uniform := allScoresEqual(scored)
+
+	entries := make([]tasteEntry, 0, len(scored))
+	for _, anime := range scored {
+		source := anime
+		if catalogAnime, ok := catalog[animeKey(anime)]; ok {
+			source = catalogAnime
+		}
+
+		weight := (anime.Score - mean) / 2
+		if uniform {
+			weight = anime.Score / 10
+		}
+
+		entries = append(entries, tasteEntry{
+			key:      animeKey(anime),
+			name:     anime.Name,
+			weight:   weight,
+			features: animeFeatures(source),
+		})
+	}
+	return entries, nil
+}
+
+func allScoresEqual(animes []model.Anime) bool {
+	for _, anime := range animes[1:] {
+		if anime.Score != animes[0].Score {
+			return false
+		}
+	}
+	return true
+}
+
+// findCatalogCopies loads the catalog documents behind a set of list entries, keyed by animeKey
+func findCatalogCopies(list []model.Anime) (map[string]model.Anime, error) {
+	names := []string{}
+	anilistIDs := []int{}
+	for _, anime := range list {
+		names = append(names, anime.Name)
+		if anime.AniListID > 0 {
+			anilistIDs = append(anilistIDs, anime.AniListID)
+		}
+	}
+
+	filter := PublicCatalogFilter(bson.M{
+		"$or": []bson.M{
+			{"name": bson.M{"$in": names}},
+			{"anilist_id": bson.M{"$in": anilistIDs}},
+		},
+	})
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[string]model.Anime, len(animes)*2)
+	for _, anime := range animes {
+		catalog[animeKey(anime)] = anime
+		catalog["name:"+strings.ToLower(strings.TrimSpace(anime.Name))] = anime
+	}
+	return catalog, nil
+}
+
+// animeFeatures turns an anime into a sparse feature vector. Each kind of feature is
+// scaled so an anime with many genres doesn't outweigh one with a few.
+func animeFeatures(anime model.Anime) map[string]float64 {
+	groups := map[string][]string{
+		"genre":  anime.Genre,
+		"tag":    anime.Tags,
+		"studio": anime.Information.Studios,
+	}
+
+	for _, member := range anime.Staff {
+		role := strings.ToLower(member.Role)
+		for _, key := range recommendationStaffRoles {
+			if strings.Contains(role, key) {
+				groups["staff"] = append(groups["staff"], member.Name)
+				break
+			}
+		}
+	}
+	if anime.Type != "" {
+		groups["format"] = []string{string(anime.Type)}
+	}
+	if anime.Year > 0 {
+		groups["era"] = []string{strconv.Itoa(anime.Year/5*5) + "s"}
+	}
+
+	features := make(map[string]float64)
+	for kind, values := range groups {
+		seen := make(map[string]bool)
+		var unique []string
+		for _, value := range values {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value != "" && !seen[value] {
+				seen[value] = true
+				unique = append(unique, value)
+			}
+		}
+		if len(unique) == 0 {
+			continue
+		}
+
+		weight := recommendationFeatureWeights[kind] / math.Sqrt(float64(len(unique)))
+		for _, value := range unique {
+			features[kind+":"+value] = weight
+		}
+	}
+	return features
+}
+
+// buildTasteProfile sums the weighted feature vectors of the user's scored entries
+func buildTasteProfile(entries []tasteEntry) map[string]float64 {
+	profile := make(map[string]float64)
+	totalWeight := 0.0
+	for _, entry := range entries {
+		totalWeight += math.Abs(entry.weight)
+		for feature, value := range entry.features {
+			profile[feature] += entry.weight * value
+		}
+	}
+	if totalWeight > 0 {
+		for feature := range profile {
+			profile[feature] /= totalWeight
+		}
+	}
+	return profile
+}
+
+// topTasteFeatures returns the strongest positive features of a profile
+func topTasteFeatures(profile map[string]float64, n int) []TasteFeature {
+	features := []TasteFeature{}
+	for key, weight := range profile {
+		if weight <= 0 {
+			continue
+		}
+		kind, value, _ := strings.Cut(key, ":")
+		features = append(features, TasteFeature{Kind: kind, Value: value, Weight: math.Round(weight*1000) / 1000})
+	}
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].Weight > features[j].Weight
+	})
+	if len(features) > n {
+		features = features[:n]
+	}
+	return features
+}
+
+// findRecommendationCandidates loads catalog anime sharing a strong feature with the profile,
+// leaving out anything already on the user's list
+func findRecommendationCandidates(profile map[string]float64, list []model.Anime) ([]model.Anime, error) {
+	fields := map[string]string{"genre": "genre", "tag": "tags", "studio": "information.studios"}
+	values := make(map[string][]string)
+	for _, feature := range topTasteFeatures(profile, 30) {
+		if field, ok := fields[feature.Kind]; ok {
+			values[field] = append(values[field], feature.Value)
+		}
+	}
+
+	var or []bson.M
+	for field, vals := range values {
+		var patterns []interface{}
+		for _, value := range vals {
+			// Features are lower-cased, catalog values aren't
+			patterns = append(patterns, ExactText(value))
+		}
+		or = append(or, bson.M{field: bson.M{"$in": patterns}})
+	}
+	if len(or) == 0 {
+		return nil, nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "score", Value: -1}}).
+		SetLimit(RECOMMENDATION_CANDIDATE_POOL).
+		SetProjection(bson.M{"characters": 0, "related": 0, "themes": 0})
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(bson.M{"$or": or}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return nil, err
+	}
+
+	return excludeListed(animes, list), nil
+}
+
+// animeKeys returns every key an anime may be stored under in list copies and the similarity table
+func animeKeys(anime model.Anime) []string {
+	nameKey := "name:" + strings.ToLower(strings.TrimSpace(anime.Name))
+	if key := animeKey(anime); key != nameKey {
+		return []string{key, nameKey}
+	}
+	return []string{nameKey}
+}
+
+// excludeListed drops catalog anime that are already on the user's list
+func excludeListed(animes, list []model.Anime) []model.Anime {
+	onList := make(map[string]bool, len(list)*2)
+	for _, anime := range list {
+		for _, key := range animeKeys(anime) {
+			onList[key] = true
+		}
+	}
+
+	result := make([]model.Anime, 0, len(animes))
+	for _, anime := range animes {
+		listed := false
+		for _, key := range animeKeys(anime) {
+			listed = listed || onList[key]
+		}
+		if !listed {
+			result = append(result, anime)
+		}
+	}
+	return result
+}
+
+// collaborativeScore accumulates item-item evidence for one candidate
+type collaborativeScore struct {
+	score         float64
+	contributions map[string]float64 // Liked title name -> contribution
+}
+
+// because names the two liked titles that contributed most
+func (c *collaborativeScore) because() []string {
+	names := make([]string, 0, len(c.contributions))
+	for name := range c.contributions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return c.contributions[names[i]] > c.contributions[names[j]]
+	})
+	if len(names) > 2 {
+		names = names[:2]
+	}
+	return names
+}
+
+// collaborativeScores sums the neighbors of every liked entry, weighted by how much it was liked
+func collaborativeScores(entries []tasteEntry) (map[string]*collaborativeScore, error) {
+	var keys []string
+	for _, entry := range entries {
+		if entry.weight > 0 {
+			keys = append(keys, entry.key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	stored, err := getItemNeighbors(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]*collaborativeScore)
+	for _, entry := range entries {
+		if entry.weight <= 0 {
+			continue
+		}
+		for _, neighbor := range stored[entry.key].Neighbors {
+			cf, ok := scores[neighbor.Key]
+			if !ok {
+				cf = &collaborativeScore{contributions: make(map[string]float64)}
+				scores[neighbor.Key] = cf
+			}
+			contribution := entry.weight * neighbor.Score
+			cf.score += contribution
+			cf.contributions[entry.name] += contribution
+		}
+	}
+	return scores, nil
+}
+
+func lookupCollaborativeScore(scores map[string]*collaborativeScore, anime model.Anime) *collaborativeScore {
+	for _, key := range animeKeys(anime) {
+		if cf, ok := scores[key]; ok {
+			return cf
+		}
+	}
+	return nil
+}
+
+// collaborativeCandidates loads the strongest collaborative picks that content matching didn't find
+func collaborativeCandidates(scores map[string]*collaborativeScore, candidates, list []model.Anime) []model.Anime {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool, len(candidates)*2)
+	for _, anime := range candidates {
+		for _, key := range animeKeys(anime) {
+			known[key] = true
+		}
+	}
+
+	var keys []string
+	for key, cf := range scores {
+		if !known[key] && cf.score > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return scores[keys[i]].score > scores[keys[j]].score
+	})
+	if len(keys) > RECOMMENDATION_CF_CANDIDATES {
+		keys = keys[:RECOMMENDATION_CF_CANDIDATES]
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	catalog, err := findCatalogByKeys(keys)
+	if err != nil {
+		log.Printf("Failed to load collaborative candidates: %v", err)
+		return nil
+	}
+
+	var extra []model.Anime
+	for _, key := range keys {
+		anime, ok := catalog[key]
+		if !ok || known[animeKey(anime)] {
+			continue
+		}
+		for _, k := range animeKeys(anime) {
+			known[k] = true
+		}
+		extra = append(extra, anime)
+	}
+	return excludeListed(extra, list)
+}
+
+// popularRecommendations suggests the catalog's most popular titles to users without scores yet
+func popularRecommendations(list []model.Anime, limit int) ([]Recommendation, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "statistics.members", Value: -1}, {Key: "score", Value: -1}}).
+		SetLimit(int64(limit + len(list))).
+		SetProjection(bson.M{"characters": 0, "related": 0, "themes": 0, "staff": 0})
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(nil), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return nil, err
+	}
+
+	recommendations := []Recommendation{}
+	for _, anime := range excludeListed(animes, list) {
+		recommendations = append(recommendations, Recommendation{
+			Anime:       anime,
+			Score:       anime.Score,
+			Source:      RECOMMENDATION_SOURCE_POPULAR,
+			Explanation: "Popular with anime fans",
+		})
+		if len(recommendations) == limit {
+			break
+		}
+	}
+	return recommendations, nil
+}
+
+func vectorNorm(vector map[string]float64) float64 {
+	sum := 0.0
+	for _, value := range vector {
+		sum += value * value
+	}
+	return math.Sqrt(sum)
+}
+
+// cosineSimilarity compares a profile (with its precomputed norm) to a feature vector
+func cosineSimilarity(profile map[string]float64, profileNorm float64, features map[string]float64) float64 {
+	featureNorm := vectorNorm(features)
+	if profileNorm == 0 || featureNorm == 0 {
+		return 0
+	}
+
+	dot := 0.0
+	for feature, value := range features {
+		dot += profile[feature] * value
+	}
+	return dot / (profileNorm * featureNorm)
+}
+
+// explainRecommendation picks the two liked titles that overlap most with a candidate
+func explainRecommendation(entries []tasteEntry, features map[string]float64) []string {
+	type contribution struct {
+		name  string
+		value float64
+	}
+	var contributions []contribution
+	for _, entry := range entries {
+		if entry.weight <= 0 {
+			continue
+		}
+		overlap := 0.0
+		for feature, value := range features {
+			overlap += entry.features[feature] * value
+		}
+		if overlap > 0 {
+			contributions = append(contributions, contribution{name: entry.name, value: overlap * entry.weight})
+		}
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		return contributions[i].value > contributions[j].value
+	})
+
+	var names []string
+	for _, c := range contributions {
+		names = append(names, c.name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	return names
+}
+
+// matchedFeatures lists the candidate's features that contribute most to the match
+func matchedFeatures(profile, features map[string]float64, n int) []string {
+	type match struct {
+		value string
+		score float64
+	}
+	var matches []match
+	for feature, value := range features {
+		if contribution := profile[feature] * value; contribution > 0 {
+			_, name, _ := strings.Cut(feature, ":")
+			matches = append(matches, match{value: name, score: contribution})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.value)
+		if len(names) == n {
+			break
+		}
+	}
+	return names
+}
+
+// collaborativeExplanation renders the reason for a pick driven by other users' lists
+func collaborativeExplanation(because []string) string {
+	switch len(because) {
+	case 0:
+		return "Liked by users with similar taste"
+	case 1:
+		return fmt.Sprintf("Fans of %s also liked this", because[0])
+	default:
+		return fmt.Sprintf("Fans of %s and %s also liked this", because[0], because[1])
+	}
+}
+
+// recommendationExplanation renders the human-readable reason for a recommendation
+func recommendationExplanation(because, matched []string) string {
+	switch {
+	case len(because) >= 2:
+		return fmt.Sprintf("Because you liked %s and %s", because[0], because[1])
+	case len(because) == 1:
+		return fmt.Sprintf("Because you liked %s", because[0])
+	case len(matched) > 0:
+		return "Matches your taste for " + strings.Join(matched, ", ")
+	default:
+		return "Recommended for you"
+	}
+}
@@ -22,7 +22,7 @@ func GetAnimesWithRedisCache(limit int, offset int) ([]models.Anime, error) {
 	}
 
 	// Get from database
-	cursor, err := config.Collection.Find(context.Background(), bson.M{}, &options.FindOptions{
+	cursor, err := config.Collection.Find(context.Background(), PublicCatalogFilter(nil), &options.FindOptions{
 		Limit: &[]int64{int64(limit)}[0],
 		Skip:  &[]int64{int64(offset)}[0],
 		Sort:  bson.D{{"score", -1}},
@@ -43,34 +43,37 @@ func GetAnimesWithRedisCache(limit int, offset int) ([]models.Anime, error) {
 	return animes, nil
 }
 
-func GetTrendingWithRedisCache() ([]models.Anime, error) {
-	cacheKey := "trending_animes_fast"
-	
+// trendingPage is a cached page of the fast trending list
+type trendingPage struct {
+	Animes []models.Anime `json:"animes"`
+	Next   string         `json:"next"`
+}
+
+// GetTrendingWithRedisCache returns a page of well rated anime. Pages reached by offset are
+// cached; pages after a cursor are not, as every cursor is different.
+func GetTrendingWithRedisCache(page PageRequest) ([]models.Anime, string, error) {
+	cacheKey := fmt.Sprintf("trending_animes_fast:%s:%d:%d", page.Sort, page.Limit, page.Offset)
+
 	// Try Redis first
-	var cached []models.Anime
-	if err := cache.Get(cacheKey, &cached); err == nil {
-		return cached, nil
+	var cached trendingPage
+	if page.After == nil {
+		if err := cache.Get(cacheKey, &cached); err == nil {
+			return cached.Animes, cached.Next, nil
+		}
 	}
 
 	// Get top rated from database
-	cursor, err := config.Collection.Find(context.Background(), bson.M{
-		"score": bson.M{"$gte": 7.0},
-	}, &options.FindOptions{
-		Limit: &[]int64{50}[0],
-		Sort:  bson.D{{"score", -1}, {"year", -1}},
-	})
+	animes := []models.Anime{}
+	filter := PublicCatalogFilter(bson.M{"score": bson.M{"$gte": 7.0}})
+	next, err := FindPageInto(context.Background(), config.Collection, filter, page, searchProjection, &animes)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer cursor.Close(context.Background())
 
-	var animes []models.Anime
-	if err := cursor.All(context.Background(), &animes); err != nil {
-		return nil, err
+	// Cache for 5 minutes
+	if page.After == nil {
+		cache.Set(cacheKey, trendingPage{Animes: animes, Next: next}, 5*time.Minute)
 	}
 
-	// Cache for 5 minutes
-	cache.Set(cacheKey, animes, 5*time.Minute)
-	
-	return animes, nil
-}
\ No newline at end of file
+	return animes, next, nil
+}
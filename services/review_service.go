@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	REVIEW_MIN_LENGTH = 200
+	REVIEW_MAX_LENGTH = 20000
+	REVIEW_HISTORY    = 20   // Earlier versions kept per review
+	WILSON_Z          = 1.96 // 95% confidence
+)
+
+var reviewCollection *mongo.Collection
+var reviewVoteCollection *mongo.Collection
+
+// ReviewInput represents the user-editable fields of a review
+type ReviewInput struct {
+	AnimeID string              `json:"anime_id,omitempty"`
+	Body    string              `json:"body"`
+	Ratings model.ReviewRatings `json:"ratings"`
+	Spoiler bool                `json:"spoiler"`
+}
+
+// InitReviewCollections initializes the review and review vote collections
+func InitReviewCollections() {
+	if config.DB == nil {
+		return
+	}
+
+	ctx := context.Background()
+	reviewCollection = config.GetCollection(config.DB, "reviews")
+	reviewCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "anime_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "anime_id", Value: 1}, {Key: "wilson_score", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+
+	reviewVoteCollection = config.GetCollection(config.DB, "review_votes")
+	reviewVoteCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "review_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
+
+func getReviewCollections() (*mongo.Collection, *mongo.Collection, error) {
+	if reviewCollection == nil || reviewVoteCollection == nil {
+		InitReviewCollections()
+	}
+	if reviewCollection == nil || reviewVoteCollection == nil {
+		return nil, nil, fmt.Errorf("review collections not initialized")
+	}
+	return reviewCollection, reviewVoteCollection, nil
+}
+
+func validateReviewInput(input ReviewInput) error {
+	if len(input.Body) < REVIEW_MIN_LENGTH || len(input.Body) > REVIEW_MAX_LENGTH {
+		return fmt.Errorf("review must be between %d and %d characters", REVIEW_MIN_LENGTH, REVIEW_MAX_LENGTH)
+	}
+
+	for _, rating := range []int{input.Ratings.Story, input.Ratings.Animation, input.Ratings.Sound, input.Ratings.Characters} {
+		if rating < 1 || rating > 10 {
+			return fmt.Errorf("ratings must be between 1 and 10")
+		}
+	}
+	return nil
+}
+
+func overallRating(ratings model.ReviewRatings) float64 {
+	sum := ratings.Story + ratings.Animation + ratings.Sound + ratings.Characters
+	return math.Round(float64(sum)/4*10) / 10
+}
+
+// wilsonScore returns the lower bound of the Wilson score interval for the helpful ratio
+func wilsonScore(positive, negative int) float64 {
+	n := float64(positive + negative)
+	if n == 0 {
+		return 0
+	}
+
+	p := float64(positive) / n
+	z2 := WILSON_Z * WILSON_Z
+	return (p + z2/(2*n) - WILSON_Z*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// CreateReview adds a review by a user for a catalog anime
+func CreateReview(userID, userName string, input ReviewInput) (*model.Review, error) {
+	reviews, _, err := getReviewCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateReviewInput(input); err != nil {
+		return nil, err
+	}
+
+	animeID, err := primitive.ObjectIDFromHex(input.AnimeID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anime id")
+	}
+
+	var anime model.Anime
+	if err := config.Collection.FindOne(context.Background(), PublicCatalogFilter(bson.M{"_id": animeID})).Decode(&anime); err != nil {
+		return nil, fmt.Errorf("anime not found")
+	}
+
+	now := time.Now()
+	review := model.Review{
+		AnimeID:   animeID,
+		AnimeName: anime.Name,
+		UserID:    userID,
+		UserName:  userName,
+		Body:      input.Body,
+		Ratings:   input.Ratings,
+		Overall:   overallRating(input.Ratings),
+		Spoiler:   input.Spoiler,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := reviews.InsertOne(context.Background(), review)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("you have already reviewed this anime")
+		}
+		return nil, err
+	}
+
+	review.ID = result.InsertedID.(primitive.ObjectID)
+	FlagContent(model.ReportReview, review.ID.Hex(), review.Body)
+	return &review, nil
+}
+
+// GetReview returns a single review including its edit history
+func GetReview(reviewID string) (*model.Review, error) {
+	reviews, _, err := getReviewCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	var review model.Review
+	if err := reviews.FindOne(context.Background(), bson.M{"_id": objID}).Decode(&review); err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// UpdateReview edits a user's own review, keeping the previous version in its history. Only
+// the last REVIEW_HISTORY versions are kept.
+func UpdateReview(userID, reviewID string, input ReviewInput) (*model.Review, error) {
+	reviews, _, err := getReviewCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateReviewInput(input); err != nil {
+		return nil, err
+	}
+
+	existing, err := GetReview(reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("review not found")
+	}
+	if existing.UserID != userID {
+		return nil, fmt.Errorf("you can only edit your own reviews")
+	}
+
+	now := time.Now()
+	revision := model.ReviewRevision{
+		Body:     existing.Body,
+		Ratings:  existing.Ratings,
+		Spoiler:  existing.Spoiler,
+		EditedAt: now,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"body":       input.Body,
+			"ratings":    input.Ratings,
+			"overall":    overallRating(input.Ratings),
+			"spoiler":    input.Spoiler,
+			"updated_at": now,
+		},
+		// Only the latest versions are kept, so a review edited over and over stays small
+		"$push": bson.M{"history": bson.M{
+			"$each":  []model.ReviewRevision{revision},
+			"$slice": -REVIEW_HISTORY,
+		}},
+	}
+
+	if _, err := reviews.UpdateOne(context.Background(), bson.M{"_id": existing.ID, "user_id": userID}, update); err != nil {
+		return nil, err
+	}
+
+	FlagContent(model.ReportReview, reviewID, input.Body)
+
+	return GetReview(reviewID)
+}
+
+// DeleteReview removes a user's own review along with its votes
+func DeleteReview(userID, reviewID string) error {
+	reviews, votes, err := getReviewCollections()
+	if err != nil {
+		return err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(reviewID)
+	if err != nil {
+		return err
+	}
+
+	result, err := reviews.DeleteOne(context.Background(), bson.M{"_id": objID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("review not found")
+	}
+
+	_, err = votes.DeleteMany(context.Background(), bson.M{"review_id": objID})
+	return err
+}
+
+// VoteReview records a helpful/not-helpful vote and re-ranks the review
+func VoteReview(userID, reviewID string, helpful bool) (*model.Review, error) {
+	reviews, votes, err := getReviewCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	review, err := GetReview(reviewID)
+	if err != nil || review.Hidden {
+		return nil, fmt.Errorf("review not found")
+	}
+	if review.UserID == userID {
+		return nil, fmt.Errorf("you cannot vote on your own review")
+	}
+
+	ctx := context.Background()
+	_, err = votes.UpdateOne(ctx,
+		bson.M{"review_id": review.ID, "user_id": userID},
+		bson.M{
+			"$set":         bson.M{"helpful": helpful},
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recount from the vote log so changed votes are handled correctly
+	helpfulCount, err := votes.CountDocuments(ctx, bson.M{"review_id": review.ID, "helpful": true})
+	if err != nil {
+		return nil, err
+	}
+	notHelpfulCount, err := votes.CountDocuments(ctx, bson.M{"review_id": review.ID, "helpful": false})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = reviews.UpdateOne(ctx, bson.M{"_id": review.ID}, bson.M{"$set": bson.M{
+		"helpful":      helpfulCount,
+		"not_helpful":  notHelpfulCount,
+		"wilson_score": wilsonScore(int(helpfulCount), int(notHelpfulCount)),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetReview(reviewID)
+}
+
+// GetReviewsForAnime lists reviews of an anime ranked by Wilson score
+func GetReviewsForAnime(animeID string, page PageRequest) ([]model.Review, string, error) {
+	objID, err := primitive.ObjectIDFromHex(animeID)
+	if err != nil {
+		return nil, "", err
+	}
+	return findReviews(bson.M{"anime_id": objID}, page)
+}
+
+// GetReviewsByUser lists a user's reviews, newest first
+func GetReviewsByUser(userID string, page PageRequest) ([]model.Review, string, error) {
+	return findReviews(bson.M{"user_id": userID}, page)
+}
+
+func findReviews(filter bson.M, page PageRequest) ([]model.Review, string, error) {
+	reviews, _, err := getReviewCollections()
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter["hidden"] = bson.M{"$ne": true}
+	results := []model.Review{}
+	next, err := FindPageInto(context.Background(), reviews, filter, page, bson.M{"history": 0}, &results)
+	if err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
+}
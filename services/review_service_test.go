@@ -0,0 +1,93 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	model "animeverse/models"
+)
+
+func TestOverallRating(t *testing.T) {
+	tests := []struct {
+		ratings model.ReviewRatings
+		want    float64
+	}{
+		{model.ReviewRatings{Story: 10, Animation: 10, Sound: 10, Characters: 10}, 10},
+		{model.ReviewRatings{Story: 8, Animation: 7, Sound: 9, Characters: 6}, 7.5},
+		{model.ReviewRatings{Story: 7, Animation: 8, Sound: 8, Characters: 8}, 7.8}, // 7.75, rounded to one decimal
+		{model.ReviewRatings{Story: 1, Animation: 1, Sound: 1, Characters: 2}, 1.3},
+		{model.ReviewRatings{Story: 1, Animation: 1, Sound: 1, Characters: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		if got := overallRating(tt.ratings); got != tt.want {
+			t.Errorf("overallRating(%+v) = %v, want %v", tt.ratings, got, tt.want)
+		}
+	}
+}
+
+func TestWilsonScore(t *testing.T) {
+	tests := []struct {
+		positive, negative int
+		want               float64
+	}{
+		{0, 0, 0},
+		{0, 10, 0},
+		{1, 0, 0.2065},
+		{10, 0, 0.7225},
+		{5, 5, 0.2366},
+		{100, 20, 0.7565},
+	}
+
+	for _, tt := range tests {
+		if got := wilsonScore(tt.positive, tt.negative); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("wilsonScore(%d, %d) = %.4f, want %.4f", tt.positive, tt.negative, got, tt.want)
+		}
+	}
+}
+
+func TestWilsonScoreOrdersByConfidence(t *testing.T) {
+	// Each pair is ranked first over second
+	tests := []struct {
+		first, second [2]int
+	}{
+		{[2]int{10, 0}, [2]int{1, 0}},    // The same ratio with more votes
+		{[2]int{100, 20}, [2]int{10, 0}}, // Many votes, mostly helpful, over a few all helpful
+		{[2]int{10, 1}, [2]int{10, 5}},   // Fewer unhelpful votes
+		{[2]int{1, 0}, [2]int{0, 0}},     // Any helpful vote over none
+		{[2]int{5, 5}, [2]int{1, 0}},     // Ten split votes over a single helpful one
+	}
+
+	for _, tt := range tests {
+		first := wilsonScore(tt.first[0], tt.first[1])
+		second := wilsonScore(tt.second[0], tt.second[1])
+		if first < second {
+			t.Errorf("wilsonScore%v = %.4f ranks below wilsonScore%v = %.4f", tt.first, first, tt.second, second)
+		}
+	}
+}
+
+func TestValidateReviewInput(t *testing.T) {
+	body := strings.Repeat("a", REVIEW_MIN_LENGTH)
+	ratings := model.ReviewRatings{Story: 8, Animation: 7, Sound: 9, Characters: 6}
+
+	tests := []struct {
+		name  string
+		input ReviewInput
+		valid bool
+	}{
+		{"valid", ReviewInput{Body: body, Ratings: ratings}, true},
+		{"longest", ReviewInput{Body: strings.Repeat("a", REVIEW_MAX_LENGTH), Ratings: ratings}, true},
+		{"too short", ReviewInput{Body: body[1:], Ratings: ratings}, false},
+		{"too long", ReviewInput{Body: strings.Repeat("a", REVIEW_MAX_LENGTH+1), Ratings: ratings}, false},
+		{"missing rating", ReviewInput{Body: body, Ratings: model.ReviewRatings{Story: 8, Animation: 7, Sound: 9}}, false},
+		{"rating above 10", ReviewInput{Body: body, Ratings: model.ReviewRatings{Story: 11, Animation: 7, Sound: 9, Characters: 6}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := validateReviewInput(tt.input); (err == nil) != tt.valid {
+			t.Errorf("%s: validateReviewInput error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
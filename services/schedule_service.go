@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// The stored window spans every timezone's "today" plus the coming week
+	SCHEDULE_LOOKBACK  = 2 * 24 * time.Hour
+	SCHEDULE_LOOKAHEAD = 9 * 24 * time.Hour
+	SCHEDULE_RETENTION = 30 * 24 * time.Hour
+	SCHEDULE_MAX_PAGES = 20
+)
+
+var airingScheduleCollection *mongo.Collection
+
+// ErrUnknownScheduleDay is returned when a schedule is requested for a day that is not a weekday name
+var ErrUnknownScheduleDay = fmt.Errorf("unknown day, expected a weekday name or \"today\"")
+
+// ScheduledEpisode is a schedule entry as seen from the requester's timezone
+type ScheduledEpisode struct {
+	model.AiringScheduleEntry `bson:",inline"`
+	LocalTime                 string `json:"local_time"`        // HH:MM in the requested timezone
+	CountdownSeconds          int64  `json:"countdown_seconds"` // Negative once the episode has aired
+	Aired                     bool   `json:"aired"`
+}
+
+// ScheduleDay groups the episodes airing on one local calendar day
+type ScheduleDay struct {
+	Date     string             `json:"date"` // YYYY-MM-DD
+	Weekday  string             `json:"weekday"`
+	Episodes []ScheduledEpisode `json:"episodes"`
+}
+
+// WeeklySchedule is the airing schedule for the seven days starting today
+type WeeklySchedule struct {
+	Timezone string            `json:"timezone"`
+	Days     []ScheduleDay     `json:"days"`
+	Next     *ScheduledEpisode `json:"next,omitempty"` // The soonest episode still to air
+}
+
+// ScheduleQuery selects which part of the schedule to return
+type ScheduleQuery struct {
+	Day      string         // Weekday name or "today"; empty for the whole week
+	Location *time.Location // Defaults to UTC
+	UserID   string         // When set, only anime on this user's list
+}
+
+// InitAiringScheduleCollection initializes the airing_schedule collection
+func InitAiringScheduleCollection() {
+	if config.DB == nil {
+		return
+	}
+
+	airingScheduleCollection = config.GetCollection(config.DB, "airing_schedule")
+	airingScheduleCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "airing_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(SCHEDULE_RETENTION.Seconds())),
+		},
+		{Keys: bson.D{{Key: "anilist_id", Value: 1}}},
+	})
+}
+
+func getAiringScheduleCollection() (*mongo.Collection, error) {
+	if airingScheduleCollection == nil {
+		InitAiringScheduleCollection()
+	}
+	if airingScheduleCollection == nil {
+		return nil, fmt.Errorf("airing schedule collection not initialized")
+	}
+	return airingScheduleCollection, nil
+}
+
+// aniListAiringPage is one page of AniList airing schedules
+type aniListAiringPage struct {
+	Data struct {
+		Page struct {
+			PageInfo struct {
+				HasNextPage bool `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			AiringSchedules []struct {
+				ID       int   `json:"id"`
+				Episode  int   `json:"episode"`
+				AiringAt int64 `json:"airingAt"`
+				Media    struct {
+					ID    int `json:"id"`
+					Title struct {
+						Romaji  string `json:"romaji"`
+						English string `json:"english"`
+					} `json:"title"`
+					Format     string `json:"format"`
+					Episodes   int    `json:"episodes"`
+					IsAdult    bool   `json:"isAdult"`
+					CoverImage struct {
+						Large string `json:"large"`
+					} `json:"coverImage"`
+				} `json:"media"`
+			} `json:"airingSchedules"`
+		} `json:"Page"`
+	} `json:"data"`
+}
+
+const aniListAiringQuery = `
+query ($from: Int, $to: Int, $page: Int) {
+	Page(page: $page, perPage: 50) {
+		pageInfo {
+			hasNextPage
+		}
+		airingSchedules(airingAt_greater: $from, airingAt_lesser: $to, sort: TIME) {
+			id
+			episode
+			airingAt
+			media {
+				id
+				title {
+					romaji
+					english
+				}
+				format
+				episodes
+				isAdult
+				coverImage {
+					large
+				}
+			}
+		}
+	}
+}`
+
+// StartAiringScheduleScheduler refreshes the airing schedule at startup and then every interval,
+// so the schedule stays current without an admin triggering a season update
+func StartAiringScheduleScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := RefreshAiringSchedule(); err != nil {
+			log.Printf("Airing schedule refresh failed: %v", err)
+		} else {
+			log.Printf("Airing schedule refreshed with %d entries", count)
+		}
+		<-ticker.C
+	}
+}
+
+// RefreshAiringSchedule replaces the stored schedule for the current window with AniList's.
+// Entries AniList no longer lists in the window (delays, cancellations) are removed.
+func RefreshAiringSchedule() (int, error) {
+	collection, err := getAiringScheduleCollection()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	from, to := now.Add(-SCHEDULE_LOOKBACK), now.Add(SCHEDULE_LOOKAHEAD)
+
+	var entries []model.AiringScheduleEntry
+	complete := false
+	for page := 1; page <= SCHEDULE_MAX_PAGES; page++ {
+		result, err := fetchAiringPage(from, to, page)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, airing := range result.Data.Page.AiringSchedules {
+			if airing.Media.IsAdult {
+				continue
+			}
+			title := airing.Media.Title.English
+			if title == "" {
+				title = airing.Media.Title.Romaji
+			}
+			entries = append(entries, model.AiringScheduleEntry{
+				ID:            airing.ID,
+				AniListID:     airing.Media.ID,
+				Title:         title,
+				ImageUrl:      airing.Media.CoverImage.Large,
+				Format:        airing.Media.Format,
+				Episode:       airing.Episode,
+				TotalEpisodes: airing.Media.Episodes,
+				AiringAt:      time.Unix(airing.AiringAt, 0).UTC(),
+				UpdatedAt:     now,
+			})
+		}
+
+		if !result.Data.Page.PageInfo.HasNextPage {
+			complete = true
+			break
+		}
+	}
+
+	if err := linkScheduleToCatalog(entries); err != nil {
+		log.Printf("Failed to link schedule to catalog: %v", err)
+	}
+
+	ctx := context.Background()
+	ids := make([]int, 0, len(entries))
+	if len(entries) > 0 {
+		writes := make([]mongo.WriteModel, 0, len(entries))
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": entry.ID}).
+				SetReplacement(entry).
+				SetUpsert(true))
+		}
+		if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return 0, err
+		}
+	}
+
+	// Only prune when the whole window was fetched, otherwise later pages would be lost
+	if complete {
+		collection.DeleteMany(ctx, bson.M{
+			"airing_at": bson.M{"$gt": from, "$lt": to},
+			"_id":       bson.M{"$nin": ids},
+		})
+	}
+
+	log.Printf("Stored %d scheduled episodes", len(entries))
+	return len(entries), nil
+}
+
+func fetchAiringPage(from, to time.Time, page int) (*aniListAiringPage, error) {
+	requestBody := map[string]interface{}{
+		"query": aniListAiringQuery,
+		"variables": map[string]interface{}{
+			"from": from.Unix(),
+			"to":   to.Unix(),
+			"page": page,
+		},
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AniList returned status %d", resp.StatusCode)
+	}
+
+	var result aniListAiringPage
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// linkScheduleToCatalog sets the catalog anime ID of entries whose anime has been imported
+func linkScheduleToCatalog(entries []model.AiringScheduleEntry) error {
+	anilistIDs := []int{}
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if !seen[entry.AniListID] {
+			seen[entry.AniListID] = true
+			anilistIDs = append(anilistIDs, entry.AniListID)
+		}
+	}
+	if len(anilistIDs) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx,
+		PublicCatalogFilter(bson.M{"anilist_id": bson.M{"$in": anilistIDs}}),
+		options.Find().SetProjection(bson.M{"anilist_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	catalogIDs := make(map[int]primitive.ObjectID)
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if cur.Decode(&anime) == nil {
+			catalogIDs[anime.AniListID] = anime.ID
+		}
+	}
+
+	for i := range entries {
+		if id, ok := catalogIDs[entries[i].AniListID]; ok {
+			entries[i].AnimeID = &id
+		}
+	}
+	return cur.Err()
+}
+
+// GetWeeklySchedule returns the episodes airing over the seven local days starting today
+func GetWeeklySchedule(query ScheduleQuery) (*WeeklySchedule, error) {
+	collection, err := getAiringScheduleCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	loc := query.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := time.Now()
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	start, days := today, 7
+	if query.Day != "" {
+		offset, err := scheduleDayOffset(query.Day, local.Weekday())
+		if err != nil {
+			return nil, err
+		}
+		start, days = today.AddDate(0, 0, offset), 1
+	}
+	end := start.AddDate(0, 0, days)
+
+	var onList map[string]bool
+	if query.UserID != "" {
+		list, err := GetUserList(query.UserID)
+		if err != nil {
+			return nil, err
+		}
+		onList = make(map[string]bool, len(list)*2)
+		for _, anime := range list {
+			for _, key := range animeKeys(anime) {
+				onList[key] = true
+			}
+		}
+	}
+
+	ctx := context.Background()
+	cur, err := collection.Find(ctx,
+		bson.M{"airing_at": bson.M{"$gte": start.UTC(), "$lt": end.UTC()}},
+		options.Find().SetSort(bson.D{{Key: "airing_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	schedule := &WeeklySchedule{Timezone: loc.String(), Days: make([]ScheduleDay, days)}
+	dayIndex := make(map[string]int, days)
+	for i := range schedule.Days {
+		date := start.AddDate(0, 0, i)
+		dayIndex[date.Format("2006-01-02")] = i
+		schedule.Days[i] = ScheduleDay{
+			Date:     date.Format("2006-01-02"),
+			Weekday:  date.Weekday().String(),
+			Episodes: []ScheduledEpisode{},
+		}
+	}
+
+	for cur.Next(ctx) {
+		var entry model.AiringScheduleEntry
+		if err := cur.Decode(&entry); err != nil {
+			continue
+		}
+		if onList != nil && !onList[fmt.Sprintf("anilist:%d", entry.AniListID)] &&
+			!onList["name:"+strings.ToLower(strings.TrimSpace(entry.Title))] {
+			continue
+		}
+
+		airingAt := entry.AiringAt.In(loc)
+		episode := ScheduledEpisode{
+			AiringScheduleEntry: entry,
+			LocalTime:           airingAt.Format("15:04"),
+			CountdownSeconds:    int64(entry.AiringAt.Sub(now).Seconds()),
+			Aired:               !entry.AiringAt.After(now),
+		}
+
+		day, ok := dayIndex[airingAt.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		schedule.Days[day].Episodes = append(schedule.Days[day].Episodes, episode)
+
+		if schedule.Next == nil && !episode.Aired {
+			next := episode
+			schedule.Next = &next
+		}
+	}
+	return schedule, cur.Err()
+}
+
+// scheduleDayOffset returns how many days after today the named weekday falls
+func scheduleDayOffset(day string, today time.Weekday) (int, error) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	if day == "today" {
+		return 0, nil
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.ToLower(weekday.String()) == day {
+			return (int(weekday) - int(today) + 7) % 7, nil
+		}
+	}
+	return 0, ErrUnknownScheduleDay
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SEARCH_SOURCE_SEARCH = "search"
+	SEARCH_SOURCE_FILTER = "filter"
+	SEARCH_SOURCE_FAST   = "fast"
+
+	SEARCH_LOG_RETENTION       = 90 * 24 * time.Hour
+	SEARCH_CLICK_WINDOW        = time.Hour // How long after a search a click on its results still counts
+	SEARCH_TEXT_MAX_SIZE       = 200
+	SEARCH_RESULT_ID_MAX_SIZE  = 64
+	SEARCH_REPORT_MIN_SEARCHES = 5   // Searches a query needs before its click-through is judged
+	SEARCH_LOW_CLICK_THROUGH   = 0.1 // Share of searches with a click below which click-through is low
+)
+
+var searchLogCollection *mongo.Collection
+
+// SearchQueryStats sums up the logged searches of one normalized query
+type SearchQueryStats struct {
+	Query        string     `json:"query" bson:"_id"`
+	Text         string     `json:"text" bson:"text"` // The latest spelling searched
+	Searches     int64      `json:"searches" bson:"searches"`
+	Clicks       int64      `json:"clicks" bson:"clicks"`
+	ClickThrough float64    `json:"click_through" bson:"click_through"`
+	ZeroResults  int64      `json:"zero_results" bson:"zero_results"` // Searches that found nothing
+	LastResults  int64      `json:"last_results" bson:"last_results"` // Results of the latest search
+	LastSearched time.Time  `json:"last_searched" bson:"last_searched"`
+	ImportedAt   *time.Time `json:"imported_at,omitempty" bson:"imported_at,omitempty"`
+}
+
+// SearchReport lists the most searched queries, the queries that still find nothing and the
+// queries whose results are rarely clicked
+type SearchReport struct {
+	Since           time.Time          `json:"since"`
+	Searches        int64              `json:"searches"`
+	TopQueries      []SearchQueryStats `json:"top_queries"`
+	ZeroResults     []SearchQueryStats `json:"zero_results"`
+	LowClickThrough []SearchQueryStats `json:"low_click_through"`
+}
+
+// SearchImport is the outcome of importing anime for a search that found nothing
+type SearchImport struct {
+	Query   string `json:"query"`
+	Source  string `json:"source"`  // "anilist" or "jikan"
+	Results int64  `json:"results"` // What the search finds now
+}
+
+// InitSearchLogCollection initializes the search log, which forgets searches after SEARCH_LOG_RETENTION
+func InitSearchLogCollection() {
+	if config.DB == nil {
+		return
+	}
+
+	searchLogCollection = config.GetCollection(config.DB, "search_log")
+	searchLogCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(SEARCH_LOG_RETENTION.Seconds())),
+		},
+		{Keys: bson.D{{Key: "query", Value: 1}, {Key: "results", Value: 1}}},
+	})
+}
+
+func getSearchLogCollection() (*mongo.Collection, error) {
+	if searchLogCollection == nil {
+		InitSearchLogCollection()
+	}
+	if searchLogCollection == nil {
+		return nil, fmt.Errorf("search log collection not initialized")
+	}
+	return searchLogCollection, nil
+}
+
+// LogSearch records a search and how many results it found, and returns the ID that clicks on
+// its results are reported against. Nothing about the searcher is recorded. The write happens in
+// the background so searches never wait for it. Text without words is not logged and gives "".
+func LogSearch(text, source string, results int64) string {
+	query := NormalizeTitle(text)
+	if query == "" {
+		return ""
+	}
+	collection, err := getSearchLogCollection()
+	if err != nil {
+		return ""
+	}
+
+	entry := model.SearchLogEntry{
+		ID:        primitive.NewObjectID(),
+		Query:     query,
+		Text:      truncateString(strings.TrimSpace(text), SEARCH_TEXT_MAX_SIZE),
+		Source:    source,
+		Results:   results,
+		CreatedAt: time.Now(),
+	}
+	go func() {
+		if _, err := collection.InsertOne(context.Background(), entry); err != nil {
+			log.Printf("Failed to log search: %v", err)
+		}
+	}()
+	return entry.ID.Hex()
+}
+
+// RecordSearchClick records the result clicked from a logged search. Only the first click within
+// SEARCH_CLICK_WINDOW of the search counts; later ones are ignored without error.
+func RecordSearchClick(searchID, resultID string, rank int) error {
+	id, err := primitive.ObjectIDFromHex(searchID)
+	if err != nil {
+		return fmt.Errorf("invalid search ID")
+	}
+	if resultID == "" || len(resultID) > SEARCH_RESULT_ID_MAX_SIZE {
+		return fmt.Errorf("invalid result ID")
+	}
+	if rank < 1 {
+		return fmt.Errorf("rank must be a positive number")
+	}
+	collection, err := getSearchLogCollection()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = collection.UpdateOne(context.Background(),
+		bson.M{
+			"_id":        id,
+			"clicked_at": bson.M{"$exists": false},
+			"created_at": bson.M{"$gte": now.Add(-SEARCH_CLICK_WINDOW)},
+		},
+		bson.M{"$set": bson.M{"clicked_id": resultID, "clicked_rank": rank, "clicked_at": now}})
+	return err
+}
+
+// GetSearchReport sums up the searches logged since the given time, listing up to limit queries
+// in each part of the report. Zero-result queries are those whose latest search found nothing
+// and that no admin has imported anime for since.
+func GetSearchReport(since time.Time, limit int) (*SearchReport, error) {
+	collection, err := getSearchLogCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	bySearches := bson.M{"$sort": bson.D{{Key: "searches", Value: -1}, {Key: "_id", Value: 1}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$query",
+			"text":          bson.M{"$last": "$text"},
+			"searches":      bson.M{"$sum": 1},
+			"clicks":        bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$clicked_at", false}}, 1, 0}}},
+			"zero_results":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$results", 0}}, 1, 0}}},
+			"last_results":  bson.M{"$last": "$results"},
+			"last_searched": bson.M{"$last": "$created_at"},
+			"imported_at":   bson.M{"$max": "$imported_at"},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"click_through": bson.M{"$divide": bson.A{"$clicks", "$searches"}}}}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{bson.M{"$group": bson.M{"_id": nil, "searches": bson.M{"$sum": "$searches"}}}},
+			"top":    bson.A{bySearches, bson.M{"$limit": limit}},
+			"zero": bson.A{
+				bson.M{"$match": bson.M{"last_results": 0, "imported_at": nil}},
+				bySearches, bson.M{"$limit": limit},
+			},
+			"low": bson.A{
+				bson.M{"$match": bson.M{
+					"searches":      bson.M{"$gte": SEARCH_REPORT_MIN_SEARCHES},
+					"last_results":  bson.M{"$gt": 0},
+					"click_through": bson.M{"$lt": SEARCH_LOW_CLICK_THROUGH},
+				}},
+				bySearches, bson.M{"$limit": limit},
+			},
+		}}},
+	}
+
+	ctx := context.Background()
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var out []struct {
+		Totals []struct {
+			Searches int64 `bson:"searches"`
+		} `bson:"totals"`
+		Top  []SearchQueryStats `bson:"top"`
+		Zero []SearchQueryStats `bson:"zero"`
+		Low  []SearchQueryStats `bson:"low"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	report := &SearchReport{
+		Since:           since,
+		TopQueries:      []SearchQueryStats{},
+		ZeroResults:     []SearchQueryStats{},
+		LowClickThrough: []SearchQueryStats{},
+	}
+	if len(out) == 0 {
+		return report, nil
+	}
+	if len(out[0].Totals) > 0 {
+		report.Searches = out[0].Totals[0].Searches
+	}
+	if out[0].Top != nil {
+		report.TopQueries = out[0].Top
+	}
+	if out[0].Zero != nil {
+		report.ZeroResults = out[0].Zero
+	}
+	if out[0].Low != nil {
+		report.LowClickThrough = out[0].Low
+	}
+	return report, nil
+}
+
+// ImportSearchTerm imports anime for a search that found nothing, from AniList and failing that
+// from Jikan, and marks the term's zero-result searches as handled. It returns what the search
+// finds once the import is done.
+func ImportSearchTerm(text string) (*SearchImport, error) {
+	query := NormalizeTitle(text)
+	if query == "" {
+		return nil, fmt.Errorf("search term is required")
+	}
+
+	result := &SearchImport{Query: query, Source: "anilist"}
+	if err := ImportFromAniList(text); err != nil {
+		log.Printf("AniList import for search '%s' failed, trying Jikan: %v", text, err)
+		result.Source = "jikan"
+		if _, err := ImportSearchResults(text); err != nil {
+			return nil, fmt.Errorf("no anime could be imported for %q", text)
+		}
+	}
+
+	// Count afresh rather than through the search cache, which may still hold the empty result
+	_, total, err := NewSearchService().performSearch(query, bson.M{}, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	result.Results = total
+
+	if collection, err := getSearchLogCollection(); err == nil {
+		collection.UpdateMany(context.Background(),
+			bson.M{"query": query, "results": 0},
+			bson.M{"$set": bson.M{"imported_at": time.Now()}})
+	}
+	return result, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
 	"time"
@@ -11,14 +12,50 @@ import (
 	"animeverse/config"
 	"animeverse/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const SEARCH_CACHE_PREFIX = "search:"
+const (
+	SEARCH_CACHE_PREFIX      = "search:"
+	SEARCH_SYNOPSIS_TERMS    = 200 // Distinct synopsis terms kept per anime
+	SEARCH_FUZZY_CANDIDATES  = 100
+	SEARCH_EXACT_TITLE_BONUS = 10.0
+	SEARCH_PARTIAL_HIT       = 0.5 // A last word that is only a prefix of a title term, as while typing
+	SEARCH_TERMS_VERSION     = 2   // Raised when terms are built differently; 2 normalizes titles first
+)
+
+// searchFields are the weighted fields of the search index, strongest first.
+// Title fields also reward titles that are mostly made of the query's terms.
+var searchFields = []struct {
+	Field  string
+	Weight float64
+	Title  bool
+}{
+	{"name", 10, true},
+	{"english", 8, true},
+	{"synonyms", 6, true},
+	{"japanese", 4, true},
+	{"synopsis", 1, false},
+}
+
+// searchProjection leaves out the heavy detail fields from result cards
+var searchProjection = bson.M{"search": 0, "name_key": 0, "title_keys": 0, "characters": 0, "staff": 0, "related": 0, "themes": 0}
 
-type SearchResult struct {
-	Anime *models.Anime `json:"anime"`
-	Score float64       `json:"score"`
+func init() {
+	// Keep each catalog anime's search terms in step with its titles and synopsis
+	OnCatalogChange(func(event CatalogEvent) {
+		if event.Type == CatalogAnimeRemoved {
+			return
+		}
+		terms := buildSearchTerms(event.Anime)
+		if sameSearchTerms(event.Anime.Search, terms) {
+			return
+		}
+		config.Collection.UpdateOne(context.Background(), PublicCatalogFilter(bson.M{"_id": event.Anime.ID}),
+			bson.M{"$set": bson.M{"search": terms}})
+	})
 }
 
 type SearchService struct {
@@ -31,187 +68,421 @@ func NewSearchService() *SearchService {
 	}
 }
 
-func (s *SearchService) SearchAnime(query string, limit int) ([]*models.Anime, error) {
-	// Check cache first
-	cacheKey := fmt.Sprintf("%s%s:%d", SEARCH_CACHE_PREFIX, strings.ToLower(query), limit)
-	
-	var cachedResults []*models.Anime
-	if err := cache.Get(cacheKey, &cachedResults); err == nil {
-		return cachedResults, nil
+// rankedSearch is the cached ranking of one results page; documents are loaded fresh on each hit
+type rankedSearch struct {
+	IDs    []primitive.ObjectID `json:"ids"`
+	Scores []float64            `json:"scores"`
+	Total  int64                `json:"total"`
+}
+
+// EnsureSearchIndexes indexes each weighted field of the search terms and the title keys
+func EnsureSearchIndexes() {
+	if config.Collection == nil {
+		return
+	}
+
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name_key", Value: 1}}},
+		{Keys: bson.D{{Key: "title_keys", Value: 1}}},
+	}
+	for _, f := range searchFields {
+		indexes = append(indexes, mongo.IndexModel{Keys: bson.D{{Key: "search." + f.Field, Value: 1}}})
+	}
+	if _, err := config.Collection.Indexes().CreateMany(context.Background(), indexes); err != nil {
+		log.Printf("Failed to create search indexes: %v", err)
+	}
+}
+
+// Search ranks catalog anime by how well their weighted fields match the query text and returns
+// one page of them, each carrying its "relevance" score, along with the total number of matches.
+// The query's field filters narrow the matches. Ties are broken by member count and then ID, so
+// pages never overlap or skip results.
+func (s *SearchService) Search(q *SearchQuery, limit, skip int) ([]primitive.M, int64, error) {
+	if q.Empty() {
+		return []primitive.M{}, 0, nil
 	}
-	
-	// Perform search
-	results, err := s.performSearch(query, limit)
+
+	cacheKey := fmt.Sprintf("%s%s:%d:%d", SEARCH_CACHE_PREFIX, q.String(), limit, skip)
+	var ranked rankedSearch
+	if err := cache.Get(cacheKey, &ranked); err == nil {
+		results, err := s.loadRanked(ranked)
+		return results, ranked.Total, err
+	}
+
+	results, total, err := s.performSearch(NormalizeTitle(q.Text), q.Filter(), limit, skip)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	
-	// Cache results
-	cache.Set(cacheKey, results, s.CacheDuration)
-	
-	return results, nil
+
+	ranked = rankedSearch{Total: total}
+	for _, doc := range results {
+		id, _ := doc["_id"].(primitive.ObjectID)
+		score, _ := doc["relevance"].(float64)
+		ranked.IDs = append(ranked.IDs, id)
+		ranked.Scores = append(ranked.Scores, score)
+	}
+	cache.Set(cacheKey, ranked, s.CacheDuration)
+
+	return results, total, nil
+}
+
+// SearchSorted pages through the anime matching the query in one of the list sort orders
+// rather than by relevance. It returns the page, the next cursor and the number of matches.
+func (s *SearchService) SearchSorted(q *SearchQuery, page PageRequest) ([]primitive.M, string, int64, error) {
+	var match bson.M
+	if query := NormalizeTitle(q.Text); query != "" {
+		match = titleFragmentMatch(query)
+		if terms := searchTerms(query); len(terms) > 0 {
+			match = searchTextMatch(query, terms)
+		}
+	}
+	filter := PublicCatalogFilter(withFilter(match, q.Filter()))
+
+	ctx := context.Background()
+	total, err := config.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	results, next, err := FindPage(ctx, config.Collection, filter, page, searchProjection)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return results, next, total, nil
 }
 
-func (s *SearchService) performSearch(query string, limit int) ([]*models.Anime, error) {
+func (s *SearchService) performSearch(query string, filter bson.M, limit, skip int) ([]primitive.M, int64, error) {
 	ctx := context.Background()
-	
-	// Multi-stage search strategy
-	var allResults []SearchResult
-	
-	// 1. Exact name match (highest priority)
-	exactResults, _ := s.searchExact(ctx, query)
-	for _, anime := range exactResults {
-		allResults = append(allResults, SearchResult{Anime: anime, Score: 100.0})
-	}
-	
-	// 2. Fuzzy name match
-	fuzzyResults, _ := s.searchFuzzy(ctx, query)
-	for _, anime := range fuzzyResults {
-		score := s.calculateSimilarity(query, anime.Name)
-		allResults = append(allResults, SearchResult{Anime: anime, Score: score * 80})
-	}
-	
-	// 3. Genre match
-	genreResults, _ := s.searchByGenre(ctx, query)
-	for _, anime := range genreResults {
-		allResults = append(allResults, SearchResult{Anime: anime, Score: 60.0})
-	}
-	
-	// Remove duplicates and sort by score
-	uniqueResults := s.removeDuplicates(allResults)
-	sort.Slice(uniqueResults, func(i, j int) bool {
-		if uniqueResults[i].Score == uniqueResults[j].Score {
-			// Secondary sort by popularity (score)
-			return uniqueResults[i].Anime.Score > uniqueResults[j].Anime.Score
+
+	if query == "" {
+		// Filters alone; every match is equally relevant
+		return s.searchFiltered(ctx, filter, limit, skip)
+	}
+
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		// Only stop words or single letters; plain title matching still finds "K" or "It"
+		return s.searchFuzzy(ctx, query, filter, limit, skip)
+	}
+
+	prefix := searchPrefix(query)
+
+	relevance := bson.A{}
+	for _, f := range searchFields {
+		relevance = append(relevance, fieldRelevance("search."+f.Field, f.Weight, f.Title, terms, prefix))
+	}
+	relevance = append(relevance, bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{bson.M{"$literal": query}, bson.M{"$ifNull": bson.A{"$title_keys", bson.A{}}}}},
+		SEARCH_EXACT_TITLE_BONUS,
+		0,
+	}})
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: PublicCatalogFilter(withFilter(searchTextMatch(query, terms), filter))}},
+		{{Key: "$addFields", Value: bson.M{"relevance": bson.M{"$round": bson.A{bson.M{"$add": relevance}, 3}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "relevance", Value: -1}, {Key: "statistics.members", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"results": bson.A{
+				bson.M{"$skip": skip},
+				bson.M{"$limit": limit},
+				bson.M{"$project": searchProjection},
+			},
+			"total": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+
+	cur, err := config.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out []struct {
+		Results []primitive.M `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, 0, err
+	}
+	if len(out) == 0 || len(out[0].Total) == 0 {
+		// No whole-word match; fall back to title fragments such as "naru" or "hiro"
+		return s.searchFuzzy(ctx, query, filter, limit, skip)
+	}
+
+	return out[0].Results, out[0].Total[0].Count, nil
+}
+
+// searchPrefix is the last word of the query. While typing it is usually unfinished, so it
+// may also match as the prefix of a title term.
+func searchPrefix(query string) string {
+	if words := tokenizeText(query); len(words) > 0 && !strings.HasSuffix(query, " ") {
+		return words[len(words)-1]
+	}
+	return ""
+}
+
+// searchTextMatch matches anime having any of the terms in a weighted field, or a title term
+// starting with the query's last word
+func searchTextMatch(query string, terms []string) bson.M {
+	prefix := searchPrefix(query)
+	var match []bson.M
+	for _, f := range searchFields {
+		field := "search." + f.Field
+		match = append(match, bson.M{field: bson.M{"$in": terms}})
+		if f.Title && prefix != "" {
+			match = append(match, bson.M{field: PrefixText(prefix)})
 		}
-		return uniqueResults[i].Score > uniqueResults[j].Score
-	})
-	
-	// Extract anime and limit results
-	var results []*models.Anime
-	for i, result := range uniqueResults {
-		if i >= limit {
-			break
+	}
+	return bson.M{"$or": match}
+}
+
+// titleFragmentMatch matches anime with the normalized query anywhere in one of their titles
+func titleFragmentMatch(query string) bson.M {
+	return bson.M{"title_keys": ContainsText(query)}
+}
+
+// withFilter narrows a filter by another one in a new filter, so neither is modified
+// when the result is passed on to PublicCatalogFilter
+func withFilter(base, extra bson.M) bson.M {
+	clauses := []bson.M{}
+	for _, filter := range []bson.M{base, extra} {
+		if len(filter) > 0 {
+			clauses = append(clauses, filter)
 		}
-		results = append(results, result.Anime)
 	}
-	
-	return results, nil
+	if len(clauses) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": clauses}
 }
 
-func (s *SearchService) searchExact(ctx context.Context, query string) ([]*models.Anime, error) {
-	filter := bson.M{
-		"name": bson.M{"$regex": "^" + query + "$", "$options": "i"},
+// fieldRelevance scores one field as its weight times the share of query terms it contains.
+// For titles that is further scaled by how much of the title the query covers, so "Naruto"
+// outranks "Naruto: Shippuden" for the query "naruto".
+func fieldRelevance(field string, weight float64, title bool, terms []string, prefix string) bson.M {
+	hits := bson.A{bson.M{"$size": bson.M{"$setIntersection": bson.A{"$$terms", bson.M{"$literal": terms}}}}}
+	if title && prefix != "" {
+		hits = append(hits, bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$not": bson.A{bson.M{"$in": bson.A{bson.M{"$literal": prefix}, "$$terms"}}}},
+				bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+					"input": "$$terms",
+					"as":    "term",
+					"in":    bson.M{"$eq": bson.A{bson.M{"$indexOfCP": bson.A{"$$term", bson.M{"$literal": prefix}}}, 0}},
+				}}}},
+			}},
+			SEARCH_PARTIAL_HIT,
+			0,
+		}})
+	}
+
+	score := bson.A{weight, bson.M{"$divide": bson.A{"$$hits", len(terms)}}}
+	if title {
+		score = append(score, bson.M{"$add": bson.A{0.5, bson.M{"$divide": bson.A{
+			bson.M{"$multiply": bson.A{0.5, "$$hits"}},
+			bson.M{"$max": bson.A{bson.M{"$size": "$$terms"}, 1}},
+		}}}})
 	}
-	
-	return s.executeSearch(ctx, filter, 5)
+
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"terms": bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}}},
+		"in": bson.M{"$let": bson.M{
+			"vars": bson.M{"hits": bson.M{"$add": hits}},
+			"in":   bson.M{"$multiply": score},
+		}},
+	}}
 }
 
-func (s *SearchService) searchFuzzy(ctx context.Context, query string) ([]*models.Anime, error) {
-	filter := bson.M{
-		"name": bson.M{"$regex": query, "$options": "i"},
+// searchFuzzy matches the query anywhere in a title and ranks by similarity to the name
+func (s *SearchService) searchFuzzy(ctx context.Context, query string, filter bson.M, limit, skip int) ([]primitive.M, int64, error) {
+	candidates, err := s.executeSearch(ctx, withFilter(titleFragmentMatch(query), filter), 0, SEARCH_FUZZY_CANDIDATES)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, anime := range candidates {
+		name, _ := anime["name"].(string)
+		anime["relevance"] = s.calculateSimilarity(query, NormalizeTitle(name))
+	}
+	// Candidates arrive by member count and ID, and the stable sort keeps that order among ties
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i]["relevance"].(float64) > candidates[j]["relevance"].(float64)
+	})
+
+	total := int64(len(candidates))
+	if skip >= len(candidates) {
+		return []primitive.M{}, total, nil
 	}
-	
-	return s.executeSearch(ctx, filter, 20)
+	return candidates[skip:min(skip+limit, len(candidates))], total, nil
 }
 
-func (s *SearchService) searchByGenre(ctx context.Context, query string) ([]*models.Anime, error) {
-	filter := bson.M{
-		"genre": bson.M{"$in": []string{query}},
+// searchFiltered pages through the anime matching filter, most popular first
+func (s *SearchService) searchFiltered(ctx context.Context, filter bson.M, limit, skip int) ([]primitive.M, int64, error) {
+	total, err := config.Collection.CountDocuments(ctx, PublicCatalogFilter(withFilter(nil, filter)))
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := s.executeSearch(ctx, filter, skip, limit)
+	if err != nil {
+		return nil, 0, err
 	}
-	
-	return s.executeSearch(ctx, filter, 10)
+	for _, anime := range results {
+		anime["relevance"] = 0.0
+	}
+	return results, total, nil
 }
 
-func (s *SearchService) executeSearch(ctx context.Context, filter bson.M, limit int) ([]*models.Anime, error) {
-	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.D{{"score", -1}})
-	
-	cursor, err := config.Collection.Find(ctx, filter, opts)
+func (s *SearchService) executeSearch(ctx context.Context, filter bson.M, skip, limit int) ([]primitive.M, error) {
+	opts := options.Find().
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "statistics.members", Value: -1}, {Key: "_id", Value: 1}}).
+		SetProjection(searchProjection)
+
+	cursor, err := config.Collection.Find(ctx, PublicCatalogFilter(withFilter(nil, filter)), opts)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-	
-	var animes []*models.Anime
-	for cursor.Next(ctx) {
-		var anime models.Anime
-		if err := cursor.Decode(&anime); err != nil {
-			continue
-		}
-		animes = append(animes, &anime)
+
+	animes := []primitive.M{}
+	if err := cursor.All(ctx, &animes); err != nil {
+		return nil, err
 	}
-	
 	return animes, nil
 }
 
+// loadRanked fetches the documents of a cached ranking, in ranked order
+func (s *SearchService) loadRanked(ranked rankedSearch) ([]primitive.M, error) {
+	if len(ranked.IDs) == 0 {
+		return []primitive.M{}, nil
+	}
+
+	ctx := context.Background()
+	cursor, err := config.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": ranked.IDs}},
+		options.Find().SetProjection(searchProjection))
+	if err != nil {
+		return nil, err
+	}
+	var docs []primitive.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]primitive.M, len(docs))
+	for _, doc := range docs {
+		if id, ok := doc["_id"].(primitive.ObjectID); ok {
+			byID[id] = doc
+		}
+	}
+	results := make([]primitive.M, 0, len(ranked.IDs))
+	for i, id := range ranked.IDs {
+		if doc, ok := byID[id]; ok {
+			doc["relevance"] = ranked.Scores[i]
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+// calculateSimilarity scores a normalized title against the normalized query. Titles that are
+// neither the query nor contain it are scored by the trigrams they share with it, as the
+// spelling index compares words.
 func (s *SearchService) calculateSimilarity(query, target string) float64 {
-	query = strings.ToLower(query)
-	target = strings.ToLower(target)
-	
-	// Simple similarity calculation
 	if query == target {
 		return 1.0
 	}
-	
+
 	if strings.Contains(target, query) {
 		return 0.8
 	}
-	
-	// Levenshtein distance approximation
-	return s.levenshteinSimilarity(query, target)
-}
-
-func (s *SearchService) levenshteinSimilarity(s1, s2 string) float64 {
-	if len(s1) == 0 {
-		return float64(len(s2))
-	}
-	if len(s2) == 0 {
-		return float64(len(s1))
-	}
-	
-	matrix := make([][]int, len(s1)+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len(s2)+1)
-		matrix[i][0] = i
-	}
-	
-	for j := 0; j <= len(s2); j++ {
-		matrix[0][j] = j
-	}
-	
-	for i := 1; i <= len(s1); i++ {
-		for j := 1; j <= len(s2); j++ {
-			cost := 0
-			if s1[i-1] != s2[j-1] {
-				cost = 1
-			}
-			
-			deletion := matrix[i-1][j] + 1
-			insertion := matrix[i][j-1] + 1
-			substitution := matrix[i-1][j-1] + cost
-			
-			matrix[i][j] = min(deletion, min(insertion, substitution))
+
+	return trigramSimilarity(query, target)
+}
+
+// fillSearchFields sets the search terms and title keys of an anime that is written without
+// catalog events, as bulk import does, so it is searchable as soon as it is stored
+func fillSearchFields(anime *models.Anime) {
+	anime.Search = buildSearchTerms(*anime)
+	anime.NameKey, anime.TitleKeys = buildTitleKeys(*anime)
+}
+
+// buildSearchTerms derives the weighted search terms of an anime. Text is normalized first,
+// as queries are, so spelling variants of a word share a term.
+func buildSearchTerms(anime models.Anime) *models.SearchTerms {
+	synopsis := anime.Synopsis
+	if synopsis == "" {
+		synopsis = anime.Notes
+	}
+	synopsisTerms := searchTerms(NormalizeTitle(synopsis))
+	if len(synopsisTerms) > SEARCH_SYNOPSIS_TERMS {
+		synopsisTerms = synopsisTerms[:SEARCH_SYNOPSIS_TERMS]
+	}
+
+	return &models.SearchTerms{
+		Name:      searchTerms(NormalizeTitle(anime.Name)),
+		English:   searchTerms(NormalizeTitle(anime.AlternativeTitles.English)),
+		Synonyms:  searchTerms(NormalizeTitle(strings.Join(anime.AlternativeTitles.Synonyms, " "))),
+		Japanese:  searchTerms(NormalizeTitle(anime.AlternativeTitles.Japanese)),
+		Synopsis:  synopsisTerms,
+		Version:   SEARCH_TERMS_VERSION,
+		IndexedAt: time.Now(),
+	}
+}
+
+func sameSearchTerms(a, b *models.SearchTerms) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return sameStrings(a.Name, b.Name) && sameStrings(a.English, b.English) &&
+		sameStrings(a.Synonyms, b.Synonyms) && sameStrings(a.Japanese, b.Japanese) &&
+		sameStrings(a.Synopsis, b.Synopsis) && a.Version == b.Version
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
-	
-	distance := matrix[len(s1)][len(s2)]
-	maxLen := max(len(s1), len(s2))
-	
-	return 1.0 - float64(distance)/float64(maxLen)
-}
-
-func (s *SearchService) removeDuplicates(results []SearchResult) []SearchResult {
-	seen := make(map[string]bool)
-	var unique []SearchResult
-	
-	for _, result := range results {
-		if !seen[result.Anime.ID.Hex()] {
-			seen[result.Anime.ID.Hex()] = true
-			unique = append(unique, result)
+	return true
+}
+
+// BackfillSearchTerms indexes a batch of catalog anime whose search terms are missing or were
+// built by an older SEARCH_TERMS_VERSION. It returns how many were indexed and whether more remain.
+func BackfillSearchTerms() (int, bool, error) {
+	if config.Collection == nil {
+		return 0, false, fmt.Errorf("database not initialized")
+	}
+
+	filter := PublicCatalogFilter(bson.M{"search.version": bson.M{"$ne": SEARCH_TERMS_VERSION}})
+	opts := options.Find().
+		SetProjection(bson.M{"name": 1, "alternative_titles": 1, "synopsis": 1, "notes": 1}).
+		SetLimit(BACKFILL_BATCH)
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, false, err
+	}
+	var animes []models.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return 0, false, err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(animes))
+	for _, anime := range animes {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": anime.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"search": buildSearchTerms(anime)}}))
+	}
+	if len(writes) > 0 {
+		if _, err := config.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return 0, false, err
 		}
 	}
-	
-	return unique
+
+	return len(animes), len(animes) == BACKFILL_BATCH, nil
 }
 
 func min(a, b int) int {
@@ -226,4 +497,4 @@ func max(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
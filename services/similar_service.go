@@ -0,0 +1,558 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SIMILAR_NEIGHBORS        = 20
+	SIMILAR_MIN_SCORE        = 0.05
+	SIMILAR_MAX_TERMS        = 64 // Strongest synopsis terms kept per anime
+	SIMILAR_BACKFILL_BATCH   = 100
+	SIMILAR_REBUILD_INTERVAL = 24 * time.Hour
+
+	SIMILAR_TEXT_WEIGHT   = 0.5
+	SIMILAR_GENRE_WEIGHT  = 0.25
+	SIMILAR_TAG_WEIGHT    = 0.15
+	SIMILAR_STUDIO_WEIGHT = 0.1
+)
+
+// SimilarAnime is a catalog anime with its content similarity to another one
+type SimilarAnime struct {
+	Anime model.Anime `json:"anime"`
+	Score float64     `json:"score"`
+}
+
+// weightedTerm is one entry of a sparse TF-IDF vector
+type weightedTerm struct {
+	term   uint32
+	weight float32
+}
+
+// contentDoc is the compact, in-memory representation of one catalog anime
+type contentDoc struct {
+	id      primitive.ObjectID
+	name    string
+	vector  []weightedTerm // Sorted by term, L2-normalised
+	genres  []uint32       // Sorted
+	tags    []uint32
+	studios []uint32
+}
+
+// contentIndex holds every catalog anime's content features. Terms and labels are
+// interned to integers so tens of thousands of titles stay small and fast to compare.
+type contentIndex struct {
+	mu      sync.RWMutex
+	docs    map[primitive.ObjectID]*contentDoc
+	terms   map[string]uint32
+	df      map[uint32]int
+	builtAt time.Time
+	ready   bool
+}
+
+var (
+	similarIndex                = newContentIndex()
+	contentSimilarityCollection *mongo.Collection
+
+	// Neighbor lists go stale as term weights drift, so the stored ones are refreshed a
+	// batch at a time between the daily rebuilds
+	similarRunner = newCatalogIndex("similar anime", SIMILAR_REBUILD_INTERVAL, rebuildSimilarIndex, applySimilarUpdates).
+			every(10*time.Second, refreshSimilarNeighbors)
+)
+
+func newContentIndex() *contentIndex {
+	return &contentIndex{
+		docs:  make(map[primitive.ObjectID]*contentDoc),
+		terms: make(map[string]uint32),
+		df:    make(map[uint32]int),
+	}
+}
+
+// InitContentSimilarityCollection initializes the content_similarity collection
+func InitContentSimilarityCollection() {
+	if config.DB == nil {
+		return
+	}
+	contentSimilarityCollection = config.GetCollection(config.DB, "content_similarity")
+	contentSimilarityCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "neighbors.key", Value: 1}},
+	})
+}
+
+func getContentSimilarityCollection() (*mongo.Collection, error) {
+	if contentSimilarityCollection == nil {
+		InitContentSimilarityCollection()
+	}
+	if contentSimilarityCollection == nil {
+		return nil, fmt.Errorf("content similarity collection not initialized")
+	}
+	return contentSimilarityCollection, nil
+}
+
+// StartSimilarIndex builds the content index, then keeps it and the stored neighbor
+// lists current: catalog changes are applied as they happen, missing or stale lists
+// are backfilled in small batches, and the whole index is rebuilt once a day so
+// term weights follow the growing catalog.
+func StartSimilarIndex() {
+	similarRunner.run()
+}
+
+// rebuildSimilarIndex loads the whole catalog into a fresh index
+func rebuildSimilarIndex() error {
+	start := time.Now()
+	opts := options.Find().SetProjection(bson.M{
+		"name": 1, "synopsis": 1, "notes": 1, "genre": 1, "tags": 1, "information.studios": 1,
+	})
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(nil), opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	// First pass collects term frequencies, since IDF needs the whole catalog
+	type pending struct {
+		anime model.Anime
+		tf    map[string]int
+	}
+	var animes []pending
+	documentFrequency := make(map[string]int)
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		tf := termFrequencies(anime)
+		for term := range tf {
+			documentFrequency[term]++
+		}
+		animes = append(animes, pending{anime: anime, tf: tf})
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	index := newContentIndex()
+	for term, count := range documentFrequency {
+		index.df[index.intern("t:"+term)] = count
+	}
+	for _, p := range animes {
+		index.docs[p.anime.ID] = index.buildDoc(p.anime, p.tf, float64(len(animes)))
+	}
+	index.builtAt = start
+	index.ready = true
+
+	similarIndex.mu.Lock()
+	similarIndex.docs = index.docs
+	similarIndex.terms = index.terms
+	similarIndex.df = index.df
+	similarIndex.builtAt = index.builtAt
+	similarIndex.ready = true
+	similarIndex.mu.Unlock()
+
+	log.Printf("Built similar anime index for %d titles in %v", len(animes), time.Since(start))
+	return nil
+}
+
+// termFrequencies counts the synopsis terms of an anime
+func termFrequencies(anime model.Anime) map[string]int {
+	text := anime.Synopsis
+	if text == "" && anime.Notes != "Added by user" {
+		// Imports store the synopsis in notes
+		text = anime.Notes
+	}
+
+	tf := make(map[string]int)
+	for _, term := range tokenizeText(text) {
+		tf[term]++
+	}
+	return tf
+}
+
+// intern returns the integer ID of a term or label. Callers must hold the write lock.
+func (idx *contentIndex) intern(term string) uint32 {
+	if id, ok := idx.terms[term]; ok {
+		return id
+	}
+	id := uint32(len(idx.terms))
+	idx.terms[term] = id
+	return id
+}
+
+// internSet interns a list of labels under a prefix into a sorted, de-duplicated set
+func (idx *contentIndex) internSet(prefix string, values []string) []uint32 {
+	seen := make(map[uint32]bool)
+	var set []uint32
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		id := idx.intern(prefix + value)
+		if !seen[id] {
+			seen[id] = true
+			set = append(set, id)
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	return set
+}
+
+// buildDoc turns an anime into its compact representation using the current document
+// frequencies over a catalog of total titles. Callers must hold the write lock.
+func (idx *contentIndex) buildDoc(anime model.Anime, tf map[string]int, total float64) *contentDoc {
+	var vector []weightedTerm
+	for term, count := range tf {
+		id := idx.intern("t:" + term)
+		df := idx.df[id]
+		if df == 0 {
+			df = 1
+		}
+		weight := (1 + math.Log(float64(count))) * math.Log(1+total/float64(df))
+		vector = append(vector, weightedTerm{term: id, weight: float32(weight)})
+	}
+
+	// Keep only the most distinctive terms
+	sort.Slice(vector, func(i, j int) bool { return vector[i].weight > vector[j].weight })
+	if len(vector) > SIMILAR_MAX_TERMS {
+		vector = vector[:SIMILAR_MAX_TERMS]
+	}
+	norm := 0.0
+	for _, wt := range vector {
+		norm += float64(wt.weight) * float64(wt.weight)
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i].weight = float32(float64(vector[i].weight) / norm)
+	}
+	sort.Slice(vector, func(i, j int) bool { return vector[i].term < vector[j].term })
+
+	return &contentDoc{
+		id:      anime.ID,
+		name:    anime.Name,
+		vector:  vector,
+		genres:  idx.internSet("g:", anime.Genre),
+		tags:    idx.internSet("k:", anime.Tags),
+		studios: idx.internSet("s:", anime.Information.Studios),
+	}
+}
+
+// contentSimilarity combines synopsis cosine similarity with genre, tag and studio overlap
+func contentSimilarity(a, b *contentDoc) float64 {
+	score := SIMILAR_TEXT_WEIGHT*sparseDot(a.vector, b.vector) +
+		SIMILAR_GENRE_WEIGHT*jaccard(a.genres, b.genres) +
+		SIMILAR_TAG_WEIGHT*jaccard(a.tags, b.tags)
+
+	// Studios overlap relative to the smaller set, since most anime have one studio
+	if shared := intersectionSize(a.studios, b.studios); shared > 0 {
+		smaller := len(a.studios)
+		if len(b.studios) < smaller {
+			smaller = len(b.studios)
+		}
+		score += SIMILAR_STUDIO_WEIGHT * float64(shared) / float64(smaller)
+	}
+	return score
+}
+
+func sparseDot(a, b []weightedTerm) float64 {
+	dot := 0.0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].term == b[j].term:
+			dot += float64(a[i].weight) * float64(b[j].weight)
+			i++
+			j++
+		case a[i].term < b[j].term:
+			i++
+		default:
+			j++
+		}
+	}
+	return dot
+}
+
+func intersectionSize(a, b []uint32) int {
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return shared
+}
+
+func jaccard(a, b []uint32) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := intersectionSize(a, b)
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// computeSimilarNeighbors scores one anime against the whole catalog
+func computeSimilarNeighbors(id primitive.ObjectID) ([]model.AnimeNeighbor, bool) {
+	similarIndex.mu.RLock()
+	defer similarIndex.mu.RUnlock()
+
+	doc, ok := similarIndex.docs[id]
+	if !ok {
+		return nil, false
+	}
+
+	var neighbors []model.AnimeNeighbor
+	for otherID, other := range similarIndex.docs {
+		if otherID == id {
+			continue
+		}
+		score := contentSimilarity(doc, other)
+		if score < SIMILAR_MIN_SCORE {
+			continue
+		}
+		neighbors = append(neighbors, model.AnimeNeighbor{
+			Key:   otherID.Hex(),
+			Name:  other.name,
+			Score: math.Round(score*10000) / 10000,
+		})
+	}
+
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Score > neighbors[j].Score
+	})
+	if len(neighbors) > SIMILAR_NEIGHBORS {
+		neighbors = neighbors[:SIMILAR_NEIGHBORS]
+	}
+	return neighbors, true
+}
+
+// storeSimilarNeighbors saves an anime's neighbor list
+func storeSimilarNeighbors(id primitive.ObjectID, name string, neighbors []model.AnimeNeighbor) error {
+	collection, err := getContentSimilarityCollection()
+	if err != nil {
+		return err
+	}
+
+	doc := model.AnimeNeighbors{Key: id.Hex(), Name: name, Neighbors: neighbors, UpdatedAt: time.Now()}
+	_, err = collection.ReplaceOne(context.Background(), bson.M{"_id": doc.Key}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// applySimilarUpdates reflects catalog changes one by one, as each rewrites its own neighbor lists
+func applySimilarUpdates(events []CatalogEvent) {
+	for _, event := range events {
+		applySimilarUpdate(event)
+	}
+}
+
+// applySimilarUpdate reflects one catalog change in the index and the stored neighbor lists
+func applySimilarUpdate(event CatalogEvent) {
+	collection, err := getContentSimilarityCollection()
+	if err != nil {
+		return
+	}
+	ctx := context.Background()
+	key := event.Anime.ID.Hex()
+
+	if event.Type == CatalogAnimeRemoved {
+		similarIndex.mu.Lock()
+		delete(similarIndex.docs, event.Anime.ID)
+		similarIndex.mu.Unlock()
+
+		collection.DeleteOne(ctx, bson.M{"_id": key})
+		collection.UpdateMany(ctx, bson.M{"neighbors.key": key}, bson.M{"$pull": bson.M{"neighbors": bson.M{"key": key}}})
+		return
+	}
+
+	similarIndex.mu.Lock()
+	if !similarIndex.ready {
+		similarIndex.mu.Unlock()
+		return
+	}
+	tf := termFrequencies(event.Anime)
+	if _, existed := similarIndex.docs[event.Anime.ID]; !existed {
+		// Edits keep the old frequencies until the next rebuild; new titles count now
+		for term := range tf {
+			similarIndex.df[similarIndex.intern("t:"+term)]++
+		}
+	}
+	total := float64(len(similarIndex.docs) + 1)
+	similarIndex.docs[event.Anime.ID] = similarIndex.buildDoc(event.Anime, tf, total)
+	similarIndex.mu.Unlock()
+
+	neighbors, ok := computeSimilarNeighbors(event.Anime.ID)
+	if !ok {
+		return
+	}
+	if err := storeSimilarNeighbors(event.Anime.ID, event.Anime.Name, neighbors); err != nil {
+		log.Printf("Failed to store similar anime for %s: %v", event.Anime.Name, err)
+		return
+	}
+
+	// Offer the changed anime to each of its neighbors' lists, keeping them sorted and capped
+	for _, neighbor := range neighbors {
+		entry := model.AnimeNeighbor{Key: key, Name: event.Anime.Name, Score: neighbor.Score}
+		filter := bson.M{"_id": neighbor.Key}
+		collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"neighbors": bson.M{"key": key}}})
+		collection.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"neighbors": bson.M{
+			"$each":  []model.AnimeNeighbor{entry},
+			"$sort":  bson.M{"score": -1},
+			"$slice": SIMILAR_NEIGHBORS,
+		}}})
+	}
+}
+
+// refreshSimilarNeighbors runs backfillSimilarNeighbors between index rebuilds
+func refreshSimilarNeighbors() {
+	if err := backfillSimilarNeighbors(); err != nil {
+		log.Printf("Similar anime backfill failed: %v", err)
+	}
+}
+
+// backfillSimilarNeighbors computes a batch of neighbor lists that are missing or older than the index
+func backfillSimilarNeighbors() error {
+	collection, err := getContentSimilarityCollection()
+	if err != nil {
+		return err
+	}
+
+	similarIndex.mu.RLock()
+	builtAt := similarIndex.builtAt
+	ids := make([]primitive.ObjectID, 0, len(similarIndex.docs))
+	for id := range similarIndex.docs {
+		ids = append(ids, id)
+	}
+	similarIndex.mu.RUnlock()
+
+	ctx := context.Background()
+	cur, err := collection.Find(ctx,
+		bson.M{"updated_at": bson.M{"$gte": builtAt}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	fresh := make(map[string]bool)
+	for cur.Next(ctx) {
+		var doc struct {
+			Key string `bson:"_id"`
+		}
+		if cur.Decode(&doc) == nil {
+			fresh[doc.Key] = true
+		}
+	}
+	cur.Close(ctx)
+
+	computed := 0
+	for _, id := range ids {
+		if fresh[id.Hex()] {
+			continue
+		}
+		neighbors, ok := computeSimilarNeighbors(id)
+		if !ok {
+			continue
+		}
+
+		similarIndex.mu.RLock()
+		name := ""
+		if doc, ok := similarIndex.docs[id]; ok {
+			name = doc.name
+		}
+		similarIndex.mu.RUnlock()
+
+		if err := storeSimilarNeighbors(id, name, neighbors); err != nil {
+			return err
+		}
+		computed++
+		if computed == SIMILAR_BACKFILL_BATCH {
+			break
+		}
+	}
+	return nil
+}
+
+// GetSimilarAnime returns the catalog anime most similar in content to the given one
+func GetSimilarAnime(animeID string, limit int) ([]SimilarAnime, error) {
+	anime, err := getCatalogAnime(animeID)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := getContentSimilarityCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	var stored model.AnimeNeighbors
+	err = collection.FindOne(context.Background(), bson.M{"_id": anime.ID.Hex()}).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		// Not backfilled yet, so compute it now
+		neighbors, ok := computeSimilarNeighbors(anime.ID)
+		if !ok {
+			return []SimilarAnime{}, nil
+		}
+		storeSimilarNeighbors(anime.ID, anime.Name, neighbors)
+		stored.Neighbors = neighbors
+	} else if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(stored.Neighbors))
+	for _, neighbor := range stored.Neighbors {
+		if id, err := primitive.ObjectIDFromHex(neighbor.Key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	result := []SimilarAnime{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetProjection(bson.M{"characters": 0, "staff": 0, "related": 0, "themes": 0})
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(bson.M{"_id": bson.M{"$in": ids}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	byID := make(map[string]model.Anime, len(ids))
+	for cur.Next(ctx) {
+		var match model.Anime
+		if cur.Decode(&match) == nil {
+			byID[match.ID.Hex()] = match
+		}
+	}
+
+	for _, neighbor := range stored.Neighbors {
+		match, ok := byID[neighbor.Key]
+		if !ok {
+			continue
+		}
+		result = append(result, SimilarAnime{Anime: match, Score: neighbor.Score})
+		if len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
@@ -9,13 +9,15 @@ import (
 	model "animeverse/models"
 )
 
-func SmartSearch(search, genre, year, season, format, status, userID string) []primitive.M {
+func SmartSearch(query *SearchQuery, userID string, info InformationFilters, page PageRequest) ([]primitive.M, string) {
 	// First, try local search
-	localResults := FilterAnimes(search, genre, year, season, format, status, userID)
+	localResults, next := FilterAnimes(query, userID, info, page)
 	
-	// If we have results or no search term, return local results
-	if len(localResults) > 0 || search == "" {
-		return localResults
+	// If we have results or no search term, return local results. Only an empty first
+	// page means there is nothing local to find.
+	search := query.Text
+	if len(localResults) > 0 || search == "" || page.Offset > 0 || page.After != nil {
+		return localResults, next
 	}
 	
 	// If no local results and we have a search term, try external search
@@ -27,24 +29,24 @@ func SmartSearch(search, genre, year, season, format, status, userID string) []p
 		if count, err := ImportSearchResults(search); err == nil && count > 0 {
 			log.Printf("Imported %d anime from external search", count)
 			// Search again after import
-			return FilterAnimes(search, genre, year, season, format, status, userID)
+			return FilterAnimes(query, userID, info, page)
 		}
 		
 		// Try AniList as fallback
 		if err := ImportFromAniList(search); err == nil {
 			log.Printf("Imported anime from AniList for search '%s'", search)
 			// Search again after import
-			return FilterAnimes(search, genre, year, season, format, status, userID)
+			return FilterAnimes(query, userID, info, page)
 		}
 	}
 	
 	// Return empty results if nothing found
-	return []primitive.M{}
+	return []primitive.M{}, ""
 }
 
 func ImportSearchResults(searchTerm string) (int, error) {
 	// Use existing Jikan import but with search
-	resp, err := http.Get("https://api.jikan.moe/v4/anime?q=" + searchTerm + "&limit=10")
+	resp, err := http.Get(jikanSearchURL(searchTerm, 10))
 	if err != nil {
 		return 0, err
 	}
@@ -93,7 +95,7 @@ func ImportFromAniList(searchTerm string) error {
 }
 
 func SearchJikanAPI(query string) ([]JikanAnime, error) {
-	resp, err := http.Get("https://api.jikan.moe/v4/anime?q=" + query + "&limit=10")
+	resp, err := http.Get(jikanSearchURL(query, 10))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SPELLING_MAX_SUGGESTIONS  = 3
+	SPELLING_MIN_WORD         = 3 // Shorter words are left as typed
+	SPELLING_REBUILD_INTERVAL = 24 * time.Hour
+)
+
+// spellingIndex is the dictionary of words in catalog titles. Words are found by the trigrams
+// they share with a misspelling: one edit changes at most three trigrams, so a word within d
+// edits shares all but 3d of them and only words sharing that many are compared letter by letter.
+type spellingIndex struct {
+	mu     sync.RWMutex
+	words  []spellingWord
+	byWord map[string]int32
+	grams  map[string][]int32
+	docs   map[primitive.ObjectID][]int32 // Words of each anime, to count them down when it changes
+}
+
+type spellingWord struct {
+	Text  string
+	Count int // Anime with the word in one of their titles
+}
+
+var (
+	spelling = newSpellingIndex()
+
+	// Changes only count words up and down; words that fall out of use are dropped by the
+	// daily rebuild
+	spellingRunner = newCatalogIndex("spelling", SPELLING_REBUILD_INTERVAL, rebuildSpellingIndex, spelling.apply)
+)
+
+func newSpellingIndex() *spellingIndex {
+	return &spellingIndex{
+		byWord: make(map[string]int32),
+		grams:  make(map[string][]int32),
+		docs:   make(map[primitive.ObjectID][]int32),
+	}
+}
+
+// StartSpellingIndex builds the title dictionary and keeps it in step with the catalog
+func StartSpellingIndex() {
+	spellingRunner.run()
+}
+
+// rebuildSpellingIndex loads the words of every catalog title into a fresh index and swaps it in
+func rebuildSpellingIndex() error {
+	if config.Collection == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	opts := options.Find().SetProjection(bson.M{"name": 1, "alternative_titles": 1})
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(nil), opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	fresh := newSpellingIndex()
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		_, keys := buildTitleKeys(anime)
+		fresh.add(anime.ID, keys)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	spelling.mu.Lock()
+	spelling.words, spelling.byWord, spelling.grams, spelling.docs = fresh.words, fresh.byWord, fresh.grams, fresh.docs
+	spelling.mu.Unlock()
+	log.Printf("Spelling index built with %d words", len(fresh.words))
+	return nil
+}
+
+// apply adds, replaces and removes the words of changed anime
+func (s *spellingIndex) apply(events []CatalogEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		s.remove(event.Anime.ID)
+		if event.Type != CatalogAnimeRemoved {
+			_, keys := buildTitleKeys(event.Anime)
+			s.add(event.Anime.ID, keys)
+		}
+	}
+}
+
+// add counts the distinct words of an anime's normalized titles. The caller holds the lock.
+func (s *spellingIndex) add(id primitive.ObjectID, keys []string) {
+	var words []int32
+	seen := make(map[int32]bool)
+	for _, key := range keys {
+		for _, word := range strings.Fields(key) {
+			idx, ok := s.byWord[word]
+			if !ok {
+				idx = int32(len(s.words))
+				s.words = append(s.words, spellingWord{Text: word})
+				s.byWord[word] = idx
+				for _, gram := range trigrams(word) {
+					s.grams[gram] = append(s.grams[gram], idx)
+				}
+			}
+			if !seen[idx] {
+				seen[idx] = true
+				s.words[idx].Count++
+				words = append(words, idx)
+			}
+		}
+	}
+	s.docs[id] = words
+}
+
+// remove counts down the words of an anime. Words no title uses any more stay in the index
+// with no count until the next rebuild. The caller holds the lock.
+func (s *spellingIndex) remove(id primitive.ObjectID) {
+	for _, idx := range s.docs[id] {
+		s.words[idx].Count--
+	}
+	delete(s.docs, id)
+}
+
+// known reports whether a title uses the word. The caller holds the read lock.
+func (s *spellingIndex) known(word string) bool {
+	idx, ok := s.byWord[word]
+	return ok && s.words[idx].Count > 0
+}
+
+// corrections returns the title words within a few edits of word, nearest and most common
+// first. Short words allow one edit and longer ones two. The caller holds the read lock.
+func (s *spellingIndex) corrections(word string) []string {
+	length := len([]rune(word))
+	maxDistance := 1
+	if length > 4 {
+		maxDistance = 2
+	}
+
+	grams := trigrams(word)
+	shared := make(map[int32]int)
+	for _, gram := range grams {
+		for _, idx := range s.grams[gram] {
+			shared[idx]++
+		}
+	}
+	need := max(len(grams)-3*maxDistance, 1)
+
+	type candidate struct {
+		Word     spellingWord
+		Distance int
+	}
+	var candidates []candidate
+	for idx, n := range shared {
+		w := s.words[idx]
+		if n < need || w.Count == 0 {
+			continue
+		}
+		if diff := len([]rune(w.Text)) - length; diff > maxDistance || -diff > maxDistance {
+			continue
+		}
+		if d := editDistance(word, w.Text, maxDistance); d <= maxDistance {
+			candidates = append(candidates, candidate{Word: w, Distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Word.Count != b.Word.Count {
+			return a.Word.Count > b.Word.Count
+		}
+		return a.Word.Text < b.Word.Text
+	})
+	words := make([]string, 0, SPELLING_MAX_SUGGESTIONS)
+	for _, c := range candidates[:min(len(candidates), SPELLING_MAX_SUGGESTIONS)] {
+		words = append(words, c.Word.Text)
+	}
+	return words
+}
+
+// DidYouMean returns up to SPELLING_MAX_SUGGESTIONS respellings of a query whose text has words
+// no catalog title uses, best first, with the query's field filters kept. The first respells
+// every such word its likeliest way; the others try another spelling of one word. It returns
+// nil when every word is known or none has a close match.
+func DidYouMean(q *SearchQuery) []string {
+	words := strings.Fields(NormalizeTitle(q.Text))
+	spellings := make([][]string, len(words))
+	changed := false
+
+	spelling.mu.RLock()
+	for i, word := range words {
+		spellings[i] = []string{word}
+		if len([]rune(word)) < SPELLING_MIN_WORD || strings.IndexFunc(word, unicode.IsDigit) >= 0 || spelling.known(word) {
+			continue
+		}
+		if fixes := spelling.corrections(word); len(fixes) > 0 {
+			spellings[i] = fixes
+			changed = true
+		}
+	}
+	spelling.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	best := make([]string, len(words))
+	for i := range spellings {
+		best[i] = spellings[i][0]
+	}
+	phrases := [][]string{best}
+	for i := range spellings {
+		for _, alternative := range spellings[i][1:] {
+			phrase := append([]string(nil), best...)
+			phrase[i] = alternative
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	suggestions := make([]string, 0, SPELLING_MAX_SUGGESTIONS)
+	for _, phrase := range phrases[:min(len(phrases), SPELLING_MAX_SUGGESTIONS)] {
+		respelled := &SearchQuery{Text: strings.Join(phrase, " "), Clauses: q.Clauses}
+		suggestions = append(suggestions, respelled.String())
+	}
+	return suggestions
+}
+
+// trigrams returns the distinct three-letter runs of text, padded so the first and last
+// letters of each word count as much as the middle ones
+func trigrams(text string) []string {
+	var grams []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		runes := []rune("$$" + word + "$$")
+		for i := 0; i+3 <= len(runes); i++ {
+			gram := string(runes[i : i+3])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
+
+// trigramSimilarity is the share of trigrams two texts have in common, from 0 to 1
+func trigramSimilarity(a, b string) float64 {
+	gramsA, gramsB := trigrams(a), trigrams(b)
+	if len(gramsA)+len(gramsB) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool, len(gramsA))
+	for _, gram := range gramsA {
+		inA[gram] = true
+	}
+	shared := 0
+	for _, gram := range gramsB {
+		if inA[gram] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(gramsA)+len(gramsB))
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of adjacent letters
+// that turn a into b. It gives up once the distance must exceed limit, returning limit+1.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	row := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		row[0] = i
+		best := row[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			row[j] = min(min(prev[j]+1, row[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				row[j] = min(row[j], prev2[j-2]+1)
+			}
+			best = min(best, row[j])
+		}
+		if best > limit {
+			return limit + 1
+		}
+		prev2, prev, row = prev, row, prev2
+	}
+	return min(prev[len(rb)], limit+1)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestSpellingIndex(names ...string) (*spellingIndex, []model.Anime) {
+	index := newSpellingIndex()
+	var animes []model.Anime
+	var events []CatalogEvent
+	for _, name := range names {
+		anime := model.Anime{ID: primitive.NewObjectID(), Name: name}
+		animes = append(animes, anime)
+		events = append(events, CatalogEvent{Type: CatalogAnimeAdded, Anime: anime})
+	}
+	index.apply(events)
+	return index, animes
+}
+
+var spellingTitles = []string{"Naruto Shippuden", "Naruto", "Boruto", "Cat Street", "Cat Planet", "Cot", "Cute High"}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"$$a", "$a$", "a$$"}},
+		{"cat", []string{"$$c", "$ca", "cat", "at$", "t$$"}},
+		{"aa aa", []string{"$$a", "$aa", "aa$", "a$$"}},
+		{"ナルト", []string{"$$ナ", "$ナル", "ナルト", "ルト$", "ト$$"}},
+	}
+
+	for _, tt := range tests {
+		if got := trigrams(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("trigrams(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"naruto", "naruto", 1},
+		{"", "", 0},
+		{"cat", "", 0},
+		{"abc", "xyz", 0},
+		{"cat", "cut", 0.4}, // "$$c" and "t$$" of five each
+	}
+
+	for _, tt := range tests {
+		if got := trigramSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("trigramSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := trigramSimilarity(tt.b, tt.a); got != tt.want {
+			t.Errorf("trigramSimilarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"", "", 2, 0},
+		{"cat", "cat", 2, 0},
+		{"cat", "cut", 2, 1},
+		{"cat", "cats", 2, 1},
+		{"cats", "cat", 2, 1},
+		{"cat", "act", 2, 1},
+		{"nartuo", "naruto", 2, 1},
+		{"shipuden", "shippuden", 2, 1},
+		{"ナルト", "ナルド", 1, 1},
+		{"kitten", "sitting", 5, 3},
+		{"kitten", "sitting", 1, 2}, // Gives up past the limit
+		{"abc", "", 1, 2},
+		{"", "abc", 1, 2},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSpellingCorrections(t *testing.T) {
+	index, _ := newTestSpellingIndex(spellingTitles...)
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"narutp", []string{"naruto"}},
+		{"nartuo", []string{"naruto"}},
+		{"shipuden", []string{"shippuden"}},
+		{"cut", []string{"cat", "cot", "cute"}}, // Nearest, then most common, then alphabetical
+		{"naxxxo", []string{}},                  // Three edits away
+		{"zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := index.corrections(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("corrections(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestSpellingIndexApply(t *testing.T) {
+	index, animes := newTestSpellingIndex("Cat Street", "Cat Planet")
+	if !index.known("cat") || !index.known("street") {
+		t.Fatal("title words are not known")
+	}
+
+	index.apply([]CatalogEvent{{Type: CatalogAnimeRemoved, Anime: animes[0]}})
+	if index.known("street") {
+		t.Error("a word of a removed anime is still known")
+	}
+	if !index.known("cat") {
+		t.Error("a word still used by another anime was forgotten")
+	}
+	if got := index.corrections("stret"); len(got) != 0 {
+		t.Errorf("corrections(stret) = %q, want none after its anime was removed", got)
+	}
+
+	renamed := animes[1]
+	renamed.Name = "Dog Planet"
+	index.apply([]CatalogEvent{{Type: CatalogAnimeUpdated, Anime: renamed}})
+	if index.known("cat") || !index.known("dog") || !index.known("planet") {
+		t.Error("a renamed anime's words were not updated")
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	saved := spelling
+	defer func() { spelling = saved }()
+	spelling, _ = newTestSpellingIndex(spellingTitles...)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"narutp shipuden", []string{"naruto shippuden"}},
+		{"Nartuo xy", []string{"naruto xy"}}, // Short words are left as typed
+		{"genre:action narutp", []string{`genre:"action" naruto`}},
+		{"cut narutp", []string{"cat naruto", "cot naruto", "cute naruto"}},
+		{"naruto", nil},
+		{"narutp2", nil},
+		{"zzzzzz", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseSearchQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := DidYouMean(q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DidYouMean(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -120,10 +120,10 @@ func GetTopRatedMixed() ([]models.Anime, error) {
 	var allAnime []models.Anime
 
 	for _, genre := range genres {
-		filter := bson.M{
+		filter := PublicCatalogFilter(bson.M{
 			"genre": bson.M{"$in": []string{genre}},
 			"score": bson.M{"$gte": 7.0},
-		}
+		})
 		
 		opts := options.Find().SetLimit(2).SetSort(bson.D{{"score", -1}})
 		
@@ -174,24 +174,13 @@ func verifyAndEnhanceScores(animes []models.Anime) ([]models.Anime, error) {
 }
 
 func getScoreFromAniList(animeName string) (float64, error) {
-	query := fmt.Sprintf(`{
+	query := `query ($search: String) {
 		Page(page: 1, perPage: 1) {
-			media(search: "%s", type: ANIME) {
+			media(search: $search, type: ANIME) {
 				averageScore
 			}
 		}
-	}`, animeName)
-
-	requestBody := map[string]interface{}{
-		"query": query,
-	}
-
-	jsonData, _ := json.Marshal(requestBody)
-	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
+	}`
 
 	var result struct {
 		Data struct {
@@ -203,7 +192,7 @@ func getScoreFromAniList(animeName string) (float64, error) {
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := QueryAniList(query, map[string]interface{}{"search": animeName}, &result); err != nil {
 		return 0, err
 	}
 
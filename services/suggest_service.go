@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SUGGEST_MAX_LIMIT        = 20
+	SUGGEST_MAX_WORD_STARTS  = 6 // Words of a title that a suggestion can start matching from
+	SUGGEST_REBUILD_INTERVAL = 24 * time.Hour
+	SUGGEST_HOT_SCAN         = 2000  // Prefixes matching more keys than this have their ranking cached
+	SUGGEST_HOT_PREFIXES     = 10000 // Cached rankings kept before the cache starts over
+
+	suggestTitleStartBonus = 3.0 // The prefix starts the title rather than a later word
+	suggestExactBonus      = 2.0 // The prefix is the whole title
+	suggestMainNameBonus   = 0.5 // Matched the main name rather than an alternative title
+)
+
+// Suggestion is one typeahead result
+type Suggestion struct {
+	ID       primitive.ObjectID `json:"_id"`
+	Name     string             `json:"name"`
+	Matched  string             `json:"matched,omitempty"` // The alternative title that matched, when not the name
+	ImageUrl string             `json:"imageUrl,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Year     int                `json:"year,omitempty"`
+}
+
+// suggestIndex is a prefix index over every title variant of the catalog. Each title is
+// keyed once from each of its first words, and the keys are kept sorted, so the titles
+// sharing a prefix are one contiguous run found by binary search. Keys are substrings of
+// the normalized titles, which keeps a 30k catalog to a few megabytes.
+type suggestIndex struct {
+	mu      sync.RWMutex
+	entries []suggestEntry
+	docs    []suggestDoc
+	byID    map[primitive.ObjectID]int32
+
+	// Short prefixes such as "s" match tens of thousands of keys, so their rankings are
+	// cached until the index next changes
+	hotMu sync.Mutex
+	hot   map[string][]suggestCandidate
+}
+
+type suggestCandidate struct {
+	Doc     int32
+	Variant int8
+	Score   float64
+}
+
+type suggestEntry struct {
+	Key     string
+	Doc     int32
+	Variant int8 // Index into the doc's titles
+	Start   bool // Key is the whole title rather than a later word onwards
+}
+
+type suggestDoc struct {
+	Suggestion
+	Titles  []string // Display titles, the name first
+	Keys    []string // Normalized titles, parallel to Titles
+	Weight  float64
+	Removed bool
+}
+
+var (
+	suggestions = &suggestIndex{byID: make(map[primitive.ObjectID]int32), hot: make(map[string][]suggestCandidate)}
+
+	// Imports publish in bursts; each batch is merged into the sorted keys in one pass and
+	// the hot prefixes it invalidated are ranked again straight away
+	suggestRunner = newCatalogIndex("suggestion", SUGGEST_REBUILD_INTERVAL, rebuildSuggestIndex, func(batch []CatalogEvent) {
+		suggestions.warm(suggestions.apply(batch))
+	})
+)
+
+// StartSuggestIndex builds the typeahead index and keeps it in step with the catalog
+func StartSuggestIndex() {
+	suggestRunner.run()
+}
+
+// rebuildSuggestIndex loads every catalog title into a fresh index and swaps it in
+func rebuildSuggestIndex() error {
+	if config.Collection == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	opts := options.Find().SetProjection(bson.M{
+		"name": 1, "alternative_titles": 1, "imageUrl": 1, "type": 1, "year": 1, "score": 1, "statistics.members": 1,
+	})
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, PublicCatalogFilter(nil), opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	fresh := &suggestIndex{byID: make(map[primitive.ObjectID]int32)}
+	for cur.Next(ctx) {
+		var anime model.Anime
+		if err := cur.Decode(&anime); err != nil {
+			continue
+		}
+		doc := newSuggestDoc(anime)
+		idx := int32(len(fresh.docs))
+		fresh.docs = append(fresh.docs, doc)
+		fresh.byID[anime.ID] = idx
+		fresh.entries = append(fresh.entries, doc.entries(idx)...)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	sort.Slice(fresh.entries, func(i, j int) bool { return fresh.entries[i].Key < fresh.entries[j].Key })
+
+	suggestions.mu.Lock()
+	suggestions.entries, suggestions.docs, suggestions.byID = fresh.entries, fresh.docs, fresh.byID
+	hot := suggestions.resetHot()
+	suggestions.mu.Unlock()
+
+	log.Printf("Suggestion index built: %d titles, %d keys", len(fresh.docs), len(fresh.entries))
+	suggestions.warm(append(hot, strings.Split("abcdefghijklmnopqrstuvwxyz0123456789", "")...))
+	return nil
+}
+
+func newSuggestDoc(anime model.Anime) suggestDoc {
+	doc := suggestDoc{
+		Suggestion: Suggestion{
+			ID:       anime.ID,
+			Name:     anime.Name,
+			ImageUrl: anime.ImageUrl,
+			Type:     string(anime.Type),
+			Year:     anime.Year,
+		},
+		// Member counts span several orders of magnitude; the score separates titles without any
+		Weight: math.Log1p(float64(anime.Statistics.Members)) + anime.Score/10,
+	}
+
+	seen := make(map[string]bool)
+	titles := append([]string{anime.Name, anime.AlternativeTitles.English, anime.AlternativeTitles.Japanese},
+		anime.AlternativeTitles.Synonyms...)
+	for _, title := range titles {
+		key := NormalizeTitle(title)
+		if key == "" || seen[key] || len(doc.Titles) > math.MaxInt8 {
+			continue
+		}
+		seen[key] = true
+		doc.Titles = append(doc.Titles, title)
+		doc.Keys = append(doc.Keys, key)
+	}
+	return doc
+}
+
+// entries keys each title from its start and from the start of each of its next few words
+func (d suggestDoc) entries(idx int32) []suggestEntry {
+	var entries []suggestEntry
+	for variant, key := range d.Keys {
+		entries = append(entries, suggestEntry{Key: key, Doc: idx, Variant: int8(variant), Start: true})
+		for pos, words := 0, 1; words < SUGGEST_MAX_WORD_STARTS; words++ {
+			next := strings.IndexByte(key[pos:], ' ')
+			if next < 0 {
+				break
+			}
+			pos += next + 1
+			entries = append(entries, suggestEntry{Key: key[pos:], Doc: idx, Variant: int8(variant)})
+		}
+	}
+	return entries
+}
+
+// apply adds, replaces and removes anime. Their old keys are filtered out and their new keys
+// merged in, in one pass over the index however many events there are. It returns the
+// prefixes whose cached rankings it dropped.
+func (s *suggestIndex) apply(events []CatalogEvent) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := make(map[int32]bool)
+	current := make(map[int32]bool)
+	for _, event := range events {
+		idx, ok := s.byID[event.Anime.ID]
+		if ok {
+			stale[idx] = true
+		}
+
+		if event.Type == CatalogAnimeRemoved {
+			if ok {
+				// The slot stays so other entries keep their doc numbers until the next rebuild
+				s.docs[idx] = suggestDoc{Removed: true}
+				delete(s.byID, event.Anime.ID)
+				delete(current, idx)
+			}
+			continue
+		}
+
+		doc := newSuggestDoc(event.Anime)
+		if !ok {
+			idx = int32(len(s.docs))
+			s.docs = append(s.docs, doc)
+			s.byID[doc.ID] = idx
+		} else {
+			s.docs[idx] = doc
+		}
+		current[idx] = true
+	}
+
+	kept := s.entries[:0]
+	for _, entry := range s.entries {
+		if !stale[entry.Doc] {
+			kept = append(kept, entry)
+		}
+	}
+	var added []suggestEntry
+	for idx := range current {
+		added = append(added, s.docs[idx].entries(idx)...)
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Key < added[j].Key })
+
+	merged := make([]suggestEntry, 0, len(kept)+len(added))
+	i, j := 0, 0
+	for i < len(kept) && j < len(added) {
+		if added[j].Key < kept[i].Key {
+			merged = append(merged, added[j])
+			j++
+		} else {
+			merged = append(merged, kept[i])
+			i++
+		}
+	}
+	merged = append(merged, kept[i:]...)
+	merged = append(merged, added[j:]...)
+	s.entries = merged
+	return s.resetHot()
+}
+
+// resetHot drops the cached rankings and returns their prefixes
+func (s *suggestIndex) resetHot() []string {
+	s.hotMu.Lock()
+	defer s.hotMu.Unlock()
+
+	prefixes := make([]string, 0, len(s.hot))
+	for prefix := range s.hot {
+		prefixes = append(prefixes, prefix)
+	}
+	s.hot = make(map[string][]suggestCandidate)
+	return prefixes
+}
+
+// warm ranks the given prefixes ahead of requests, so the first keystroke after a
+// change does not pay for scanning them
+func (s *suggestIndex) warm(prefixes []string) {
+	for _, prefix := range prefixes {
+		s.mu.RLock()
+		s.rank(prefix)
+		s.mu.RUnlock()
+	}
+}
+
+// search returns the position of the first key not below key
+func (s *suggestIndex) search(key string) int {
+	return sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Key >= key })
+}
+
+// SuggestAnime returns up to limit titles starting with the query, or with a later word of
+// the title starting with it, most popular first
+func SuggestAnime(query string, limit int) []Suggestion {
+	prefix := NormalizeTitle(query)
+	if prefix == "" {
+		return []Suggestion{}
+	}
+	if limit <= 0 || limit > SUGGEST_MAX_LIMIT {
+		limit = SUGGEST_MAX_LIMIT
+	}
+
+	suggestions.mu.RLock()
+	defer suggestions.mu.RUnlock()
+
+	ranked := suggestions.rank(prefix)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	results := make([]Suggestion, 0, len(ranked))
+	for _, c := range ranked {
+		doc := suggestions.docs[c.Doc]
+		suggestion := doc.Suggestion
+		if c.Variant > 0 {
+			suggestion.Matched = doc.Titles[c.Variant]
+		}
+		results = append(results, suggestion)
+	}
+	return results
+}
+
+// rank scores every anime with a key starting with prefix and returns the best
+// SUGGEST_MAX_LIMIT. The caller holds the read lock.
+func (s *suggestIndex) rank(prefix string) []suggestCandidate {
+	s.hotMu.Lock()
+	cached, ok := s.hot[prefix]
+	s.hotMu.Unlock()
+	if ok {
+		return cached
+	}
+
+	best := make(map[int32]suggestCandidate)
+	start := s.search(prefix)
+	at := start
+	for ; at < len(s.entries); at++ {
+		entry := s.entries[at]
+		if !strings.HasPrefix(entry.Key, prefix) {
+			break
+		}
+
+		score := s.docs[entry.Doc].Weight
+		if entry.Start {
+			score += suggestTitleStartBonus
+			if entry.Key == prefix {
+				score += suggestExactBonus
+			}
+		}
+		if entry.Variant == 0 {
+			score += suggestMainNameBonus
+		}
+		if current, ok := best[entry.Doc]; !ok || score > current.Score {
+			best[entry.Doc] = suggestCandidate{Doc: entry.Doc, Variant: entry.Variant, Score: score}
+		}
+	}
+
+	ranked := make([]suggestCandidate, 0, len(best))
+	for _, c := range best {
+		ranked = append(ranked, c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Doc < ranked[j].Doc
+	})
+	if len(ranked) > SUGGEST_MAX_LIMIT {
+		ranked = ranked[:SUGGEST_MAX_LIMIT]
+	}
+
+	if at-start > SUGGEST_HOT_SCAN {
+		s.hotMu.Lock()
+		if len(s.hot) >= SUGGEST_HOT_PREFIXES {
+			s.hot = make(map[string][]suggestCandidate)
+		}
+		s.hot[prefix] = ranked
+		s.hotMu.Unlock()
+	}
+	return ranked
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func suggestAnime(name string, members int, alternatives ...string) model.Anime {
+	anime := model.Anime{ID: primitive.NewObjectID(), Name: name}
+	anime.Statistics.Members = members
+	if len(alternatives) > 0 {
+		anime.AlternativeTitles.English = alternatives[0]
+		anime.AlternativeTitles.Synonyms = alternatives[1:]
+	}
+	return anime
+}
+
+func newTestSuggestIndex(animes ...model.Anime) *suggestIndex {
+	index := &suggestIndex{byID: make(map[primitive.ObjectID]int32), hot: make(map[string][]suggestCandidate)}
+	events := make([]CatalogEvent, 0, len(animes))
+	for _, anime := range animes {
+		events = append(events, CatalogEvent{Type: CatalogAnimeAdded, Anime: anime})
+	}
+	index.apply(events)
+	return index
+}
+
+// rankedNames returns the names of the anime ranked for prefix, best first
+func rankedNames(index *suggestIndex, prefix string) []string {
+	names := []string{}
+	for _, c := range index.rank(NormalizeTitle(prefix)) {
+		names = append(names, index.docs[c.Doc].Name)
+	}
+	return names
+}
+
+var (
+	suggestNaruto    = suggestAnime("Naruto", 1000)
+	suggestShippuden = suggestAnime("Naruto Shippuden", 2000000)
+	suggestBoruto    = suggestAnime("Boruto: Naruto Next Generations", 500000)
+	suggestTitan     = suggestAnime("Shingeki no Kyojin", 3000000, "Attack on Titan", "AoT")
+)
+
+func TestSuggestIndexRanking(t *testing.T) {
+	index := newTestSuggestIndex(suggestNaruto, suggestShippuden, suggestBoruto, suggestTitan,
+		suggestAnime("Monster", 1000), suggestAnime("Pluto: Monster", 1000))
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		// Popularity counts for most; starting the title and matching it in full add to it
+		{"naruto", []string{"Naruto Shippuden", "Boruto: Naruto Next Generations", "Naruto"}},
+		{"Naru", []string{"Naruto Shippuden", "Boruto: Naruto Next Generations", "Naruto"}},
+		{"monster", []string{"Monster", "Pluto: Monster"}},
+		{"mon", []string{"Monster", "Pluto: Monster"}},
+		{"naruto s", []string{"Naruto Shippuden"}},
+		{"next gen", []string{"Boruto: Naruto Next Generations"}},
+		{"attack", []string{"Shingeki no Kyojin"}},
+		{"titan", []string{"Shingeki no Kyojin"}},
+		{"Kyoujin", []string{"Shingeki no Kyojin"}},
+		{"aruto", []string{}},
+		{"bleach", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := rankedNames(index, tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rank(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestIndexReportsMatchedTitle(t *testing.T) {
+	saved := suggestions
+	defer func() { suggestions = saved }()
+	suggestions = newTestSuggestIndex(suggestNaruto, suggestTitan)
+
+	tests := []struct {
+		query, name, matched string
+	}{
+		{"shingeki", "Shingeki no Kyojin", ""},
+		{"attack on", "Shingeki no Kyojin", "Attack on Titan"},
+		{"aot", "Shingeki no Kyojin", "AoT"},
+		{"naruto", "Naruto", ""},
+	}
+
+	for _, tt := range tests {
+		results := SuggestAnime(tt.query, 5)
+		if len(results) != 1 || results[0].Name != tt.name || results[0].Matched != tt.matched {
+			t.Errorf("SuggestAnime(%q) = %+v, want %q matched on %q", tt.query, results, tt.name, tt.matched)
+		}
+	}
+	if results := SuggestAnime("  ", 5); len(results) != 0 {
+		t.Errorf("SuggestAnime on a blank query = %+v", results)
+	}
+}
+
+func TestSuggestAnimeLimit(t *testing.T) {
+	saved := suggestions
+	defer func() { suggestions = saved }()
+
+	var animes []model.Anime
+	for i := 0; i < SUGGEST_MAX_LIMIT+5; i++ {
+		animes = append(animes, suggestAnime(fmt.Sprintf("Gundam %d", i), i))
+	}
+	suggestions = newTestSuggestIndex(animes...)
+
+	tests := []struct {
+		limit, want int
+	}{
+		{3, 3},
+		{SUGGEST_MAX_LIMIT, SUGGEST_MAX_LIMIT},
+		{0, SUGGEST_MAX_LIMIT},
+		{-1, SUGGEST_MAX_LIMIT},
+		{SUGGEST_MAX_LIMIT + 1, SUGGEST_MAX_LIMIT},
+	}
+	for _, tt := range tests {
+		if got := len(SuggestAnime("gundam", tt.limit)); got != tt.want {
+			t.Errorf("SuggestAnime(limit %d) returned %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+
+	if first := SuggestAnime("gundam", 1)[0].Name; first != fmt.Sprintf("Gundam %d", SUGGEST_MAX_LIMIT+4) {
+		t.Errorf("most popular suggestion = %q", first)
+	}
+}
+
+func TestSuggestIndexApply(t *testing.T) {
+	index := newTestSuggestIndex(suggestNaruto, suggestShippuden, suggestBoruto)
+
+	renamed := suggestNaruto
+	renamed.Name = "Bleach"
+	index.apply([]CatalogEvent{
+		{Type: CatalogAnimeUpdated, Anime: renamed},
+		{Type: CatalogAnimeRemoved, Anime: suggestShippuden},
+		{Type: CatalogAnimeAdded, Anime: suggestTitan},
+	})
+
+	if !sort.SliceIsSorted(index.entries, func(i, j int) bool { return index.entries[i].Key < index.entries[j].Key }) {
+		t.Fatal("keys are out of order after apply")
+	}
+	if got, want := rankedNames(index, "naruto"), []string{"Boruto: Naruto Next Generations"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rank(suggestNaruto) = %q, want %q", got, want)
+	}
+	if got, want := rankedNames(index, "bleach"), []string{"Bleach"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rank(bleach) = %q, want %q", got, want)
+	}
+	if got, want := rankedNames(index, "attack"), []string{"Shingeki no Kyojin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rank(attack) = %q, want %q", got, want)
+	}
+
+	// Removing an anime that is not indexed changes nothing
+	before := len(index.entries)
+	index.apply([]CatalogEvent{{Type: CatalogAnimeRemoved, Anime: suggestAnime("Unknown", 0)}})
+	if len(index.entries) != before {
+		t.Errorf("removing an unknown anime changed the index from %d to %d keys", before, len(index.entries))
+	}
+}
+
+func TestSuggestIndexCachesHotPrefixes(t *testing.T) {
+	var animes []model.Anime
+	for i := 0; i <= SUGGEST_HOT_SCAN; i++ {
+		animes = append(animes, suggestAnime(fmt.Sprintf("Series %d", i), i))
+	}
+	index := newTestSuggestIndex(animes...)
+
+	index.rank("series")
+	index.rank("series 1999")
+	if _, ok := index.hot["series"]; !ok {
+		t.Error("a prefix matching every title was not cached")
+	}
+	if _, ok := index.hot["series 1999"]; ok {
+		t.Error("a prefix matching one title was cached")
+	}
+
+	if dropped := index.apply([]CatalogEvent{{Type: CatalogAnimeAdded, Anime: suggestAnime("Series X", 1<<30)}}); !reflect.DeepEqual(dropped, []string{"series"}) {
+		t.Errorf("apply dropped %q, want the cached prefix", dropped)
+	}
+	if got := rankedNames(index, "series"); len(got) == 0 || got[0] != "Series X" {
+		t.Errorf("a stale ranking survived the change: %q", got)
+	}
+}
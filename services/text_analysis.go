@@ -0,0 +1,117 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stopWords are common English words that carry no meaning for matching
+var stopWords = map[string]bool{
+	"a": true, "about": true, "after": true, "again": true, "against": true, "all": true, "also": true,
+	"an": true, "and": true, "any": true, "are": true, "as": true, "at": true, "be": true, "because": true,
+	"been": true, "before": true, "being": true, "between": true, "both": true, "but": true, "by": true,
+	"can": true, "could": true, "did": true, "do": true, "does": true, "doing": true, "down": true,
+	"during": true, "each": true, "even": true, "ever": true, "few": true, "for": true, "from": true,
+	"further": true, "had": true, "has": true, "have": true, "having": true, "he": true, "her": true,
+	"here": true, "hers": true, "herself": true, "him": true, "himself": true, "his": true, "how": true,
+	"however": true, "i": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"itself": true, "just": true, "me": true, "more": true, "most": true, "much": true, "must": true,
+	"my": true, "no": true, "nor": true, "not": true, "now": true, "of": true, "off": true, "on": true,
+	"once": true, "one": true, "only": true, "or": true, "other": true, "our": true, "out": true,
+	"over": true, "own": true, "same": true, "she": true, "should": true, "so": true, "some": true,
+	"such": true, "than": true, "that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "those": true, "through": true, "to": true,
+	"too": true, "under": true, "until": true, "up": true, "upon": true, "very": true, "was": true,
+	"we": true, "were": true, "what": true, "when": true, "where": true, "which": true, "while": true,
+	"who": true, "whom": true, "why": true, "will": true, "with": true, "within": true, "would": true,
+	"yet": true, "you": true, "your": true, "written": true, "source": true, "mal": true, "rewrite": true,
+}
+
+// tokenizeText splits text into lower-cased, stemmed terms, dropping markup and stop words
+func tokenizeText(text string) []string {
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 2 || stopWords[word] {
+			continue
+		}
+		terms = append(terms, stemTerm(word))
+	}
+	return terms
+}
+
+// stemTerm strips common English inflections so "fighting", "fights" and "fight" match.
+// It is deliberately conservative: short words and names are left alone.
+func stemTerm(term string) string {
+	if len(term) <= 4 {
+		return term
+	}
+
+	switch {
+	case strings.HasSuffix(term, "ies") && len(term) > 5:
+		return term[:len(term)-3] + "y"
+	case strings.HasSuffix(term, "sses"):
+		return term[:len(term)-2]
+	case strings.HasSuffix(term, "ness") && len(term) > 6:
+		return term[:len(term)-4]
+	case strings.HasSuffix(term, "ing") && len(term) > 6:
+		return undoubleConsonant(term[:len(term)-3])
+	case strings.HasSuffix(term, "ed") && len(term) > 5:
+		return undoubleConsonant(term[:len(term)-2])
+	case strings.HasSuffix(term, "ly") && len(term) > 5:
+		return term[:len(term)-2]
+	case strings.HasSuffix(term, "s") && !strings.HasSuffix(term, "ss") && !strings.HasSuffix(term, "us") && !strings.HasSuffix(term, "is"):
+		return term[:len(term)-1]
+	}
+	return term
+}
+
+// undoubleConsonant turns "runn" (from "running") back into "run"
+func undoubleConsonant(stem string) string {
+	n := len(stem)
+	if n >= 3 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeioulsz", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+// searchTerms tokenizes text for the search index and returns each term once. Japanese and
+// Chinese titles have no spaces between words, so those are also split into character pairs.
+func searchTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	for _, term := range tokenizeText(text) {
+		add(term)
+		runes := []rune(term)
+		if len(runes) <= 2 || !isCJK(runes) {
+			continue
+		}
+		for i := 0; i+2 <= len(runes); i++ {
+			add(string(runes[i : i+2]))
+		}
+	}
+	return terms
+}
+
+func isCJK(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			return true
+		}
+	}
+	return false
+}
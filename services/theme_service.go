@@ -1,11 +1,9 @@
 package services
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 )
 
 type ThemeData struct {
@@ -25,9 +23,9 @@ func GetAnimeThemes(animeName string) (*ThemeData, error) {
 }
 
 func fetchThemesFromAniList(animeName string) (*ThemeData, error) {
-	query := fmt.Sprintf(`{
+	query := `query ($search: String) {
 		Page(page: 1, perPage: 1) {
-			media(search: "%s", type: ANIME) {
+			media(search: $search, type: ANIME) {
 				title {
 					romaji
 					english
@@ -38,22 +36,7 @@ func fetchThemesFromAniList(animeName string) (*ThemeData, error) {
 				}
 			}
 		}
-	}`, animeName)
-
-	requestBody := map[string]interface{}{
-		"query": query,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	}`
 
 	var anilistResp struct {
 		Data struct {
@@ -72,7 +55,7 @@ func fetchThemesFromAniList(animeName string) (*ThemeData, error) {
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&anilistResp); err != nil {
+	if err := QueryAniList(query, map[string]interface{}{"search": animeName}, &anilistResp); err != nil {
 		return nil, err
 	}
 
@@ -93,7 +76,7 @@ func fetchThemesFromAniList(animeName string) (*ThemeData, error) {
 
 func fetchThemesFromMAL(animeName string) (*ThemeData, error) {
 	// Search for anime on Jikan API
-	searchURL := fmt.Sprintf("https://api.jikan.moe/v4/anime?q=%s&limit=1", strings.ReplaceAll(animeName, " ", "%20"))
+	searchURL := jikanSearchURL(animeName, 1)
 	
 	resp, err := http.Get(searchURL)
 	if err != nil {
@@ -144,31 +127,16 @@ func fetchThemesFromMAL(animeName string) (*ThemeData, error) {
 }
 
 func GetHighQualityImages(animeName string) (string, string, error) {
-	query := fmt.Sprintf(`{
+	query := `query ($search: String) {
 		Page(page: 1, perPage: 1) {
-			media(search: "%s", type: ANIME) {
+			media(search: $search, type: ANIME) {
 				coverImage {
 					extraLarge
 				}
 				bannerImage
 			}
 		}
-	}`, animeName)
-
-	requestBody := map[string]interface{}{
-		"query": query,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", "", err
-	}
-
-	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", "", err
-	}
-	defer resp.Body.Close()
+	}`
 
 	var anilistResp struct {
 		Data struct {
@@ -183,7 +151,7 @@ func GetHighQualityImages(animeName string) (string, string, error) {
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&anilistResp); err != nil {
+	if err := QueryAniList(query, map[string]interface{}{"search": animeName}, &anilistResp); err != nil {
 		return "", "", err
 	}
 
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+)
+
+// kanaRomaji is the Hepburn reading of each hiragana; katakana are read through their hiragana
+var kanaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa",
+	'ヷ': "va", 'ヸ': "vi", 'ヹ': "ve", 'ヺ': "vo", // Katakana only
+}
+
+// Small kana that merge with the syllable before them, as in きゃ "kya" and ファ "fa"
+var (
+	smallYKana     = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+	smallVowelKana = map[rune]string{'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o"}
+)
+
+// longVowels folds the romanizations of long vowels together, so "Kyoujin", "Kyōjin" and
+// "Kyojin" share a key. Macrons are already gone by the time it runs.
+var longVowels = strings.NewReplacer("ou", "o", "oo", "o", "uu", "u")
+
+// titleKeysReady is set once every anime, catalog and list copies alike, has its title keys
+var titleKeysReady atomic.Bool
+
+func init() {
+	// Keep each catalog anime's title keys in step with its titles
+	OnCatalogChange(func(event CatalogEvent) {
+		if event.Type == CatalogAnimeRemoved {
+			return
+		}
+		nameKey, titleKeys := buildTitleKeys(event.Anime)
+		if nameKey == event.Anime.NameKey && sameStrings(titleKeys, event.Anime.TitleKeys) {
+			return
+		}
+		config.Collection.UpdateOne(context.Background(), PublicCatalogFilter(bson.M{"_id": event.Anime.ID}),
+			bson.M{"$set": bson.M{"name_key": nameKey, "title_keys": titleKeys}})
+	})
+}
+
+// NormalizeTitle reduces a title to the key that its spelling variants share. Full-width
+// characters become half-width, kana become Hepburn romaji, accents and macrons are dropped,
+// long vowels are folded and punctuation and symbols separate words. "Shingeki no Kyoujin",
+// "Shingeki no Kyōjin" and "ＳＨＩＮＧＥＫＩ ＮＯ ＫＹＯＪＩＮ" all become "shingeki no kyojin",
+// and "Re:Zero kara Hajimeru" becomes "re zero kara hajimeru".
+func NormalizeTitle(title string) string {
+	title = kanaToRomaji(strings.ToLower(norm.NFKC.String(title)))
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(title) {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’', r == '`':
+			// Accents, macrons and apostrophes, as in "JoJo's"
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	for i, word := range words {
+		words[i] = longVowels.Replace(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// kanaToRomaji spells hiragana and katakana in romaji, setting each run of kana apart from
+// surrounding kanji as a word of its own
+func kanaToRomaji(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	inKana := false
+
+	for i := 0; i < len(runes); i++ {
+		r := toHiragana(runes[i])
+		syllable, ok := kanaRomaji[r]
+		if !ok && r != 'っ' && r != 'ー' {
+			if inKana {
+				b.WriteByte(' ')
+				inKana = false
+			}
+			b.WriteRune(runes[i])
+			continue
+		}
+		if !inKana {
+			b.WriteByte(' ')
+			inKana = true
+		}
+
+		switch r {
+		case 'ー':
+			// Lengthens the vowel before it, which folding removes anyway
+			continue
+		case 'っ':
+			// Doubles the consonant of the next syllable, as in がっこう "gakkou"
+			if i+1 < len(runes) {
+				if next := kanaRomaji[toHiragana(runes[i+1])]; next != "" && !strings.ContainsRune("aiueon", rune(next[0])) {
+					if strings.HasPrefix(next, "ch") {
+						b.WriteByte('t')
+					} else {
+						b.WriteByte(next[0])
+					}
+				}
+			}
+			continue
+		}
+
+		if i+1 < len(runes) {
+			next := toHiragana(runes[i+1])
+			if vowel, ok := smallYKana[next]; ok && len(syllable) > 1 && strings.HasSuffix(syllable, "i") {
+				stem := syllable[:len(syllable)-1]
+				if stem != "sh" && stem != "ch" && stem != "j" {
+					stem += "y"
+				}
+				b.WriteString(stem + vowel)
+				i++
+				continue
+			}
+			if vowel, ok := smallVowelKana[next]; ok {
+				stem := syllable[:len(syllable)-1]
+				if syllable == "u" {
+					stem = "w"
+				}
+				if stem != "" {
+					b.WriteString(stem + vowel)
+					i++
+					continue
+				}
+			}
+		}
+		b.WriteString(syllable)
+	}
+	return b.String()
+}
+
+// toHiragana maps katakana to the hiragana with the same sound
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// buildTitleKeys returns the normalized name of an anime and the distinct normalized forms
+// of all its titles, the name's first
+func buildTitleKeys(anime model.Anime) (string, []string) {
+	nameKey := NormalizeTitle(anime.Name)
+
+	var keys []string
+	seen := make(map[string]bool)
+	titles := append([]string{anime.Name, anime.AlternativeTitles.English, anime.AlternativeTitles.Japanese},
+		anime.AlternativeTitles.Synonyms...)
+	for _, title := range titles {
+		key := NormalizeTitle(title)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return nameKey, keys
+}
+
+// BackfillTitleKeys stores the title keys of a batch of anime that have none yet, list copies
+// included since lists sort by title too. It returns how many were updated and whether more remain.
+func BackfillTitleKeys() (int, bool, error) {
+	if config.Collection == nil {
+		return 0, false, fmt.Errorf("database not initialized")
+	}
+
+	filter := bson.M{"name_key": bson.M{"$exists": false}}
+	opts := options.Find().
+		SetProjection(bson.M{"name": 1, "alternative_titles": 1}).
+		SetLimit(BACKFILL_BATCH)
+
+	ctx := context.Background()
+	cur, err := config.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, false, err
+	}
+	var animes []model.Anime
+	if err := cur.All(ctx, &animes); err != nil {
+		return 0, false, err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(animes))
+	for _, anime := range animes {
+		nameKey, titleKeys := buildTitleKeys(anime)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": anime.ID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"name_key":   nameKey,
+				"title_keys": titleKeys,
+			}}))
+	}
+	if len(writes) > 0 {
+		if _, err := config.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return 0, false, err
+		}
+	}
+
+	more := len(animes) == BACKFILL_BATCH
+	if !more {
+		titleKeysReady.Store(true)
+	}
+	return len(animes), more, nil
+}
+
+// TitleKeysReady reports whether every anime has its title keys, so sorting by title is complete
+func TitleKeysReady() bool {
+	return titleKeysReady.Load()
+}
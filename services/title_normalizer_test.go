@@ -0,0 +1,73 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	model "animeverse/models"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"", ""},
+		{"Shingeki no Kyoujin", "shingeki no kyojin"},
+		{"Shingeki no Kyōjin", "shingeki no kyojin"},
+		{"Shingeki no Kyojin", "shingeki no kyojin"},
+		{"ＳＨＩＮＧＥＫＩ ＮＯ ＫＹＯＪＩＮ", "shingeki no kyojin"},
+		{"Re:Zero kara Hajimeru Isekai Seikatsu", "re zero kara hajimeru isekai seikatsu"},
+		{"  Steins;Gate  ", "steins gate"},
+		{"JoJo's Bizarre Adventure", "jojos bizarre adventure"},
+		{"Pokémon", "pokemon"},
+		{"Mob Psycho 100", "mob psycho 100"},
+		{"ナルト", "naruto"},
+		{"しんげき", "shingeki"},
+		{"ワンピース", "wanpisu"},
+		{"がっこう", "gakko"},
+		{"マッチ", "matchi"},
+		{"きゃ", "kya"},
+		{"しょ", "sho"},
+		{"ファイト", "faito"},
+		{"進撃の巨人", "進撃 no 巨人"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTitleIsStable(t *testing.T) {
+	for _, title := range []string{"Shingeki no Kyōjin", "Re:Zero", "ナルト", "進撃の巨人", "Ｋ－ＯＮ！"} {
+		key := NormalizeTitle(title)
+		if again := NormalizeTitle(key); again != key {
+			t.Errorf("NormalizeTitle(%q) = %q, but normalizing it again gives %q", title, key, again)
+		}
+	}
+}
+
+func TestBuildTitleKeys(t *testing.T) {
+	anime := model.Anime{
+		Name: "Shingeki no Kyojin",
+		AlternativeTitles: model.AlternativeTitles{
+			English:  "Attack on Titan",
+			Japanese: "進撃の巨人",
+			Synonyms: []string{"Shingeki no Kyoujin", "AoT", "", "!!"},
+		},
+	}
+
+	nameKey, keys := buildTitleKeys(anime)
+	if nameKey != "shingeki no kyojin" {
+		t.Errorf("name key = %q, want %q", nameKey, "shingeki no kyojin")
+	}
+	want := []string{"shingeki no kyojin", "attack on titan", "進撃 no 巨人", "aot"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("title keys = %q, want %q", keys, want)
+	}
+
+	if nameKey, keys := buildTitleKeys(model.Anime{}); nameKey != "" || len(keys) != 0 {
+		t.Errorf("an anime without titles has keys %q, %q", nameKey, keys)
+	}
+}
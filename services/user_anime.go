@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"animeverse/config"
@@ -9,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func AddAnimeToUserList(userID, animeName string, status model.WatchStatus) (*model.Anime, error) {
@@ -49,6 +51,7 @@ func AddAnimeToUserList(userID, animeName string, status model.WatchStatus) (*mo
 		// If still not found, create basic entry
 		if anime.Name == "" {
 			anime = model.Anime{
+				ID:        primitive.NewObjectID(),
 				UserID:    userID,
 				Name:      animeName,
 				Type:      "TV",
@@ -69,6 +72,7 @@ func AddAnimeToUserList(userID, animeName string, status model.WatchStatus) (*mo
 		return nil, err
 	}
 
+	listChanged(userID, "added", anime)
 	return &anime, nil
 }
 
@@ -98,6 +102,9 @@ func UpdateAnimeStatus(userID, animeID string, status model.WatchStatus) (*model
 	// Return updated anime
 	var anime model.Anime
 	err = config.Collection.FindOne(context.Background(), filter).Decode(&anime)
+	if err == nil {
+		listChanged(userID, "status_changed", anime)
+	}
 	return &anime, err
 }
 
@@ -126,9 +133,56 @@ func UpdateAnimeScore(userID, animeID string, score int) (*model.Anime, error) {
 
 	var anime model.Anime
 	err = config.Collection.FindOne(context.Background(), filter).Decode(&anime)
+	if err == nil {
+		listChanged(userID, "score_changed", anime)
+	}
 	return &anime, err
 }
 
+// UpdateAnimeProgress sets how many episodes of a list entry the user has watched
+func UpdateAnimeProgress(userID, animeID string, watched int) (*model.Anime, error) {
+	objID, err := primitive.ObjectIDFromHex(animeID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"_id":     objID,
+		"user_id": userID,
+	}
+
+	var previous model.Anime
+	if err := config.Collection.FindOne(context.Background(), filter).Decode(&previous); err != nil {
+		return nil, err
+	}
+	if watched < 0 || (previous.Progress.Total > 0 && watched > previous.Progress.Total) {
+		return nil, fmt.Errorf("watched episodes must be between 0 and %d", previous.Progress.Total)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"progress.watched": watched,
+			"updated_at":       time.Now(),
+		},
+	}
+
+	var anime model.Anime
+	err = config.Collection.FindOneAndUpdate(context.Background(), filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&anime)
+	if err != nil {
+		return nil, err
+	}
+
+	if anime.Progress.Watched != previous.Progress.Watched {
+		listChanged(userID, "progress_changed", anime)
+	}
+	if anime.Progress.Watched > previous.Progress.Watched {
+		EmitEpisodeWatched(userID, anime, previous.Progress.Watched)
+	}
+	return &anime, nil
+}
+
 func RemoveAnimeFromUserList(userID, animeID string) error {
 	objID, err := primitive.ObjectIDFromHex(animeID)
 	if err != nil {
@@ -140,8 +194,17 @@ func RemoveAnimeFromUserList(userID, animeID string) error {
 		"user_id": userID,
 	}
 
-	_, err = config.Collection.DeleteOne(context.Background(), filter)
-	return err
+	var anime model.Anime
+	err = config.Collection.FindOneAndDelete(context.Background(), filter).Decode(&anime)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	listChanged(userID, "removed", anime)
+	return nil
 }
 
 func SearchAndAddAnime(userID, query string) ([]map[string]interface{}, error) {
@@ -164,4 +227,10 @@ func SearchAndAddAnime(userID, query string) ([]map[string]interface{}, error) {
 	}
 
 	return results, nil
-}
\ No newline at end of file
+}
+
+// listChanged tells webhooks about a list edit and drops caches derived from the list
+func listChanged(userID, action string, anime model.Anime) {
+	InvalidateRecommendations(userID)
+	EmitListUpdated(userID, action, anime)
+}
@@ -44,6 +44,7 @@ func CreateOrUpdateUser(supabaseID, email, name string) (*model.User, error) {
 		}
 		
 		user.ID = result.InsertedID.(primitive.ObjectID)
+		SendWelcomeEmail(user)
 		return &user, nil
 	} else if err != nil {
 		return nil, err
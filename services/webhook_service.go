@@ -0,0 +1,656 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"animeverse/config"
+	model "animeverse/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	WEBHOOK_EVENT_LIST_UPDATED    = "list.updated"
+	WEBHOOK_EVENT_EPISODE_WATCHED = "episode.watched"
+	WEBHOOK_EVENT_ANIME_ADDED     = "anime.added"
+	WEBHOOK_EVENT_IMPORT_FINISHED = "import.finished"
+
+	WEBHOOK_STATUS_PENDING   = "pending"
+	WEBHOOK_STATUS_SUCCEEDED = "succeeded"
+	WEBHOOK_STATUS_FAILED    = "failed"
+
+	WEBHOOK_MAX_PER_OWNER    = 10
+	WEBHOOK_MAX_ATTEMPTS     = 6
+	WEBHOOK_DISABLE_AFTER    = 5 // Consecutive failed deliveries before a webhook is switched off
+	WEBHOOK_TIMEOUT          = 10 * time.Second
+	WEBHOOK_SIGNATURE_HEADER = "X-AnimeVerse-Signature"
+)
+
+// webhookScopeEvents lists the events each kind of webhook may subscribe to
+var webhookScopeEvents = map[model.WebhookScope][]string{
+	model.WebhookScopeUser:  {WEBHOOK_EVENT_LIST_UPDATED, WEBHOOK_EVENT_EPISODE_WATCHED},
+	model.WebhookScopeAdmin: {WEBHOOK_EVENT_ANIME_ADDED, WEBHOOK_EVENT_IMPORT_FINISHED},
+}
+
+var (
+	webhookCollection         *mongo.Collection
+	webhookDeliveryCollection *mongo.Collection
+)
+
+// WebhookInput is the editable part of a webhook
+type WebhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// CreatedWebhook is returned once on creation, the only time the secret is shown
+type CreatedWebhook struct {
+	model.Webhook
+	Secret string `json:"secret"`
+}
+
+// webhookEnvelope is the JSON body sent to webhook endpoints
+type webhookEnvelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func init() {
+	OnCatalogChange(func(event CatalogEvent) {
+		if event.Type == CatalogAnimeAdded {
+			EmitWebhookEvent(WEBHOOK_EVENT_ANIME_ADDED, "", webhookAnimeData(event.Anime))
+		}
+	})
+}
+
+// InitWebhookCollections initializes the webhooks and webhook_deliveries collections
+func InitWebhookCollections() {
+	if config.DB == nil {
+		return
+	}
+
+	webhookCollection = config.GetCollection(config.DB, "webhooks")
+	webhookCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "owner_id", Value: 1}}},
+		{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "events", Value: 1}, {Key: "active", Value: 1}}},
+	})
+
+	webhookDeliveryCollection = config.GetCollection(config.DB, "webhook_deliveries")
+	webhookDeliveryCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "_id", Value: -1}}},
+		// Delivery logs are kept for 30 days
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 3600)},
+	})
+}
+
+func getWebhookCollections() (*mongo.Collection, *mongo.Collection, error) {
+	if webhookCollection == nil || webhookDeliveryCollection == nil {
+		InitWebhookCollections()
+	}
+	if webhookCollection == nil || webhookDeliveryCollection == nil {
+		return nil, nil, fmt.Errorf("webhook collections not initialized")
+	}
+	return webhookCollection, webhookDeliveryCollection, nil
+}
+
+// webhookOwnerFilter restricts queries to webhooks the caller manages.
+// Admin webhooks are shared between all admins.
+func webhookOwnerFilter(scope model.WebhookScope, ownerID string) bson.M {
+	filter := bson.M{"scope": scope}
+	if scope == model.WebhookScopeUser {
+		filter["owner_id"] = ownerID
+	}
+	return filter
+}
+
+// validateWebhookInput checks the URL and that every event is allowed for the scope
+func validateWebhookInput(scope model.WebhookScope, input WebhookInput) error {
+	parsed, err := url.Parse(strings.TrimSpace(input.URL))
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http(s) URL")
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("webhook URL must not contain credentials")
+	}
+
+	if len(input.Events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+	for _, event := range input.Events {
+		if !containsString(webhookScopeEvents[scope], event) {
+			return fmt.Errorf("unsupported event %q, allowed: %s", event, strings.Join(webhookScopeEvents[scope], ", "))
+		}
+	}
+	return nil
+}
+
+// CreateWebhook registers a webhook and generates its signing secret
+func CreateWebhook(scope model.WebhookScope, ownerID string, input WebhookInput) (*CreatedWebhook, error) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookInput(scope, input); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	count, err := webhooks.CountDocuments(ctx, webhookOwnerFilter(scope, ownerID))
+	if err != nil {
+		return nil, err
+	}
+	if count >= WEBHOOK_MAX_PER_OWNER {
+		return nil, fmt.Errorf("webhook limit of %d reached", WEBHOOK_MAX_PER_OWNER)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	webhook := model.Webhook{
+		ID:        primitive.NewObjectID(),
+		OwnerID:   ownerID,
+		Scope:     scope,
+		URL:       strings.TrimSpace(input.URL),
+		Events:    input.Events,
+		Secret:    "whsec_" + hex.EncodeToString(secret),
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := webhooks.InsertOne(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &CreatedWebhook{Webhook: webhook, Secret: webhook.Secret}, nil
+}
+
+// GetWebhooks lists the webhooks the caller manages
+func GetWebhooks(scope model.WebhookScope, ownerID string) ([]model.Webhook, error) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cur, err := webhooks.Find(ctx, webhookOwnerFilter(scope, ownerID), options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	result := []model.Webhook{}
+	if err := cur.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetWebhook returns one webhook the caller manages
+func GetWebhook(scope model.WebhookScope, ownerID, webhookID string) (*model.Webhook, error) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook ID")
+	}
+
+	filter := webhookOwnerFilter(scope, ownerID)
+	filter["_id"] = objID
+
+	var webhook model.Webhook
+	if err := webhooks.FindOne(context.Background(), filter).Decode(&webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook edits a webhook. Re-activating it clears its failure streak.
+func UpdateWebhook(scope model.WebhookScope, ownerID, webhookID string, input WebhookInput) (*model.Webhook, error) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := GetWebhook(scope, ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields left out of the request keep their current value
+	if input.URL == "" {
+		input.URL = existing.URL
+	}
+	if len(input.Events) == 0 {
+		input.Events = existing.Events
+	}
+	if err := validateWebhookInput(scope, input); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{
+		"url":        strings.TrimSpace(input.URL),
+		"events":     input.Events,
+		"updated_at": time.Now(),
+	}
+	update := bson.M{"$set": set}
+	if input.Active != nil {
+		set["active"] = *input.Active
+		if *input.Active {
+			set["consecutive_failures"] = 0
+			update["$unset"] = bson.M{"disabled_at": "", "disabled_reason": ""}
+		}
+	}
+
+	var webhook model.Webhook
+	err = webhooks.FindOneAndUpdate(context.Background(),
+		bson.M{"_id": existing.ID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(scope model.WebhookScope, ownerID, webhookID string) error {
+	webhooks, deliveries, err := getWebhookCollections()
+	if err != nil {
+		return err
+	}
+
+	webhook, err := GetWebhook(scope, ownerID, webhookID)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if _, err := webhooks.DeleteOne(ctx, bson.M{"_id": webhook.ID}); err != nil {
+		return err
+	}
+	_, err = deliveries.DeleteMany(ctx, bson.M{"webhook_id": webhook.ID})
+	return err
+}
+
+// GetWebhookDeliveries returns a page of a webhook's delivery log, newest first
+func GetWebhookDeliveries(scope model.WebhookScope, ownerID, webhookID string, page PageRequest) ([]model.WebhookDelivery, string, error) {
+	_, deliveries, err := getWebhookCollections()
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook, err := GetWebhook(scope, ownerID, webhookID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := []model.WebhookDelivery{}
+	next, err := FindPageInto(context.Background(), deliveries, bson.M{"webhook_id": webhook.ID}, page, nil, &result)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, next, nil
+}
+
+// RedeliverWebhook queues a fresh delivery of a previously sent payload
+func RedeliverWebhook(scope model.WebhookScope, ownerID, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	_, deliveries, err := getWebhookCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := GetWebhook(scope, ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delivery ID")
+	}
+
+	var original model.WebhookDelivery
+	if err := deliveries.FindOne(context.Background(), bson.M{"_id": objID, "webhook_id": webhook.ID}).Decode(&original); err != nil {
+		return nil, err
+	}
+
+	delivery := model.WebhookDelivery{
+		ID:           primitive.NewObjectID(),
+		WebhookID:    webhook.ID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		Status:       WEBHOOK_STATUS_PENDING,
+		Attempts:     []model.WebhookAttempt{},
+		RedeliveryOf: original.ID.Hex(),
+		CreatedAt:    time.Now(),
+	}
+	if err := queueWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// EmitWebhookEvent queues a delivery to every active webhook subscribed to the event.
+// User events only reach the webhooks of the user they are about; admin events
+// reach every admin webhook. The fan-out runs in the background, so the write that
+// raised the event doesn't wait on the webhook lookup.
+func EmitWebhookEvent(event, userID string, data interface{}) {
+	go emitWebhookEvent(event, userID, data)
+}
+
+func emitWebhookEvent(event, userID string, data interface{}) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return
+	}
+
+	filter := bson.M{"events": event, "active": true}
+	if containsString(webhookScopeEvents[model.WebhookScopeUser], event) {
+		if userID == "" {
+			return
+		}
+		filter["scope"] = model.WebhookScopeUser
+		filter["owner_id"] = userID
+	} else {
+		filter["scope"] = model.WebhookScopeAdmin
+	}
+
+	ctx := context.Background()
+	cur, err := webhooks.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to look up webhooks for %s: %v", event, err)
+		return
+	}
+	defer cur.Close(ctx)
+
+	var targets []model.Webhook
+	if err := cur.All(ctx, &targets); err != nil {
+		log.Printf("Failed to look up webhooks for %s: %v", event, err)
+		return
+	}
+
+	for _, webhook := range targets {
+		deliveryID := primitive.NewObjectID()
+		now := time.Now()
+
+		body, err := json.Marshal(webhookEnvelope{
+			ID:        deliveryID.Hex(),
+			Event:     event,
+			CreatedAt: now,
+			Data:      data,
+		})
+		if err != nil {
+			log.Printf("Failed to encode %s webhook payload: %v", event, err)
+			return
+		}
+
+		delivery := model.WebhookDelivery{
+			ID:        deliveryID,
+			WebhookID: webhook.ID,
+			Event:     event,
+			Payload:   string(body),
+			Status:    WEBHOOK_STATUS_PENDING,
+			Attempts:  []model.WebhookAttempt{},
+			CreatedAt: now,
+		}
+		if err := queueWebhookDelivery(delivery); err != nil {
+			log.Printf("Failed to queue %s webhook delivery: %v", event, err)
+		}
+	}
+}
+
+// queueWebhookDelivery stores a pending delivery and hands it to the job queue,
+// attempting it once inline if the queue is unavailable
+func queueWebhookDelivery(delivery model.WebhookDelivery) error {
+	_, deliveries, err := getWebhookCollections()
+	if err != nil {
+		return err
+	}
+	if _, err := deliveries.InsertOne(context.Background(), delivery); err != nil {
+		return err
+	}
+
+	if err := EnqueueWebhookDelivery(delivery.ID.Hex()); err != nil {
+		log.Printf("Webhook queue unavailable, delivering inline: %v", err)
+		go AttemptWebhookDelivery(delivery.ID.Hex(), true)
+	}
+	return nil
+}
+
+// AttemptWebhookDelivery makes one HTTP attempt at a delivery and records the outcome.
+// When final is set a failure is permanent and counts towards auto-disabling the webhook.
+func AttemptWebhookDelivery(deliveryID string, final bool) error {
+	webhooks, deliveries, err := getWebhookCollections()
+	if err != nil {
+		return err
+	}
+
+	objID, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return fmt.Errorf("invalid delivery ID")
+	}
+
+	ctx := context.Background()
+	var delivery model.WebhookDelivery
+	if err := deliveries.FindOne(ctx, bson.M{"_id": objID}).Decode(&delivery); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// Webhook was deleted in the meantime, nothing left to do
+			return nil
+		}
+		return err
+	}
+	if delivery.Status != WEBHOOK_STATUS_PENDING {
+		return nil
+	}
+
+	var webhook model.Webhook
+	if err := webhooks.FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	attempt := sendWebhookRequest(webhook, delivery)
+	update := bson.M{"$push": bson.M{"attempts": attempt}}
+
+	if attempt.Error == "" {
+		now := time.Now()
+		update["$set"] = bson.M{"status": WEBHOOK_STATUS_SUCCEEDED, "completed_at": now}
+		deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+		webhooks.UpdateOne(ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": bson.M{"consecutive_failures": 0}})
+		return nil
+	}
+
+	if !final {
+		deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+		return fmt.Errorf("webhook delivery %s failed: %s", deliveryID, attempt.Error)
+	}
+
+	now := time.Now()
+	update["$set"] = bson.M{"status": WEBHOOK_STATUS_FAILED, "completed_at": now}
+	deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	recordWebhookFailure(webhook.ID)
+	return nil
+}
+
+// recordWebhookFailure extends a webhook's failure streak and disables it once the streak is too long
+func recordWebhookFailure(webhookID primitive.ObjectID) {
+	webhooks, _, err := getWebhookCollections()
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	var webhook model.Webhook
+	err = webhooks.FindOneAndUpdate(ctx,
+		bson.M{"_id": webhookID},
+		bson.M{"$inc": bson.M{"consecutive_failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&webhook)
+	if err != nil || !webhook.Active || webhook.ConsecutiveFailures < WEBHOOK_DISABLE_AFTER {
+		return
+	}
+
+	now := time.Now()
+	webhooks.UpdateOne(ctx, bson.M{"_id": webhookID}, bson.M{"$set": bson.M{
+		"active":          false,
+		"disabled_at":     now,
+		"disabled_reason": fmt.Sprintf("%d consecutive deliveries failed", webhook.ConsecutiveFailures),
+		"updated_at":      now,
+	}})
+	log.Printf("Disabled webhook %s after %d consecutive failed deliveries", webhookID.Hex(), webhook.ConsecutiveFailures)
+}
+
+// sendWebhookRequest POSTs the signed payload and reports what happened
+func sendWebhookRequest(webhook model.Webhook, delivery model.WebhookDelivery) model.WebhookAttempt {
+	start := time.Now()
+	attempt := model.WebhookAttempt{At: start}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AnimeVerse-Webhooks/1.0")
+	req.Header.Set("X-AnimeVerse-Event", delivery.Event)
+	req.Header.Set("X-AnimeVerse-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-AnimeVerse-Timestamp", timestamp)
+	req.Header.Set(WEBHOOK_SIGNATURE_HEADER, SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := webhookHTTPClient.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("endpoint responded with %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// SignWebhookPayload computes the signature header value receivers use to verify a delivery:
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed by the webhook secret
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookHTTPClient refuses to connect to private and loopback addresses so webhooks
+// can't be used to reach internal services. Set WEBHOOK_ALLOW_PRIVATE=true for local testing.
+var webhookHTTPClient = &http.Client{
+	Timeout: WEBHOOK_TIMEOUT,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+					return nil
+				}
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+					ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+					return fmt.Errorf("webhook destination %s is not allowed", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: WEBHOOK_TIMEOUT,
+	},
+	// Redirects could point back at an internal address, so they count as failures
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// webhookAnimeData is the anime summary included in webhook payloads
+func webhookAnimeData(anime model.Anime) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         anime.ID.Hex(),
+		"name":       anime.Name,
+		"type":       anime.Type,
+		"status":     anime.Status,
+		"score":      anime.Score,
+		"progress":   anime.Progress,
+		"year":       anime.Year,
+		"season":     anime.Season,
+		"anilist_id": anime.AniListID,
+		"image_url":  anime.ImageUrl,
+	}
+}
+
+// EmitListUpdated notifies a user's webhooks about a change to their list
+func EmitListUpdated(userID, action string, anime model.Anime) {
+	EmitWebhookEvent(WEBHOOK_EVENT_LIST_UPDATED, userID, map[string]interface{}{
+		"user_id": userID,
+		"action":  action,
+		"anime":   webhookAnimeData(anime),
+	})
+}
+
+// EmitEpisodeWatched notifies a user's webhooks that their progress on an anime went up
+func EmitEpisodeWatched(userID string, anime model.Anime, previous int) {
+	EmitWebhookEvent(WEBHOOK_EVENT_EPISODE_WATCHED, userID, map[string]interface{}{
+		"user_id":          userID,
+		"anime":            webhookAnimeData(anime),
+		"episode":          anime.Progress.Watched,
+		"previous_episode": previous,
+	})
+}
+
+// EmitImportFinished notifies admin webhooks that a catalog import has run
+func EmitImportFinished(source string, imported int, importErr error) {
+	data := map[string]interface{}{
+		"source":   source,
+		"imported": imported,
+		"success":  importErr == nil,
+	}
+	if importErr != nil {
+		data["error"] = importErr.Error()
+	}
+	EmitWebhookEvent(WEBHOOK_EVENT_IMPORT_FINISHED, "", data)
+}